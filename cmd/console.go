@@ -95,14 +95,16 @@ func consoleStart(r *reporter.Reporter, config ConsoleConfiguration, checkOnly b
 	if err != nil {
 		return fmt.Errorf("unable to initialize ClickHouse component: %w", err)
 	}
-	authenticationComponent, err := authentication.New(r, config.Auth)
-	if err != nil {
-		return fmt.Errorf("unable to initialize authentication component: %w", err)
-	}
 	databaseComponent, err := database.New(r, config.Database)
 	if err != nil {
 		return fmt.Errorf("unable to initialize database component: %w", err)
 	}
+	authenticationComponent, err := authentication.New(r, config.Auth, authentication.Dependencies{
+		Database: databaseComponent,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to initialize authentication component: %w", err)
+	}
 	consoleComponent, err := console.New(r, config.Console, console.Dependencies{
 		Daemon:       daemonComponent,
 		HTTP:         httpComponent,
@@ -127,8 +129,8 @@ func consoleStart(r *reporter.Reporter, config ConsoleConfiguration, checkOnly b
 	components := []interface{}{
 		httpComponent,
 		clickhouseComponent,
-		authenticationComponent,
 		databaseComponent,
+		authenticationComponent,
 		consoleComponent,
 	}
 	return StartStopComponents(r, daemonComponent, components)
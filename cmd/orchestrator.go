@@ -13,6 +13,7 @@ import (
 	"akvorado/common/http"
 	"akvorado/common/reporter"
 	"akvorado/orchestrator"
+	"akvorado/orchestrator/alerting"
 	"akvorado/orchestrator/clickhouse"
 	"akvorado/orchestrator/kafka"
 )
@@ -24,6 +25,7 @@ type OrchestratorConfiguration struct {
 	ClickHouseDB clickhousedb.Configuration `yaml:"-"`
 	ClickHouse   clickhouse.Configuration
 	Kafka        kafka.Configuration
+	Alerting     alerting.Configuration
 	Orchestrator orchestrator.Configuration `mapstructure:",squash" yaml:",inline"`
 	// Other service configurations
 	Inlet        []InletConfiguration        `validate:"dive"`
@@ -43,6 +45,7 @@ func (c *OrchestratorConfiguration) Reset() {
 		ClickHouseDB: clickhousedb.DefaultConfiguration(),
 		ClickHouse:   clickhouse.DefaultConfiguration(),
 		Kafka:        kafka.DefaultConfiguration(),
+		Alerting:     alerting.DefaultConfiguration(),
 		Orchestrator: orchestrator.DefaultConfiguration(),
 		// Other service configurations
 		Inlet:        []InletConfiguration{inletConfiguration},
@@ -73,11 +76,18 @@ components and centralizes configuration of the various other components.`,
 			// Override some parts of the configuration
 			config.ClickHouseDB = config.ClickHouse.Configuration
 			config.ClickHouse.Kafka.Configuration = config.Kafka.Configuration
+			aggregatedFlows := false
 			for idx := range config.Inlet {
 				config.Inlet[idx].Kafka.Configuration = config.Kafka.Configuration
+				if config.Inlet[idx].Core.AggregationWindow > 0 {
+					aggregatedFlows = true
+				}
 			}
 			for idx := range config.Console {
 				config.Console[idx].ClickHouse = config.ClickHouse.Configuration
+				if aggregatedFlows {
+					config.Console[idx].Console.AggregatedFlows = true
+				}
 			}
 		}
 		if err := OrchestratorOptions.Parse(cmd.OutOrStdout(), "orchestrator", &config); err != nil {
@@ -115,6 +125,11 @@ func orchestratorStart(r *reporter.Reporter, config OrchestratorConfiguration, c
 	if err != nil {
 		return fmt.Errorf("unable to initialize kafka component: %w", err)
 	}
+	for idx := range config.Inlet {
+		for _, output := range config.Inlet[idx].Kafka.Outputs {
+			kafkaComponent.RegisterOutputTopic(output.Brokers, output.Topic)
+		}
+	}
 	clickhouseDBComponent, err := clickhousedb.New(r, config.ClickHouseDB, clickhousedb.Dependencies{
 		Daemon: daemonComponent,
 	})
@@ -129,6 +144,14 @@ func orchestratorStart(r *reporter.Reporter, config OrchestratorConfiguration, c
 	if err != nil {
 		return fmt.Errorf("unable to initialize clickhouse component: %w", err)
 	}
+	alertingComponent, err := alerting.New(r, config.Alerting, alerting.Dependencies{
+		Daemon:     daemonComponent,
+		HTTP:       httpComponent,
+		ClickHouse: clickhouseDBComponent,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to initialize alerting component: %w", err)
+	}
 	orchestratorComponent, err := orchestrator.New(r, config.Orchestrator, orchestrator.Dependencies{
 		HTTP: httpComponent,
 	})
@@ -160,6 +183,7 @@ func orchestratorStart(r *reporter.Reporter, config OrchestratorConfiguration, c
 		clickhouseDBComponent,
 		clickhouseComponent,
 		kafkaComponent,
+		alertingComponent,
 	}
 	return StartStopComponents(r, daemonComponent, components)
 }
@@ -5,6 +5,8 @@ package clickhousedb
 
 import (
 	"time"
+
+	"akvorado/common/helpers"
 )
 
 // Configuration defines how we connect to a Clickhouse database
@@ -21,6 +23,13 @@ type Configuration struct {
 	MaxOpenConns int `validate:"min=1"`
 	// DialTimeout tells how much time to wait when connecting to ClickHouse
 	DialTimeout time.Duration `validate:"min=100ms"`
+	// Cluster is the name of the cluster to use for replicated and
+	// distributed tables (empty when not using a cluster)
+	Cluster string
+	// TLS defines TLS configuration
+	TLS helpers.TLSConfiguration
+	// Settings defines ClickHouse settings to use for each query
+	Settings map[string]interface{}
 }
 
 // DefaultConfiguration represents the default configuration for connecting to Clickhouse
@@ -31,5 +40,9 @@ func DefaultConfiguration() Configuration {
 		Username:     "default",
 		MaxOpenConns: 10,
 		DialTimeout:  5 * time.Second,
+		TLS: helpers.TLSConfiguration{
+			Enable: false,
+			Verify: true,
+		},
 	}
 }
@@ -6,6 +6,7 @@ package clickhousedb
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
@@ -33,6 +34,10 @@ type Dependencies struct {
 
 // New creates a new ClickHouse wrapper
 func New(r *reporter.Reporter, config Configuration, dependencies Dependencies) (*Component, error) {
+	tlsConfig, err := config.TLS.MakeTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("cannot configure TLS for ClickHouse: %w", err)
+	}
 	conn, err := clickhouse.Open(&clickhouse.Options{
 		Addr: config.Servers,
 		Auth: clickhouse.Auth{
@@ -40,6 +45,8 @@ func New(r *reporter.Reporter, config Configuration, dependencies Dependencies)
 			Username: config.Username,
 			Password: config.Password,
 		},
+		TLS:             tlsConfig,
+		Settings:        clickhouse.Settings(config.Settings),
 		Compression:     &clickhouse.Compression{Method: clickhouse.CompressionLZ4},
 		DialTimeout:     config.DialTimeout,
 		MaxOpenConns:    config.MaxOpenConns,
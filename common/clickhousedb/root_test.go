@@ -7,10 +7,12 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 
 	"akvorado/common/clickhousedb/mocks"
+	"akvorado/common/daemon"
 	"akvorado/common/helpers"
 	"akvorado/common/reporter"
 )
@@ -155,3 +157,31 @@ func TestRealClickHouse(t *testing.T) {
 		}
 	})
 }
+
+func TestRealClickHouseSettings(t *testing.T) {
+	r := reporter.NewMock(t)
+	chServer := helpers.CheckExternalService(t, "ClickHouse", []string{"clickhouse", "localhost"}, "9000")
+	config := DefaultConfiguration()
+	config.Servers = []string{chServer}
+	config.DialTimeout = 100 * time.Millisecond
+	config.Settings = map[string]interface{}{
+		"max_execution_time": 17,
+	}
+	chComponent, err := New(r, config, Dependencies{Daemon: daemon.NewMock(t)})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, chComponent)
+
+	var got []struct {
+		V uint64 `ch:"v"`
+	}
+	err = chComponent.Select(context.Background(), &got,
+		"SELECT toUInt64(getSetting('max_execution_time')) AS v")
+	if err != nil {
+		t.Fatalf("SELECT error:\n%+v", err)
+	}
+	if len(got) != 1 || got[0].V != 17 {
+		t.Fatalf("SELECT getSetting() == %v but expected 17", got)
+	}
+}
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package helpers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSConfiguration defines TLS configuration to connect to a remote
+// service.
+type TLSConfiguration struct {
+	// Enable says if TLS should be used
+	Enable bool `validate:"required_with=CAFile CertFile KeyFile"`
+	// Verify says if we need to check remote certificates
+	Verify bool
+	// CAFile tells the location of the CA certificate to check the
+	// remote certificate. If empty, the system CA certificates are
+	// used instead.
+	CAFile string
+	// CertFile tells the location of the user certificate if any.
+	CertFile string `validate:"required_with=KeyFile"`
+	// KeyFile tells the location of the user key if any. If empty,
+	// the key is expected to be in the certificate file.
+	KeyFile string
+}
+
+// MakeTLSConfig builds a TLS configuration from the provided
+// configuration. It returns nil when TLS is not enabled.
+func (config TLSConfiguration) MakeTLSConfig() (*tls.Config, error) {
+	if !config.Enable {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: !config.Verify,
+	}
+	// Read CA certificate if provided
+	if config.CAFile != "" {
+		caCert, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA certificate: %w", err)
+		}
+		caCertPool := x509.NewCertPool()
+		if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
+			return nil, errors.New("cannot parse CA certificate")
+		}
+		tlsConfig.RootCAs = caCertPool
+	}
+	// Read user certificate if provided
+	if config.CertFile != "" {
+		keyFile := config.KeyFile
+		if keyFile == "" {
+			keyFile = config.CertFile
+		}
+		cert, err := tls.LoadX509KeyPair(config.CertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read user certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package helpers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMakeTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error:\n%+v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error:\n%+v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error:\n%+v", err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "cert.key")
+	if err := os.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("WriteFile() error:\n%+v", err)
+	}
+	if err := os.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatalf("WriteFile() error:\n%+v", err)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		got, err := TLSConfiguration{Enable: false, CAFile: certFile}.MakeTLSConfig()
+		if err != nil {
+			t.Fatalf("MakeTLSConfig() error:\n%+v", err)
+		}
+		if got != nil {
+			t.Fatalf("MakeTLSConfig() == %v but expected nil", got)
+		}
+	})
+	t.Run("skip verify", func(t *testing.T) {
+		got, err := TLSConfiguration{Enable: true, Verify: false}.MakeTLSConfig()
+		if err != nil {
+			t.Fatalf("MakeTLSConfig() error:\n%+v", err)
+		}
+		if !got.InsecureSkipVerify {
+			t.Error("MakeTLSConfig().InsecureSkipVerify == false but expected true")
+		}
+		if got.RootCAs != nil {
+			t.Error("MakeTLSConfig().RootCAs != nil")
+		}
+	})
+	t.Run("CA and client certificate", func(t *testing.T) {
+		got, err := TLSConfiguration{
+			Enable:   true,
+			Verify:   true,
+			CAFile:   certFile,
+			CertFile: certFile,
+			KeyFile:  keyFile,
+		}.MakeTLSConfig()
+		if err != nil {
+			t.Fatalf("MakeTLSConfig() error:\n%+v", err)
+		}
+		if got.InsecureSkipVerify {
+			t.Error("MakeTLSConfig().InsecureSkipVerify == true but expected false")
+		}
+		if got.RootCAs == nil {
+			t.Error("MakeTLSConfig().RootCAs == nil")
+		}
+		if len(got.Certificates) != 1 {
+			t.Errorf("MakeTLSConfig().Certificates has %d certificates, expected 1",
+				len(got.Certificates))
+		}
+	})
+	t.Run("missing CA", func(t *testing.T) {
+		_, err := TLSConfiguration{
+			Enable: true,
+			CAFile: filepath.Join(dir, "missing.pem"),
+		}.MakeTLSConfig()
+		if err == nil {
+			t.Fatal("MakeTLSConfig() did not error")
+		}
+	})
+	t.Run("invalid CA", func(t *testing.T) {
+		_, err := TLSConfiguration{
+			Enable: true,
+			CAFile: keyFile,
+		}.MakeTLSConfig()
+		if err == nil {
+			t.Fatal("MakeTLSConfig() did not error")
+		}
+	})
+}
@@ -8,12 +8,10 @@ package kafka
 import (
 	"crypto/sha256"
 	"crypto/sha512"
-	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"fmt"
-	"os"
 
+	"akvorado/common/helpers"
 	"akvorado/common/helpers/bimap"
 
 	"github.com/Shopify/sarama"
@@ -140,32 +138,17 @@ func NewConfig(config Configuration) (*sarama.Config, error) {
 	kafkaConfig.Version = sarama.KafkaVersion(config.Version)
 	if config.TLS.Enable {
 		kafkaConfig.Net.TLS.Enable = true
-		kafkaConfig.Net.TLS.Config = &tls.Config{
-			InsecureSkipVerify: !config.TLS.Verify,
-		}
-		// Read CA certificate if provided
-		if config.TLS.CAFile != "" {
-			caCert, err := os.ReadFile(config.TLS.CAFile)
-			if err != nil {
-				return nil, fmt.Errorf("cannot read CA certificate for Kafka: %w", err)
-			}
-			caCertPool := x509.NewCertPool()
-			if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
-				return nil, errors.New("cannot parse CA certificate for Kafka")
-			}
-			kafkaConfig.Net.TLS.Config.RootCAs = caCertPool
-		}
-		// Read user certificate if provided
-		if config.TLS.CertFile != "" {
-			if config.TLS.KeyFile == "" {
-				config.TLS.KeyFile = config.TLS.CertFile
-			}
-			cert, err := tls.LoadX509KeyPair(config.TLS.CertFile, config.TLS.KeyFile)
-			if err != nil {
-				return nil, fmt.Errorf("cannot read user certificate: %w", err)
-			}
-			kafkaConfig.Net.TLS.Config.Certificates = []tls.Certificate{cert}
+		tlsConfig, err := helpers.TLSConfiguration{
+			Enable:   true,
+			Verify:   config.TLS.Verify,
+			CAFile:   config.TLS.CAFile,
+			CertFile: config.TLS.CertFile,
+			KeyFile:  config.TLS.KeyFile,
+		}.MakeTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("cannot configure TLS for Kafka: %w", err)
 		}
+		kafkaConfig.Net.TLS.Config = tlsConfig
 		// SASL
 		if config.TLS.SASLUsername != "" {
 			kafkaConfig.Net.SASL.Enable = true
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package schema describes how flows are stored in ClickHouse: the
+// available flows tables and the columns usable as dimensions. It is
+// shared by the components querying flows.
+package schema
+
+import (
+	"fmt"
+
+	"akvorado/common/helpers"
+)
+
+// Columns lists the columns usable as dimensions.
+var Columns = []string{
+	"ExporterAddress",
+	"ExporterName",
+	"ExporterGroup",
+	"ExporterRole",
+	"ExporterSite",
+	"ExporterRegion",
+	"ExporterTenant",
+	"SrcAddr",
+	"DstAddr",
+	"SrcNetPrefix",
+	"DstNetPrefix",
+	"SrcAS",
+	"DstAS",
+	"DstASPath",
+	"Dst1stAS",
+	"Dst2ndAS",
+	"Dst3rdAS",
+	"DstCommunities",
+	"SrcNetName",
+	"DstNetName",
+	"SrcNetRole",
+	"DstNetRole",
+	"SrcNetSite",
+	"DstNetSite",
+	"SrcNetRegion",
+	"DstNetRegion",
+	"SrcNetTenant",
+	"DstNetTenant",
+	"SrcCountry",
+	"DstCountry",
+	"InIfName",
+	"OutIfName",
+	"InIfDescription",
+	"OutIfDescription",
+	"InIfSpeed",
+	"OutIfSpeed",
+	"InIfConnectivity",
+	"OutIfConnectivity",
+	"InIfProvider",
+	"OutIfProvider",
+	"InIfBoundary",
+	"OutIfBoundary",
+	"EType",
+	"Proto",
+	"SrcPort",
+	"DstPort",
+	"ForwardingStatus",
+	"PacketSizeBucket",
+	"SrcVlan",
+	"DstVlan",
+	"SrcMAC",
+	"DstMAC",
+	"MPLS1stLabel",
+	"IPTTL",
+	"DSCP",
+	"TCPFlags",
+	"IcmpType",
+	"IcmpCode",
+}
+
+var columnsSet = func() map[string]struct{} {
+	result := make(map[string]struct{}, len(Columns))
+	for _, column := range Columns {
+		result[column] = struct{}{}
+	}
+	return result
+}()
+
+// columnsRequiringMainTable lists columns only present in the main
+// table. Also check console/filter/parser.peg.
+var columnsRequiringMainTable = map[string]struct{}{
+	"SrcAddr":        {},
+	"DstAddr":        {},
+	"SrcNetPrefix":   {},
+	"DstNetPrefix":   {},
+	"SrcPort":        {},
+	"DstPort":        {},
+	"DstASPath":      {},
+	"DstCommunities": {},
+	"SrcVlan":        {},
+	"DstVlan":        {},
+	"SrcMAC":         {},
+	"DstMAC":         {},
+	"MPLS1stLabel":   {},
+	"IPTTL":          {},
+	"DSCP":           {},
+	"TCPFlags":       {},
+	"IcmpType":       {},
+	"IcmpCode":       {},
+}
+
+// ColumnRequiresMainTable tells if the provided column is only
+// present in the main table.
+func ColumnRequiresMainTable(column string) bool {
+	_, ok := columnsRequiringMainTable[column]
+	return ok
+}
+
+// ColumnToSQL returns the SQL expression to select the provided
+// column as a string.
+func ColumnToSQL(column string) (string, error) {
+	if _, ok := columnsSet[column]; !ok {
+		return "", fmt.Errorf("unknown column %q", column)
+	}
+	switch column {
+	case "ExporterAddress", "SrcAddr", "DstAddr":
+		return fmt.Sprintf("replaceRegexpOne(IPv6NumToString(%s), '^::ffff:', '')", column), nil
+	case "SrcAS", "DstAS", "Dst1stAS", "Dst2ndAS", "Dst3rdAS":
+		return fmt.Sprintf(`concat(toString(%s), ': ', dictGetOrDefault('asns', 'name', %s, '???'))`,
+			column, column), nil
+	case "EType":
+		return fmt.Sprintf(`if(EType = %d, 'IPv4', if(EType = %d, 'IPv6', '???'))`,
+			helpers.ETypeIPv4, helpers.ETypeIPv6), nil
+	case "Proto":
+		return `dictGetOrDefault('protocols', 'name', Proto, '???')`, nil
+	case "InIfSpeed", "OutIfSpeed", "SrcPort", "DstPort", "ForwardingStatus", "InIfBoundary", "OutIfBoundary", "SrcVlan", "DstVlan", "MPLS1stLabel", "IPTTL", "DSCP", "IcmpType", "IcmpCode":
+		return fmt.Sprintf("toString(%s)", column), nil
+	case "TCPFlags":
+		return `arrayStringConcat(arrayFilter((f, b) -> bitTest(TCPFlags, b), ['fin', 'syn', 'rst', 'psh', 'ack', 'urg', 'ece', 'cwr', 'ns'], range(9)), '|')`, nil
+	case "SrcMAC", "DstMAC":
+		return fmt.Sprintf("MACNumToString(%s)", column), nil
+	case "DstASPath":
+		return `arrayStringConcat(DstASPath, ' ')`, nil
+	case "DstCommunities":
+		return `arrayStringConcat(arrayConcat(arrayMap(c -> concat(toString(bitShiftRight(c, 16)), ':', toString(bitAnd(c, 0xffff))), DstCommunities), arrayMap(c -> concat(toString(bitAnd(bitShiftRight(c, 64), 0xffffffff)), ':', toString(bitAnd(bitShiftRight(c, 32), 0xffffffff)), ':', toString(bitAnd(c, 0xffffffff))), DstLargeCommunities)), ' ')`, nil
+	}
+	return column, nil
+}
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schema
+
+import "testing"
+
+func TestColumnToSQL(t *testing.T) {
+	cases := []struct {
+		Column            string
+		Expected          string
+		MainTableRequired bool
+		Error             bool
+	}{
+		{"ExporterName", "ExporterName", false, false},
+		{"InIfSpeed", "toString(InIfSpeed)", false, false},
+		{"DstAddr", "replaceRegexpOne(IPv6NumToString(DstAddr), '^::ffff:', '')", true, false},
+		{"Nope", "", false, true},
+		{"ExporterName/24", "", false, true},
+	}
+	for _, tc := range cases {
+		got, err := ColumnToSQL(tc.Column)
+		if err != nil && !tc.Error {
+			t.Errorf("ColumnToSQL(%q) error:\n%+v", tc.Column, err)
+			continue
+		} else if err == nil && tc.Error {
+			t.Errorf("ColumnToSQL(%q) did not error", tc.Column)
+			continue
+		}
+		if got != tc.Expected {
+			t.Errorf("ColumnToSQL(%q) == %q but expected %q", tc.Column, got, tc.Expected)
+		}
+		if mainTableRequired := ColumnRequiresMainTable(tc.Column); mainTableRequired != tc.MainTableRequired {
+			t.Errorf("ColumnRequiresMainTable(%q) == %v but expected %v",
+				tc.Column, mainTableRequired, tc.MainTableRequired)
+		}
+	}
+}
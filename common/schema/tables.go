@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/reporter"
+)
+
+// Table describes a consolidated or unconsolidated flows table.
+type Table struct {
+	Name       string
+	Resolution time.Duration
+	Oldest     time.Time
+}
+
+// ListTables queries ClickHouse for the flows tables (live one and
+// consolidated ones), their consolidation interval and their oldest
+// available data.
+func ListTables(ctx context.Context, r *reporter.Reporter, db *clickhousedb.Component) ([]Table, error) {
+	var tables []struct {
+		Name string `ch:"name"`
+	}
+	err := db.Select(ctx, &tables, `
+SELECT name
+FROM system.tables
+WHERE database=currentDatabase()
+AND table LIKE 'flows%'
+AND NOT endsWith(table, '_local')
+AND (engine LIKE '%MergeTree' OR engine = 'Distributed')
+`)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query flows table metadata: %w", err)
+	}
+
+	newTables := []Table{}
+	for _, table := range tables {
+		// Parse resolution
+		resolution := time.Duration(0)
+		if strings.HasPrefix(table.Name, "flows_") {
+			var err error
+			resolution, err = time.ParseDuration(strings.TrimPrefix(table.Name, "flows_"))
+			if err != nil {
+				r.Err(err).Msgf("cannot parse duration for table %s", table.Name)
+				continue
+			}
+		}
+		// Get oldest timestamp
+		var oldest []struct {
+			T time.Time `ch:"t"`
+		}
+		err := db.Conn.Select(ctx, &oldest,
+			fmt.Sprintf(`SELECT MIN(TimeReceived) AS t FROM %s`, table.Name))
+		if err != nil {
+			return nil, fmt.Errorf("cannot query table %s for oldest timestamp: %w", table.Name, err)
+		}
+
+		newTables = append(newTables, Table{
+			Name:       table.Name,
+			Resolution: resolution,
+			Oldest:     oldest[0].T,
+		})
+	}
+	if len(newTables) == 0 {
+		return nil, errors.New("no flows table present (yet?)")
+	}
+	return newTables, nil
+}
+
+// BestTable returns the best table among the provided ones to query
+// flows starting at the specified time with the target interval, as
+// well as the resolution of this table.
+func BestTable(tables []Table, start time.Time, targetInterval time.Duration) (string, time.Duration) {
+	table := "flows"
+	computedInterval := time.Second
+	if len(tables) > 0 {
+		// We can use the consolidated data. The first
+		// criteria is to find the tables matching the time
+		// criteria.
+		candidates := []int{}
+		for idx, table := range tables {
+			if start.After(table.Oldest.Add(table.Resolution)) {
+				candidates = append(candidates, idx)
+			}
+		}
+		if len(candidates) == 0 {
+			// No candidate, fallback to the one with oldest data
+			best := 0
+			for idx, table := range tables {
+				if tables[best].Oldest.After(table.Oldest.Add(table.Resolution)) {
+					best = idx
+				}
+			}
+			candidates = []int{best}
+			// Add other candidates that are not far off in term of oldest data
+			for idx, table := range tables {
+				if idx == best {
+					continue
+				}
+				if tables[best].Oldest.After(table.Oldest) {
+					candidates = append(candidates, idx)
+				}
+			}
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			return tables[candidates[i]].Resolution < tables[candidates[j]].Resolution
+		})
+		// If possible, use the first resolution before the target interval
+		for len(candidates) > 1 {
+			if tables[candidates[1]].Resolution < targetInterval {
+				candidates = candidates[1:]
+			} else {
+				break
+			}
+		}
+		table = tables[candidates[0]].Name
+		computedInterval = tables[candidates[0]].Resolution
+	}
+	if computedInterval < time.Second {
+		computedInterval = time.Second
+	}
+	return table, computedInterval
+}
+
+// Tables keeps track of the available flows tables to select the best
+// one for a query.
+type Tables struct {
+	r      *reporter.Reporter
+	db     *clickhousedb.Component
+	lock   sync.RWMutex
+	tables []Table
+}
+
+// NewTables creates a new tracker for flows tables. Until the first
+// refresh, only the main table is used.
+func NewTables(r *reporter.Reporter, db *clickhousedb.Component) *Tables {
+	return &Tables{
+		r:      r,
+		db:     db,
+		tables: []Table{{"flows", 0, time.Time{}}},
+	}
+}
+
+// Refresh refreshes the list of flows tables from ClickHouse.
+func (t *Tables) Refresh(ctx context.Context) error {
+	tables, err := ListTables(ctx, t.r, t.db)
+	if err != nil {
+		return err
+	}
+	t.lock.Lock()
+	t.tables = tables
+	t.lock.Unlock()
+	return nil
+}
+
+// Best returns the best table to query flows starting at the
+// specified time with the target interval, as well as its
+// resolution. When the main table is required, the table with the
+// finest resolution is selected.
+func (t *Tables) Best(start time.Time, targetInterval time.Duration, mainTableRequired bool) (string, time.Duration) {
+	if mainTableRequired {
+		targetInterval = time.Second
+	}
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return BestTable(t.tables, start, targetInterval)
+}
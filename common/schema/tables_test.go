@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/reporter"
+)
+
+func TestTablesBest(t *testing.T) {
+	r := reporter.NewMock(t)
+	ch, _ := clickhousedb.NewMock(t, r)
+	tables := NewTables(r, ch)
+	start := time.Date(2022, 04, 11, 15, 45, 10, 0, time.UTC)
+	if table, _ := tables.Best(start, 5*time.Minute, false); table != "flows" {
+		t.Fatalf("Best() == %q, expected %q", table, "flows")
+	}
+
+	tables.tables = []Table{
+		{"flows", 0, time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC)},
+		{"flows_1m0s", time.Minute, time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC)},
+		{"flows_5m0s", 5 * time.Minute, time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC)},
+	}
+	cases := []struct {
+		TargetInterval     time.Duration
+		MainTableRequired  bool
+		ExpectedTable      string
+		ExpectedResolution time.Duration
+	}{
+		{time.Minute, false, "flows", time.Second},
+		{5 * time.Minute, false, "flows_1m0s", time.Minute},
+		{time.Hour, false, "flows_5m0s", 5 * time.Minute},
+		{time.Hour, true, "flows", time.Second},
+	}
+	for _, tc := range cases {
+		table, resolution := tables.Best(start, tc.TargetInterval, tc.MainTableRequired)
+		if table != tc.ExpectedTable || resolution != tc.ExpectedResolution {
+			t.Errorf("Best(%s, %v) == %q, %s but expected %q, %s",
+				tc.TargetInterval, tc.MainTableRequired,
+				table, resolution, tc.ExpectedTable, tc.ExpectedResolution)
+		}
+	}
+}
@@ -3,6 +3,8 @@
 
 package authentication
 
+import "time"
+
 // Configuration describes the configuration for the authentication component.
 type Configuration struct {
 	// Headers define authentication headers
@@ -11,6 +13,39 @@ type Configuration struct {
 	// headers are present. Leave `User' empty to not allow access
 	// without authentication.
 	DefaultUser UserInformation
+	// OIDC defines the configuration to authenticate users with
+	// OpenID Connect instead of relying on headers
+	OIDC OIDCConfiguration
+}
+
+// OIDCConfiguration defines the configuration for OpenID Connect.
+// OIDC is enabled when the issuer URL is set.
+type OIDCConfiguration struct {
+	// IssuerURL is the URL of the OpenID provider
+	IssuerURL string `validate:"omitempty,url"`
+	// ClientID is the client ID registered at the provider
+	ClientID string `validate:"required_with=IssuerURL"`
+	// ClientSecret is the client secret registered at the provider
+	ClientSecret string
+	// RedirectURL is the URL of the callback endpoint as seen by the browser
+	RedirectURL string `validate:"required_with=IssuerURL,omitempty,url"`
+	// Scopes is the list of scopes to request
+	Scopes []string
+	// CookieSecret is the secret used to sign session cookies
+	CookieSecret string `validate:"required_with=IssuerURL,omitempty,min=16"`
+	// SessionDuration is the duration of a session
+	SessionDuration time.Duration `validate:"min=1m"`
+	// Claims maps claims to user information
+	Claims OIDCClaimsConfiguration
+}
+
+// OIDCClaimsConfiguration defines the claims to use for each user
+// attribute.
+type OIDCClaimsConfiguration struct {
+	Login  string `validate:"required"`
+	Name   string
+	Email  string
+	Groups string
 }
 
 // ConfigurationHeaders define headers used for authentication
@@ -19,6 +54,7 @@ type ConfigurationHeaders struct {
 	Name      string
 	Email     string
 	LogoutURL string
+	Groups    string
 }
 
 // DefaultConfiguration represents the default configuration for the console component.
@@ -29,10 +65,21 @@ func DefaultConfiguration() Configuration {
 			Name:      "Remote-Name",
 			Email:     "Remote-Email",
 			LogoutURL: "X-Logout-URL",
+			Groups:    "Remote-Groups",
 		},
 		DefaultUser: UserInformation{
 			Login: "__default",
 			Name:  "Default User",
 		},
+		OIDC: OIDCConfiguration{
+			Scopes:          []string{"openid", "profile", "email"},
+			SessionDuration: 12 * time.Hour,
+			Claims: OIDCClaimsConfiguration{
+				Login:  "preferred_username",
+				Name:   "name",
+				Email:  "email",
+				Groups: "groups",
+			},
+		},
 	}
 }
@@ -17,7 +17,7 @@ import (
 func TestUserHandler(t *testing.T) {
 	r := reporter.NewMock(t)
 	h := http.NewMock(t, r)
-	c, err := New(r, DefaultConfiguration())
+	c, err := New(r, DefaultConfiguration(), Dependencies{})
 	if err != nil {
 		t.Fatalf("New() error:\n%+v", err)
 	}
@@ -64,6 +64,20 @@ func TestUserHandler(t *testing.T) {
 					"email":      "alfred@batman.com",
 					"logout-url": "/logout",
 				},
+			}, {
+				Description: "user info, user logged in with groups",
+				URL:         "/api/v0/console/user/info",
+				Header: func() netHTTP.Header {
+					headers := make(netHTTP.Header)
+					headers.Add("Remote-User", "alfred")
+					headers.Add("Remote-Groups", "butlers, noc,")
+					return headers
+				}(),
+				StatusCode: 200,
+				JSONOutput: gin.H{
+					"login":  "alfred",
+					"groups": []string{"butlers", "noc"},
+				},
 			}, {
 				Description: "user info, invalid user logged in",
 				URL:         "/api/v0/console/user/info",
@@ -4,26 +4,67 @@
 package authentication
 
 import (
+	"errors"
 	"net/http"
 	"reflect"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+
+	"akvorado/console/database"
 )
 
 // UserInformation contains information about the current user.
 type UserInformation struct {
-	Login     string `json:"login" header:"LOGIN" binding:"required"`
-	Name      string `json:"name,omitempty" header:"NAME"`
-	Email     string `json:"email,omitempty" header:"EMAIL" binding:"omitempty,email"`
-	LogoutURL string `json:"logout-url,omitempty" header:"LOGOUT" binding:"omitempty,uri"`
+	Login     string   `json:"login" header:"LOGIN" binding:"required"`
+	Name      string   `json:"name,omitempty" header:"NAME"`
+	Email     string   `json:"email,omitempty" header:"EMAIL" binding:"omitempty,email"`
+	LogoutURL string   `json:"logout-url,omitempty" header:"LOGOUT" binding:"omitempty,uri"`
+	Groups    []string `json:"groups,omitempty" header:"GROUPS"`
 }
 
 // UserAuthentication is a middleware to fill information about the
 // current user. It does not really perform authentication but relies
-// on HTTP headers.
+// on HTTP headers, or on a session cookie when OpenID Connect is
+// enabled. API tokens provided as bearer tokens are mapped to their
+// owner and to the groups the owner had when creating the token.
 func (c *Component) UserAuthentication() gin.HandlerFunc {
 	return func(gc *gin.Context) {
+		if token, ok := bearerToken(gc.Request); ok && c.d.Database != nil {
+			apiToken, err := c.d.Database.LookupAPIToken(gc.Request.Context(), token)
+			if err != nil {
+				if !errors.Is(err, database.ErrNotFound) {
+					c.r.Err(err).Msg("cannot check API token")
+				}
+				gc.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid API token."})
+				gc.Abort()
+				return
+			}
+			gc.Set("user", UserInformation{
+				Login:  apiToken.User,
+				Groups: apiToken.Groups,
+			})
+			gc.Set("api-token", apiToken.ID)
+			gc.Next()
+			return
+		}
+
+		if c.oidcEnabled() {
+			info, ok := c.sessionUser(gc.Request)
+			if !ok {
+				gc.JSON(http.StatusUnauthorized, gin.H{
+					"message":   "No user logged in.",
+					"login-url": OIDCLoginURL,
+				})
+				gc.Abort()
+				return
+			}
+			gc.Set("user", info)
+			gc.Next()
+			return
+		}
+
 		var info UserInformation
 		if err := gc.ShouldBindWith(&info, customHeaderBinding{c}); err != nil {
 			if c.config.DefaultUser.Login == "" {
@@ -38,6 +79,15 @@ func (c *Component) UserAuthentication() gin.HandlerFunc {
 	}
 }
 
+// bearerToken extracts the bearer token from the Authorization header.
+func bearerToken(req *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
 type customHeaderBinding struct {
 	c *Component
 }
@@ -72,10 +122,25 @@ func (b customHeaderBinding) Bind(req *http.Request, obj interface{}) error {
 			header = b.c.config.Headers.Email
 		case "LOGOUT":
 			header = b.c.config.Headers.LogoutURL
+		case "GROUPS":
+			header = b.c.config.Headers.Groups
 		}
 		if header == "" {
 			continue
 		}
+		if sf.Type.Kind() == reflect.Slice {
+			// Comma-separated list
+			values := []string{}
+			for _, v := range strings.Split(req.Header.Get(header), ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					values = append(values, v)
+				}
+			}
+			if len(values) > 0 {
+				value.Field(i).Set(reflect.ValueOf(values))
+			}
+			continue
+		}
 		value.Field(i).SetString(req.Header.Get(header))
 	}
 
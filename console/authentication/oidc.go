@@ -0,0 +1,361 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package authentication
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// OIDCLoginURL is the URL of the login endpoint.
+	OIDCLoginURL = "/api/v0/console/auth/login"
+	// OIDCLogoutURL is the URL of the logout endpoint.
+	OIDCLogoutURL = "/api/v0/console/auth/logout"
+
+	sessionCookieName = "akvorado-session"
+	stateCookieName   = "akvorado-oidc-state"
+	stateDuration     = 10 * time.Minute
+)
+
+// oidcState contains what is needed to authenticate users with an
+// OpenID provider once discovered.
+type oidcState struct {
+	verifier      *oidc.IDTokenVerifier
+	oauth2        oauth2.Config
+	endSessionURL string
+}
+
+// oidcSession is the content of the session cookie.
+type oidcSession struct {
+	User    UserInformation `json:"user"`
+	Expires int64           `json:"expires"`
+}
+
+// oidcLoginState is the content of the cookie used during the
+// authorization code flow.
+type oidcLoginState struct {
+	State   string `json:"state"`
+	Nonce   string `json:"nonce"`
+	Next    string `json:"next"`
+	Expires int64  `json:"expires"`
+}
+
+// oidcEnabled tells if OpenID Connect authentication is enabled.
+func (c *Component) oidcEnabled() bool {
+	return c.config.OIDC.IssuerURL != ""
+}
+
+// startOIDC discovers the configuration of the OpenID provider.
+func (c *Component) startOIDC() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	provider, err := oidc.NewProvider(ctx, c.config.OIDC.IssuerURL)
+	if err != nil {
+		return fmt.Errorf("unable to discover OpenID provider: %w", err)
+	}
+	var metadata struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := provider.Claims(&metadata); err != nil {
+		return fmt.Errorf("unable to parse OpenID provider metadata: %w", err)
+	}
+	c.oidc = &oidcState{
+		verifier: provider.Verifier(&oidc.Config{ClientID: c.config.OIDC.ClientID}),
+		oauth2: oauth2.Config{
+			ClientID:     c.config.OIDC.ClientID,
+			ClientSecret: c.config.OIDC.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  c.config.OIDC.RedirectURL,
+			Scopes:       c.config.OIDC.Scopes,
+		},
+		endSessionURL: metadata.EndSessionEndpoint,
+	}
+	return nil
+}
+
+// sessionUser returns the user associated to the session cookie.
+func (c *Component) sessionUser(req *http.Request) (UserInformation, bool) {
+	cookie, err := req.Cookie(sessionCookieName)
+	if err != nil {
+		return UserInformation{}, false
+	}
+	var session oidcSession
+	if err := c.verifyCookie(sessionCookieName, cookie.Value, &session); err != nil {
+		return UserInformation{}, false
+	}
+	if time.Now().Unix() > session.Expires {
+		return UserInformation{}, false
+	}
+	return session.User, true
+}
+
+// OIDCLoginHandlerFunc redirects the user to the OpenID provider.
+func (c *Component) OIDCLoginHandlerFunc(gc *gin.Context) {
+	if c.oidc == nil {
+		gc.JSON(http.StatusNotFound, gin.H{"message": "OpenID Connect is not enabled."})
+		return
+	}
+	loginState := oidcLoginState{
+		State:   randomString(),
+		Nonce:   randomString(),
+		Next:    localRedirect(gc.Query("next")),
+		Expires: time.Now().Add(stateDuration).Unix(),
+	}
+	if err := c.setCookie(gc, stateCookieName, loginState, stateDuration); err != nil {
+		c.r.Err(err).Msg("cannot set OIDC state cookie")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Cannot initiate login."})
+		return
+	}
+	gc.Redirect(http.StatusFound,
+		c.oidc.oauth2.AuthCodeURL(loginState.State, oidc.Nonce(loginState.Nonce)))
+}
+
+// OIDCCallbackHandlerFunc handles the redirection from the OpenID
+// provider and creates a new session.
+func (c *Component) OIDCCallbackHandlerFunc(gc *gin.Context) {
+	if c.oidc == nil {
+		gc.JSON(http.StatusNotFound, gin.H{"message": "OpenID Connect is not enabled."})
+		return
+	}
+	cookie, err := gc.Request.Cookie(stateCookieName)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": "Missing login state."})
+		return
+	}
+	var loginState oidcLoginState
+	if err := c.verifyCookie(stateCookieName, cookie.Value, &loginState); err != nil ||
+		time.Now().Unix() > loginState.Expires ||
+		!hmac.Equal([]byte(gc.Query("state")), []byte(loginState.State)) {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": "Invalid login state."})
+		return
+	}
+	c.clearCookie(gc, stateCookieName)
+	if errorCode := gc.Query("error"); errorCode != "" {
+		gc.JSON(http.StatusUnauthorized, gin.H{
+			"message": fmt.Sprintf("Authentication failed: %s.", errorCode),
+		})
+		return
+	}
+
+	ctx := gc.Request.Context()
+	token, err := c.oidc.oauth2.Exchange(ctx, gc.Query("code"))
+	if err != nil {
+		c.r.Err(err).Msg("cannot exchange OIDC authorization code")
+		gc.JSON(http.StatusUnauthorized, gin.H{"message": "Authentication failed."})
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		c.r.Error().Msg("no ID token received from OpenID provider")
+		gc.JSON(http.StatusUnauthorized, gin.H{"message": "Authentication failed."})
+		return
+	}
+	idToken, err := c.oidc.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		c.r.Err(err).Msg("cannot verify OIDC ID token")
+		gc.JSON(http.StatusUnauthorized, gin.H{"message": "Authentication failed."})
+		return
+	}
+	if !hmac.Equal([]byte(idToken.Nonce), []byte(loginState.Nonce)) {
+		gc.JSON(http.StatusUnauthorized, gin.H{"message": "Authentication failed."})
+		return
+	}
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		c.r.Err(err).Msg("cannot decode OIDC claims")
+		gc.JSON(http.StatusUnauthorized, gin.H{"message": "Authentication failed."})
+		return
+	}
+	user, err := c.userFromClaims(claims)
+	if err != nil {
+		c.r.Err(err).Msg("cannot map OIDC claims to user")
+		gc.JSON(http.StatusUnauthorized, gin.H{"message": "Authentication failed."})
+		return
+	}
+
+	session := oidcSession{
+		User:    user,
+		Expires: time.Now().Add(c.config.OIDC.SessionDuration).Unix(),
+	}
+	if err := c.setCookie(gc, sessionCookieName, session, c.config.OIDC.SessionDuration); err != nil {
+		c.r.Err(err).Msg("cannot set session cookie")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Cannot create session."})
+		return
+	}
+	gc.Redirect(http.StatusFound, loginState.Next)
+}
+
+// OIDCLogoutHandlerFunc removes the session cookie and redirects the
+// user to the OpenID provider, if it supports it. It should be
+// registered for POST requests only. Cross-site requests are
+// rejected.
+func (c *Component) OIDCLogoutHandlerFunc(gc *gin.Context) {
+	if !sameOriginRequest(gc.Request) {
+		gc.JSON(http.StatusForbidden, gin.H{"message": "Cross-site request rejected."})
+		return
+	}
+	c.clearCookie(gc, sessionCookieName)
+	if c.oidc != nil && c.oidc.endSessionURL != "" {
+		gc.Redirect(http.StatusSeeOther, c.oidc.endSessionURL)
+		return
+	}
+	gc.Redirect(http.StatusSeeOther, "/")
+}
+
+// localRedirect returns the provided target if this is a path on the
+// console, or "/" otherwise. Browsers handle backslashes as slashes,
+// therefore "/\example.com" is not a local path.
+func localRedirect(next string) string {
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return "/"
+	}
+	for _, path := range []string{next, u.Path} {
+		if !strings.HasPrefix(path, "/") ||
+			strings.HasPrefix(path, "//") ||
+			strings.HasPrefix(path, "/\\") {
+			return "/"
+		}
+	}
+	return next
+}
+
+// sameOriginRequest tells if a request was issued by the console
+// itself. Browsers not sending any of the checked headers are
+// trusted.
+func sameOriginRequest(req *http.Request) bool {
+	if site := req.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin"
+	}
+	if origin := req.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		return err == nil && u.Host == req.Host
+	}
+	return true
+}
+
+// userFromClaims builds user information from the provided claims
+// using the configured mapping.
+func (c *Component) userFromClaims(claims map[string]interface{}) (UserInformation, error) {
+	mapping := c.config.OIDC.Claims
+	claim := func(name string) string {
+		value, _ := claims[name].(string)
+		return value
+	}
+	info := UserInformation{
+		Login:     claim(mapping.Login),
+		Name:      claim(mapping.Name),
+		Email:     claim(mapping.Email),
+		LogoutURL: OIDCLogoutURL,
+	}
+	switch groups := claims[mapping.Groups].(type) {
+	case string:
+		info.Groups = []string{groups}
+	case []interface{}:
+		for _, group := range groups {
+			if group, ok := group.(string); ok {
+				info.Groups = append(info.Groups, group)
+			}
+		}
+	}
+	if err := binding.Validator.ValidateStruct(&info); err != nil {
+		return UserInformation{}, err
+	}
+	return info, nil
+}
+
+// setCookie sets a signed cookie with the provided payload.
+func (c *Component) setCookie(gc *gin.Context, name string, payload interface{}, maxAge time.Duration) error {
+	value, err := c.signCookie(name, payload)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(gc.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   strings.HasPrefix(c.config.OIDC.RedirectURL, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// clearCookie removes the provided cookie.
+func (c *Component) clearCookie(gc *gin.Context, name string) {
+	http.SetCookie(gc.Writer, &http.Cookie{
+		Name:     name,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// signCookie encodes and signs the provided payload. The name of the
+// cookie is part of the signature.
+func (c *Component) signCookie(name string, payload interface{}) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return fmt.Sprintf("%s.%s", encoded,
+		base64.RawURLEncoding.EncodeToString(c.cookieMAC(name, encoded))), nil
+}
+
+// verifyCookie checks the signature of the provided cookie value and
+// decodes its payload.
+func (c *Component) verifyCookie(name string, value string, payload interface{}) error {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return errors.New("invalid cookie format")
+	}
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid cookie signature: %w", err)
+	}
+	if !hmac.Equal(got, c.cookieMAC(name, encoded)) {
+		return errors.New("invalid cookie signature")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("invalid cookie payload: %w", err)
+	}
+	return json.Unmarshal(data, payload)
+}
+
+func (c *Component) cookieMAC(name string, encoded string) []byte {
+	mac := hmac.New(sha256.New, []byte(c.config.OIDC.CookieSecret))
+	mac.Write([]byte(name))
+	mac.Write([]byte{'='})
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// randomString returns a random string suitable for state and nonce.
+func randomString() string {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(random)
+}
@@ -0,0 +1,378 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package authentication
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	netHTTP "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+
+	"akvorado/common/helpers"
+	"akvorado/common/http"
+	"akvorado/common/reporter"
+)
+
+// mockIssuer is a minimal OpenID provider.
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	nonce  string
+	claims map[string]interface{}
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error:\n%+v", err)
+	}
+	m := &mockIssuer{t: t, key: key}
+	mux := netHTTP.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w netHTTP.ResponseWriter, r *netHTTP.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/keys",
+			"end_session_endpoint":                  m.server.URL + "/logout",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w netHTTP.ResponseWriter, r *netHTTP.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{
+				Key:       &m.key.PublicKey,
+				KeyID:     "test",
+				Algorithm: "RS256",
+				Use:       "sig",
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w netHTTP.ResponseWriter, r *netHTTP.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != "good-code" {
+			w.WriteHeader(netHTTP.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     m.idToken(),
+		})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// idToken returns a signed ID token with the current claims.
+func (m *mockIssuer) idToken() string {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.RS256,
+		Key:       jose.JSONWebKey{Key: m.key, KeyID: "test"},
+	}, nil)
+	if err != nil {
+		m.t.Fatalf("NewSigner() error:\n%+v", err)
+	}
+	claims := map[string]interface{}{
+		"iss":   m.server.URL,
+		"sub":   "1234",
+		"aud":   "akvorado",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": m.nonce,
+	}
+	for k, v := range m.claims {
+		claims[k] = v
+	}
+	payload, _ := json.Marshal(claims)
+	signed, err := signer.Sign(payload)
+	if err != nil {
+		m.t.Fatalf("Sign() error:\n%+v", err)
+	}
+	raw, err := signed.CompactSerialize()
+	if err != nil {
+		m.t.Fatalf("CompactSerialize() error:\n%+v", err)
+	}
+	return raw
+}
+
+func TestOIDC(t *testing.T) {
+	r := reporter.NewMock(t)
+	h := http.NewMock(t, r)
+	issuer := newMockIssuer(t)
+	issuer.claims = map[string]interface{}{
+		"preferred_username": "alfred",
+		"name":               "Alfred Pennyworth",
+		"email":              "alfred@batman.com",
+		"groups":             []string{"butlers", "network"},
+	}
+	config := DefaultConfiguration()
+	config.OIDC.IssuerURL = issuer.server.URL
+	config.OIDC.ClientID = "akvorado"
+	config.OIDC.ClientSecret = "secret"
+	config.OIDC.RedirectURL = fmt.Sprintf("http://%s/api/v0/console/auth/callback", h.LocalAddr())
+	config.OIDC.CookieSecret = "0123456789abcdef0123456789abcdef"
+	if err := helpers.Validate.Struct(config); err != nil {
+		t.Fatalf("validate.Struct() error:\n%+v", err)
+	}
+	c, err := New(r, config, Dependencies{})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, c)
+
+	auth := h.GinRouter.Group("/api/v0/console/auth")
+	auth.GET("/login", c.OIDCLoginHandlerFunc)
+	auth.GET("/callback", c.OIDCCallbackHandlerFunc)
+	auth.POST("/logout", c.OIDCLogoutHandlerFunc)
+	endpoint := h.GinRouter.Group("/api/v0/console/user", c.UserAuthentication())
+	endpoint.GET("/info", c.UserInfoHandlerFunc)
+
+	client := &netHTTP.Client{
+		CheckRedirect: func(*netHTTP.Request, []*netHTTP.Request) error {
+			return netHTTP.ErrUseLastResponse
+		},
+	}
+	do := func(method string, path string, headers map[string]string, cookies ...*netHTTP.Cookie) *netHTTP.Response {
+		t.Helper()
+		req, _ := netHTTP.NewRequest(method, fmt.Sprintf("http://%s%s", h.LocalAddr(), path), nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s:\n%+v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	get := func(path string, cookies ...*netHTTP.Cookie) *netHTTP.Response {
+		t.Helper()
+		return do("GET", path, nil, cookies...)
+	}
+	cookie := func(resp *netHTTP.Response, name string) *netHTTP.Cookie {
+		t.Helper()
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == name {
+				return cookie
+			}
+		}
+		t.Fatalf("no cookie %q in response", name)
+		return nil
+	}
+	decode := func(resp *netHTTP.Response) map[string]interface{} {
+		t.Helper()
+		var got map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("Decode() error:\n%+v", err)
+		}
+		return got
+	}
+
+	// Not logged in
+	resp := get("/api/v0/console/user/info")
+	if resp.StatusCode != 401 {
+		t.Fatalf("GET /user/info: got status code %d, not 401", resp.StatusCode)
+	}
+	if diff := helpers.Diff(decode(resp), map[string]interface{}{
+		"message":   "No user logged in.",
+		"login-url": "/api/v0/console/auth/login",
+	}); diff != "" {
+		t.Fatalf("GET /user/info (-got, +want):\n%s", diff)
+	}
+	// Headers are ignored
+	req, _ := netHTTP.NewRequest("GET", fmt.Sprintf("http://%s/api/v0/console/user/info", h.LocalAddr()), nil)
+	req.Header.Add("Remote-User", "bruce")
+	if resp, err := client.Do(req); err != nil {
+		t.Fatalf("GET /user/info:\n%+v", err)
+	} else if resp.Body.Close(); resp.StatusCode != 401 {
+		t.Fatalf("GET /user/info with headers: got status code %d, not 401", resp.StatusCode)
+	}
+
+	// Login
+	resp = get("/api/v0/console/auth/login?next=/visualize")
+	if resp.StatusCode != 302 {
+		t.Fatalf("GET /auth/login: got status code %d, not 302", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Parse() error:\n%+v", err)
+	}
+	if !strings.HasPrefix(location.String(), issuer.server.URL+"/authorize?") {
+		t.Fatalf("GET /auth/login: unexpected redirect to %s", location)
+	}
+	if got := location.Query().Get("client_id"); got != "akvorado" {
+		t.Fatalf("GET /auth/login: client_id is %q", got)
+	}
+	state := location.Query().Get("state")
+	issuer.nonce = location.Query().Get("nonce")
+	stateCookie := cookie(resp, "akvorado-oidc-state")
+
+	// Callback with invalid state or code
+	resp = get("/api/v0/console/auth/callback?code=good-code&state=nope", stateCookie)
+	if resp.StatusCode != 400 {
+		t.Fatalf("GET /auth/callback with bad state: got status code %d, not 400", resp.StatusCode)
+	}
+	resp = get("/api/v0/console/auth/callback?code=bad-code&state="+state, stateCookie)
+	if resp.StatusCode != 401 {
+		t.Fatalf("GET /auth/callback with bad code: got status code %d, not 401", resp.StatusCode)
+	}
+
+	// Callback
+	resp = get("/api/v0/console/auth/callback?code=good-code&state="+state, stateCookie)
+	if resp.StatusCode != 302 {
+		t.Fatalf("GET /auth/callback: got status code %d, not 302", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Location"); got != "/visualize" {
+		t.Fatalf("GET /auth/callback: redirect to %q, not /visualize", got)
+	}
+	sessionCookie := cookie(resp, "akvorado-session")
+
+	// Logged in
+	resp = get("/api/v0/console/user/info", sessionCookie)
+	if resp.StatusCode != 200 {
+		t.Fatalf("GET /user/info: got status code %d, not 200", resp.StatusCode)
+	}
+	if diff := helpers.Diff(decode(resp), map[string]interface{}{
+		"login":      "alfred",
+		"name":       "Alfred Pennyworth",
+		"email":      "alfred@batman.com",
+		"logout-url": "/api/v0/console/auth/logout",
+		"groups":     []interface{}{"butlers", "network"},
+	}); diff != "" {
+		t.Fatalf("GET /user/info (-got, +want):\n%s", diff)
+	}
+
+	// Tampered session
+	tampered := *sessionCookie
+	tampered.Value = "e30" + tampered.Value[strings.Index(tampered.Value, "."):]
+	resp = get("/api/v0/console/user/info", &tampered)
+	if resp.StatusCode != 401 {
+		t.Fatalf("GET /user/info with tampered session: got status code %d, not 401", resp.StatusCode)
+	}
+
+	// Logout with GET or from another site
+	resp = get("/api/v0/console/auth/logout", sessionCookie)
+	if resp.StatusCode != 404 {
+		t.Fatalf("GET /auth/logout: got status code %d, not 404", resp.StatusCode)
+	}
+	resp = do("POST", "/api/v0/console/auth/logout",
+		map[string]string{"Sec-Fetch-Site": "cross-site"}, sessionCookie)
+	if resp.StatusCode != 403 {
+		t.Fatalf("POST /auth/logout from another site: got status code %d, not 403", resp.StatusCode)
+	}
+	resp = do("POST", "/api/v0/console/auth/logout",
+		map[string]string{"Origin": "https://evil.example.com"}, sessionCookie)
+	if resp.StatusCode != 403 {
+		t.Fatalf("POST /auth/logout from another origin: got status code %d, not 403", resp.StatusCode)
+	}
+
+	// Logout
+	resp = do("POST", "/api/v0/console/auth/logout",
+		map[string]string{
+			"Sec-Fetch-Site": "same-origin",
+			"Origin":         fmt.Sprintf("http://%s", h.LocalAddr()),
+		}, sessionCookie)
+	if resp.StatusCode != 303 {
+		t.Fatalf("POST /auth/logout: got status code %d, not 303", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Location"); got != issuer.server.URL+"/logout" {
+		t.Fatalf("POST /auth/logout: redirect to %q", got)
+	}
+	if got := cookie(resp, "akvorado-session"); got.MaxAge >= 0 {
+		t.Fatalf("POST /auth/logout: session cookie not removed")
+	}
+}
+
+func TestLocalRedirect(t *testing.T) {
+	cases := []struct {
+		Next     string
+		Expected string
+	}{
+		{"/visualize", "/visualize"},
+		{"/visualize?filter=1#top", "/visualize?filter=1#top"},
+		{"", "/"},
+		{"visualize", "/"},
+		{"//evil.com", "/"},
+		{"/\\evil.com", "/"},
+		{"/%5Cevil.com", "/"},
+		{"/%2F/evil.com", "/"},
+		{"https://evil.com/", "/"},
+		{"javascript:alert(1)", "/"},
+		{"/\t/evil.com", "/"},
+	}
+	for _, tc := range cases {
+		if got := localRedirect(tc.Next); got != tc.Expected {
+			t.Errorf("localRedirect(%q) == %q but expected %q", tc.Next, got, tc.Expected)
+		}
+	}
+}
+
+func TestUserFromClaims(t *testing.T) {
+	r := reporter.NewMock(t)
+	c, err := New(r, DefaultConfiguration(), Dependencies{})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	cases := []struct {
+		Description string
+		Claims      map[string]interface{}
+		Expected    UserInformation
+		Error       bool
+	}{
+		{
+			Description: "minimal",
+			Claims:      map[string]interface{}{"preferred_username": "alfred"},
+			Expected:    UserInformation{Login: "alfred", LogoutURL: OIDCLogoutURL},
+		}, {
+			Description: "single group",
+			Claims: map[string]interface{}{
+				"preferred_username": "alfred",
+				"groups":             "butlers",
+			},
+			Expected: UserInformation{
+				Login:     "alfred",
+				LogoutURL: OIDCLogoutURL,
+				Groups:    []string{"butlers"},
+			},
+		}, {
+			Description: "no login",
+			Claims:      map[string]interface{}{"name": "Alfred"},
+			Error:       true,
+		}, {
+			Description: "invalid email",
+			Claims: map[string]interface{}{
+				"preferred_username": "alfred",
+				"email":              "alfrednooo",
+			},
+			Error: true,
+		},
+	}
+	for _, tc := range cases {
+		got, err := c.userFromClaims(tc.Claims)
+		if err != nil && !tc.Error {
+			t.Errorf("userFromClaims(%s) error:\n%+v", tc.Description, err)
+		} else if err == nil && tc.Error {
+			t.Errorf("userFromClaims(%s) did not error", tc.Description)
+		} else if diff := helpers.Diff(got, tc.Expected); diff != "" {
+			t.Errorf("userFromClaims(%s) (-got, +want):\n%s", tc.Description, diff)
+		}
+	}
+}
@@ -4,20 +4,41 @@
 // Package authentication handles user authentication for the console.
 package authentication
 
-import "akvorado/common/reporter"
+import (
+	"akvorado/common/reporter"
+	"akvorado/console/database"
+)
 
 // Component represents the authentication compomenent.
 type Component struct {
 	r      *reporter.Reporter
+	d      *Dependencies
 	config Configuration
+
+	oidc *oidcState
+}
+
+// Dependencies define the dependencies of the authentication component.
+type Dependencies struct {
+	Database *database.Component
 }
 
 // New creates a new authentication component.
-func New(r *reporter.Reporter, configuration Configuration) (*Component, error) {
+func New(r *reporter.Reporter, configuration Configuration, dependencies Dependencies) (*Component, error) {
 	c := Component{
 		r:      r,
+		d:      &dependencies,
 		config: configuration,
 	}
 
 	return &c, nil
 }
+
+// Start starts the authentication component.
+func (c *Component) Start() error {
+	if !c.oidcEnabled() {
+		return nil
+	}
+	c.r.Info().Msg("starting authentication component")
+	return c.startOIDC()
+}
@@ -9,12 +9,13 @@ import (
 	"testing"
 
 	"akvorado/common/reporter"
+	"akvorado/console/database"
 )
 
 // NewMock instantiantes a new authentication component
-func NewMock(t *testing.T, r *reporter.Reporter) *Component {
+func NewMock(t *testing.T, r *reporter.Reporter, db *database.Component) *Component {
 	t.Helper()
-	c, err := New(r, DefaultConfiguration())
+	c, err := New(r, DefaultConfiguration(), Dependencies{Database: db})
 	if err != nil {
 		t.Fatalf("New() error:\n%+v", err)
 	}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package authentication
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"akvorado/common/helpers"
+	"akvorado/console/database"
+)
+
+// APITokenListHandlerFunc lists the API tokens of the current user.
+func (c *Component) APITokenListHandlerFunc(gc *gin.Context) {
+	user := gc.MustGet("user").(UserInformation).Login
+	tokens, err := c.d.Database.ListAPITokens(gc.Request.Context(), user)
+	if err != nil {
+		c.r.Err(err).Msg("unable to list API tokens")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to list API tokens."})
+		return
+	}
+	gc.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// APITokenCreateHandlerFunc creates a new API token for the current
+// user. The token is only returned once. An API token cannot be used
+// to create another one. The token inherits the current groups of the
+// user.
+func (c *Component) APITokenCreateHandlerFunc(gc *gin.Context) {
+	if _, ok := gc.Get("api-token"); ok {
+		gc.JSON(http.StatusForbidden, gin.H{"message": "API tokens cannot create other API tokens."})
+		return
+	}
+	user := gc.MustGet("user").(UserInformation)
+	var input database.APIToken
+	if err := gc.ShouldBindJSON(&input); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	input.User = user.Login
+	input.Groups = user.Groups
+	apiToken, token, err := c.d.Database.CreateAPIToken(gc.Request.Context(), input)
+	if err != nil {
+		c.r.Err(err).Msg("cannot create API token")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Cannot create new API token."})
+		return
+	}
+	gc.JSON(http.StatusCreated, gin.H{"id": apiToken.ID, "token": token})
+}
+
+// APITokenDeleteHandlerFunc revokes an API token of the current user.
+func (c *Component) APITokenDeleteHandlerFunc(gc *gin.Context) {
+	user := gc.MustGet("user").(UserInformation).Login
+	id, err := strconv.ParseUint(gc.Param("id"), 10, 64)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": "Bad ID format."})
+		return
+	}
+	err = c.d.Database.DeleteAPIToken(gc.Request.Context(), database.APIToken{
+		ID:   id,
+		User: user,
+	})
+	if errors.Is(err, database.ErrNotFound) {
+		gc.JSON(http.StatusNotFound, gin.H{"message": "API token not found."})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("cannot delete API token")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Cannot delete API token."})
+		return
+	}
+	gc.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package authentication
+
+import (
+	"context"
+	"fmt"
+	netHTTP "net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"akvorado/common/helpers"
+	"akvorado/common/http"
+	"akvorado/common/reporter"
+	"akvorado/console/database"
+)
+
+func TestAPITokens(t *testing.T) {
+	r := reporter.NewMock(t)
+	h := http.NewMock(t, r)
+	db := database.NewMock(t, r, database.DefaultConfiguration())
+	c := NewMock(t, r, db)
+	c.config.DefaultUser.Login = ""
+
+	endpoint := h.GinRouter.Group("/api/v0/console/user", c.UserAuthentication())
+	endpoint.GET("/info", c.UserInfoHandlerFunc)
+	endpoint.GET("/tokens", c.APITokenListHandlerFunc)
+	endpoint.POST("/tokens", c.APITokenCreateHandlerFunc)
+	endpoint.DELETE("/tokens/:id", c.APITokenDeleteHandlerFunc)
+
+	_, token, err := db.CreateAPIToken(context.Background(), database.APIToken{
+		User:        "alfred",
+		Description: "automation",
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIToken() error:\n%+v", err)
+	}
+	withToken := func(token string) netHTTP.Header {
+		headers := make(netHTTP.Header)
+		headers.Add("Authorization", "Bearer "+token)
+		return headers
+	}
+	asUser := func(user string) netHTTP.Header {
+		headers := make(netHTTP.Header)
+		headers.Add("Remote-User", user)
+		return headers
+	}
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "user info with API token",
+			URL:         "/api/v0/console/user/info",
+			Header:      withToken(token),
+			JSONOutput:  gin.H{"login": "alfred"},
+		}, {
+			Description: "user info with invalid API token",
+			URL:         "/api/v0/console/user/info",
+			Header:      withToken("akvorado_nope"),
+			StatusCode:  401,
+			JSONOutput:  gin.H{"message": "Invalid API token."},
+		}, {
+			Description: "create API token with API token",
+			URL:         "/api/v0/console/user/tokens",
+			Header:      withToken(token),
+			JSONInput:   gin.H{"description": "another one"},
+			StatusCode:  403,
+			JSONOutput:  gin.H{"message": "API tokens cannot create other API tokens."},
+		}, {
+			Description: "create API token without description",
+			URL:         "/api/v0/console/user/tokens",
+			Header:      asUser("alfred"),
+			JSONInput:   gin.H{},
+			StatusCode:  400,
+			JSONOutput: gin.H{
+				"message": "Key: 'APIToken.Description' Error:Field validation for 'Description' failed on the 'required' tag",
+			},
+		}, {
+			Description: "list API tokens as another user",
+			URL:         "/api/v0/console/user/tokens",
+			Header:      asUser("bruce"),
+			JSONOutput:  gin.H{"tokens": []gin.H{}},
+		}, {
+			Description: "revoke API token as another user",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/user/tokens/1",
+			Header:      asUser("bruce"),
+			StatusCode:  404,
+			JSONOutput:  gin.H{"message": "API token not found."},
+		}, {
+			Description: "revoke API token",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/user/tokens/1",
+			Header:      asUser("alfred"),
+			StatusCode:  204,
+			ContentType: "application/json; charset=utf-8",
+		}, {
+			Description: "user info with revoked API token",
+			URL:         "/api/v0/console/user/info",
+			Header:      withToken(token),
+			StatusCode:  401,
+			JSONOutput:  gin.H{"message": "Invalid API token."},
+		},
+	})
+
+	// The token should be gone
+	tokens, err := db.ListAPITokens(context.Background(), "alfred")
+	if err != nil {
+		t.Fatalf("ListAPITokens() error:\n%+v", err)
+	}
+	if len(tokens) != 0 {
+		t.Fatalf("ListAPITokens() should be empty, got %d tokens", len(tokens))
+	}
+
+	// Groups of the user are recorded with the token, not the ones
+	// provided in the request
+	req, _ := netHTTP.NewRequest("POST",
+		fmt.Sprintf("http://%s/api/v0/console/user/tokens", h.LocalAddr()),
+		strings.NewReader(`{"description": "with groups", "groups": ["admins"]}`))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Remote-User", "alfred")
+	req.Header.Add("Remote-Groups", "butlers, network")
+	resp, err := netHTTP.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/v0/console/user/tokens:\n%+v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 201 {
+		t.Fatalf("POST /api/v0/console/user/tokens: got status code %d, not 201", resp.StatusCode)
+	}
+	tokens, err = db.ListAPITokens(context.Background(), "alfred")
+	if err != nil {
+		t.Fatalf("ListAPITokens() error:\n%+v", err)
+	}
+	if len(tokens) != 1 {
+		t.Fatalf("ListAPITokens() should have one token, got %d tokens", len(tokens))
+	}
+	if diff := helpers.Diff(tokens[0].Groups, []string{"butlers", "network"}); diff != "" {
+		t.Fatalf("ListAPITokens() (-got, +want):\n%s", diff)
+	}
+}
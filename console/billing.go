@@ -0,0 +1,258 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"akvorado/common/helpers"
+)
+
+// billingResolution is the duration of each sample used to compute
+// the 95th percentile.
+const billingResolution = 5 * time.Minute
+
+// billingHandlerInput describes the input for the /billing endpoint.
+type billingHandlerInput struct {
+	Month string `form:"month"` // YYYY-MM, default to the current month
+	By    string `form:"by" binding:"isdefault|oneof=provider interface"`
+	Units string `form:"units" binding:"isdefault|oneof=l3bps l2bps"`
+}
+
+// billingSample is the rate for a direction of a provider or an
+// interface during a 5-minute interval.
+type billingSample struct {
+	Direction string    `ch:"direction"`
+	Time      time.Time `ch:"time"`
+	Name      string    `ch:"name"`
+	Xps       float64   `ch:"xps"`
+}
+
+// billingRow is the billing report for a provider or an interface.
+// Rates are in bits per second.
+type billingRow struct {
+	Name     string `json:"name"`
+	In       uint64 `json:"in"`
+	Out      uint64 `json:"out"`
+	Billable uint64 `json:"billable"`
+	Commit   uint64 `json:"commit"`
+	Overage  uint64 `json:"overage"`
+}
+
+// billingHandlerOutput describes the output for the /billing endpoint.
+type billingHandlerOutput struct {
+	Start   time.Time    `json:"start"`
+	End     time.Time    `json:"end"`
+	Samples int          `json:"samples"`
+	Rows    []billingRow `json:"rows"`
+}
+
+// billingTable returns the name of the table with a 5-minute
+// resolution.
+func (c *Component) billingTable() (string, bool) {
+	c.flowsTablesLock.RLock()
+	defer c.flowsTablesLock.RUnlock()
+	for _, table := range c.flowsTables {
+		if table.Resolution == billingResolution {
+			return table.Name, true
+		}
+	}
+	return "", false
+}
+
+// toSQL builds the SQL query returning the rate of each sample for
+// each direction of each provider or interface. The inbound direction
+// is the traffic received from the provider or on the interface.
+func (input billingHandlerInput) toSQL(table string, start, end time.Time, scope string) string {
+	var inName, outName, inWhere, outWhere string
+	switch input.By {
+	case "interface":
+		inName = `concat(ExporterName, '/', InIfName)`
+		outName = `concat(ExporterName, '/', OutIfName)`
+		inWhere = `InIfBoundary = 'external'`
+		outWhere = `OutIfBoundary = 'external'`
+	default:
+		inName = `InIfProvider`
+		outName = `OutIfProvider`
+		inWhere = `InIfProvider != ''`
+		outWhere = `OutIfProvider != ''`
+	}
+	units := `Bytes*SamplingRate*8`
+	if input.Units == "l2bps" {
+		units = `(Bytes+18*Packets)*SamplingRate*8`
+	}
+	timefilter := fmt.Sprintf(`TimeReceived >= toDateTime('%s', 'UTC') AND TimeReceived < toDateTime('%s', 'UTC')`,
+		start.UTC().Format("2006-01-02 15:04:05"),
+		end.UTC().Format("2006-01-02 15:04:05"))
+	part := func(direction, name, where string) string {
+		return fmt.Sprintf(`
+SELECT
+ '%s' AS direction,
+ toStartOfFiveMinutes(TimeReceived) AS time,
+ %s AS name,
+ SUM(%s)/%d AS xps
+FROM %s
+WHERE %s AND %s%s
+GROUP BY time, name`,
+			direction, name, units, uint64(billingResolution.Seconds()),
+			table, timefilter, where, scope)
+	}
+	return strings.TrimSpace(part("in", inName, inWhere) +
+		"\nUNION ALL" +
+		part("out", outName, outWhere))
+}
+
+// percentile95 returns the 95th percentile of the provided samples
+// using the usual billing method: samples are sorted, the top 5% are
+// discarded and the highest remaining one is used. Missing samples
+// are considered to be 0.
+func percentile95(samples []float64, total int) uint64 {
+	if total < len(samples) {
+		total = len(samples)
+	}
+	if total == 0 {
+		return 0
+	}
+	sorted := make([]float64, total)
+	copy(sorted[total-len(samples):], samples)
+	sort.Float64s(sorted)
+	index := (95*total+99)/100 - 1
+	return uint64(sorted[index])
+}
+
+func (c *Component) billingHandlerFunc(gc *gin.Context) {
+	var input billingHandlerInput
+	if err := gc.ShouldBindQuery(&input); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	format, ok := exportFormat(gc)
+	if !ok {
+		return
+	}
+	if input.By == "" {
+		input.By = "provider"
+	}
+	if input.Units == "" {
+		input.Units = "l3bps"
+	}
+	commits := c.config.Billing.Providers
+	if input.By == "interface" {
+		commits = c.config.Billing.Interfaces
+	}
+
+	// Compute the boundaries of the requested month
+	now := c.d.Clock.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if input.Month != "" {
+		var err error
+		start, err = time.Parse("2006-01", input.Month)
+		if err != nil {
+			gc.JSON(http.StatusBadRequest, gin.H{"message": "Month should use the YYYY-MM format."})
+			return
+		}
+	}
+	end := start.AddDate(0, 1, 0)
+	if end.After(now) {
+		end = now.Truncate(billingResolution)
+	}
+	if !end.After(start) {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": "Month should not be in the future."})
+		return
+	}
+	samples := int(end.Sub(start) / billingResolution)
+
+	table, ok := c.billingTable()
+	if !ok {
+		gc.JSON(http.StatusServiceUnavailable,
+			gin.H{"message": "No flows table with a 5-minute resolution available."})
+		return
+	}
+	if c.scopeFilter(gc).MainTableRequired {
+		gc.JSON(http.StatusForbidden,
+			gin.H{"message": "Billing reports are not available with your restrictions."})
+		return
+	}
+	sqlQuery := input.toSQL(table, start, end, c.scopeSQL(gc))
+	gc.Header("X-SQL-Query", strings.ReplaceAll(sqlQuery, "\n", "  "))
+	c.metrics.clickhouseQueries.WithLabelValues(table).Inc()
+
+	results := []billingSample{}
+	if err := c.cachedSelect(gc, &results, sqlQuery, billingResolution); err != nil {
+		c.r.Err(err).Msg("unable to query database")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to query database."})
+		return
+	}
+
+	// Collect samples for each name. Configured commits are always
+	// reported, even without traffic.
+	inSamples := map[string][]float64{}
+	outSamples := map[string][]float64{}
+	for name := range commits {
+		inSamples[name] = nil
+	}
+	for _, result := range results {
+		switch result.Direction {
+		case "in":
+			inSamples[result.Name] = append(inSamples[result.Name], result.Xps)
+		case "out":
+			outSamples[result.Name] = append(outSamples[result.Name], result.Xps)
+		}
+	}
+	names := []string{}
+	for name := range inSamples {
+		names = append(names, name)
+	}
+	for name := range outSamples {
+		if _, ok := inSamples[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	output := billingHandlerOutput{
+		Start:   start,
+		End:     end,
+		Samples: samples,
+		Rows:    make([]billingRow, 0, len(names)),
+	}
+	for _, name := range names {
+		row := billingRow{
+			Name:   name,
+			In:     percentile95(inSamples[name], samples),
+			Out:    percentile95(outSamples[name], samples),
+			Commit: commits[name],
+		}
+		row.Billable = row.In
+		if row.Out > row.Billable {
+			row.Billable = row.Out
+		}
+		if row.Commit > 0 && row.Billable > row.Commit {
+			row.Overage = row.Billable - row.Commit
+		}
+		output.Rows = append(output.Rows, row)
+	}
+
+	if format != "" {
+		tw := newTabularWriter(gc, format, fmt.Sprintf("billing-%s", start.Format("2006-01")),
+			[]string{"name", "in", "out", "billable", "commit", "overage"})
+		for _, row := range output.Rows {
+			if err := tw.write(row.Name, row.In, row.Out, row.Billable, row.Commit, row.Overage); err != nil {
+				c.r.Err(err).Msg("unable to export row")
+				break
+			}
+		}
+		if err := tw.close(); err != nil {
+			c.r.Err(err).Msg("unable to export rows")
+		}
+		return
+	}
+	gc.JSON(http.StatusOK, output)
+}
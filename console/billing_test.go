@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+
+	"akvorado/common/helpers"
+)
+
+func TestPercentile95(t *testing.T) {
+	cases := []struct {
+		Samples  []float64
+		Total    int
+		Expected uint64
+	}{
+		{nil, 0, 0},
+		{nil, 100, 0},
+		{[]float64{10}, 1, 10},
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, 20, 19},
+		{[]float64{100, 100, 100, 100, 100}, 100, 0},
+		{[]float64{100, 100, 100, 100, 100, 100}, 100, 100},
+		{[]float64{100, 200}, 1, 200},
+	}
+	for _, tc := range cases {
+		if got := percentile95(tc.Samples, tc.Total); got != tc.Expected {
+			t.Errorf("percentile95(%v, %d) == %d but expected %d",
+				tc.Samples, tc.Total, got, tc.Expected)
+		}
+	}
+}
+
+func TestBillingQuery(t *testing.T) {
+	input := billingHandlerInput{By: "interface", Units: "l2bps"}
+	got := input.toSQL("flows_5m0s",
+		time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC),
+		" AND (ExporterTenant = 'acme')")
+	expected := strings.TrimSpace(`
+SELECT
+ 'in' AS direction,
+ toStartOfFiveMinutes(TimeReceived) AS time,
+ concat(ExporterName, '/', InIfName) AS name,
+ SUM((Bytes+18*Packets)*SamplingRate*8)/300 AS xps
+FROM flows_5m0s
+WHERE TimeReceived >= toDateTime('2022-11-01 00:00:00', 'UTC') AND TimeReceived < toDateTime('2022-12-01 00:00:00', 'UTC') AND InIfBoundary = 'external' AND (ExporterTenant = 'acme')
+GROUP BY time, name
+UNION ALL
+SELECT
+ 'out' AS direction,
+ toStartOfFiveMinutes(TimeReceived) AS time,
+ concat(ExporterName, '/', OutIfName) AS name,
+ SUM((Bytes+18*Packets)*SamplingRate*8)/300 AS xps
+FROM flows_5m0s
+WHERE TimeReceived >= toDateTime('2022-11-01 00:00:00', 'UTC') AND TimeReceived < toDateTime('2022-12-01 00:00:00', 'UTC') AND OutIfBoundary = 'external' AND (ExporterTenant = 'acme')
+GROUP BY time, name`)
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("toSQL() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestBillingHandler(t *testing.T) {
+	config := DefaultConfiguration()
+	config.Billing.Providers = map[string]uint64{
+		"provider1": 1_000_000_000,
+		"provider3": 500_000_000,
+	}
+	c, h, mockConn, mockClock := NewMock(t, config)
+	mockClock.Set(time.Date(2022, 12, 15, 10, 0, 0, 0, time.UTC))
+
+	// Without the appropriate table, we cannot answer
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "no 5-minute table",
+			URL:         "/api/v0/console/billing?month=2022-11",
+			StatusCode:  503,
+			JSONOutput:  gin.H{"message": "No flows table with a 5-minute resolution available."},
+		},
+	})
+
+	c.flowsTablesLock.Lock()
+	c.flowsTables = []flowsTable{
+		{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "flows_5m0s", Resolution: 5 * time.Minute, Oldest: time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	c.flowsTablesLock.Unlock()
+
+	// A month has 8640 samples. For provider1, 500 samples above
+	// 5% in inbound direction and 100 samples (below 5%) in
+	// outbound direction. For provider2, 1000 samples in outbound
+	// direction.
+	results := []billingSample{}
+	start := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 1000; i++ {
+		ts := start.Add(time.Duration(i) * 5 * time.Minute)
+		if i < 500 {
+			results = append(results, billingSample{"in", ts, "provider1", 2_000_000_000})
+		}
+		if i < 100 {
+			results = append(results, billingSample{"out", ts, "provider1", 3_000_000_000})
+		}
+		results = append(results, billingSample{"out", ts, "provider2", 500_000_000})
+	}
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Any()).
+		SetArg(1, results).
+		Return(nil)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "invalid month",
+			URL:         "/api/v0/console/billing?month=november",
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": "Month should use the YYYY-MM format."},
+		}, {
+			Description: "future month",
+			URL:         "/api/v0/console/billing?month=2023-01",
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": "Month should not be in the future."},
+		}, {
+			Description: "JSON report",
+			URL:         "/api/v0/console/billing?month=2022-11",
+			JSONOutput: gin.H{
+				"start":   "2022-11-01T00:00:00Z",
+				"end":     "2022-12-01T00:00:00Z",
+				"samples": 8640,
+				"rows": []gin.H{
+					{
+						"name":     "provider1",
+						"in":       2e9,
+						"out":      0,
+						"billable": 2e9,
+						"commit":   1e9,
+						"overage":  1e9,
+					}, {
+						"name":     "provider2",
+						"in":       0,
+						"out":      5e8,
+						"billable": 5e8,
+						"commit":   0,
+						"overage":  0,
+					}, {
+						"name":     "provider3",
+						"in":       0,
+						"out":      0,
+						"billable": 0,
+						"commit":   5e8,
+						"overage":  0,
+					},
+				},
+			},
+		}, {
+			Description: "CSV report from cache",
+			URL:         "/api/v0/console/billing?month=2022-11&format=csv",
+			ContentType: "text/csv; charset=utf-8",
+			FirstLines: []string{
+				"name,in,out,billable,commit,overage",
+				"provider1,2000000000,0,2000000000,1000000000,1000000000",
+				"provider2,0,500000000,500000000,0,0",
+				"provider3,0,0,0,500000000,0",
+			},
+		},
+	})
+}
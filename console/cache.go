@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/gin-gonic/gin"
+
+	"akvorado/common/reporter"
+)
+
+// queryCache caches the results of ClickHouse queries. Results are
+// kept in memory and, optionally, in memcached to share them between
+// several console instances.
+type queryCache struct {
+	r       *reporter.Reporter
+	clock   clock.Clock
+	size    int
+	entries map[string]queryCacheEntry
+	lock    sync.Mutex
+
+	memcache *memcache.Client
+}
+
+// queryCacheEntry is an entry in the in-memory cache.
+type queryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// newQueryCache creates a new query cache. It returns nil when caching
+// is disabled.
+func newQueryCache(r *reporter.Reporter, clock clock.Clock, config CacheConfiguration) *queryCache {
+	if config.Size == 0 && len(config.Memcached) == 0 {
+		return nil
+	}
+	qc := &queryCache{
+		r:       r,
+		clock:   clock,
+		size:    config.Size,
+		entries: make(map[string]queryCacheEntry),
+	}
+	if len(config.Memcached) > 0 {
+		qc.memcache = memcache.New(config.Memcached...)
+	}
+	return qc
+}
+
+// get retrieves an entry from the cache.
+func (qc *queryCache) get(key string) ([]byte, bool) {
+	if qc.size > 0 {
+		qc.lock.Lock()
+		entry, ok := qc.entries[key]
+		qc.lock.Unlock()
+		if ok && qc.clock.Now().Before(entry.expires) {
+			return entry.value, true
+		}
+	}
+	if qc.memcache != nil {
+		item, err := qc.memcache.Get(key)
+		if err != nil {
+			if !errors.Is(err, memcache.ErrCacheMiss) {
+				qc.r.Err(err).Msg("cannot query memcached")
+			}
+			return nil, false
+		}
+		return item.Value, true
+	}
+	return nil, false
+}
+
+// set stores an entry into the cache.
+func (qc *queryCache) set(key string, value []byte, ttl time.Duration) {
+	if qc.size > 0 {
+		now := qc.clock.Now()
+		qc.lock.Lock()
+		if _, ok := qc.entries[key]; !ok && len(qc.entries) >= qc.size {
+			qc.evict(now)
+		}
+		qc.entries[key] = queryCacheEntry{
+			value:   value,
+			expires: now.Add(ttl),
+		}
+		qc.lock.Unlock()
+	}
+	if qc.memcache != nil {
+		if err := qc.memcache.Set(&memcache.Item{
+			Key:        key,
+			Value:      value,
+			Expiration: int32(ttl.Seconds()),
+		}); err != nil {
+			qc.r.Err(err).Msg("cannot store results into memcached")
+		}
+	}
+}
+
+// evict removes expired entries from the in-memory cache. If none
+// expired, the entry expiring first is removed. The lock should be
+// held.
+func (qc *queryCache) evict(now time.Time) {
+	var oldestKey string
+	var oldestExpires time.Time
+	for key, entry := range qc.entries {
+		if !now.Before(entry.expires) {
+			delete(qc.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.expires.Before(oldestExpires) {
+			oldestKey = key
+			oldestExpires = entry.expires
+		}
+	}
+	if len(qc.entries) >= qc.size {
+		delete(qc.entries, oldestKey)
+	}
+}
+
+// cachedSelect executes the provided SELECT query and stores the
+// results into dest, unless they are already present in the cache.
+// The resolution of the queried table tells how long results are
+// kept. Caching can be bypassed with a "Cache-Control: no-cache"
+// header.
+func (c *Component) cachedSelect(gc *gin.Context, dest interface{}, query string, resolution time.Duration) error {
+	ctx := c.t.Context(gc.Request.Context())
+	if c.cache == nil || strings.Contains(gc.GetHeader("Cache-Control"), "no-cache") {
+		return c.d.ClickHouseDB.Conn.Select(ctx, dest, query)
+	}
+
+	// The version is part of the key as the format of the results
+	// may change between versions.
+	sum := sha256.Sum256([]byte(c.config.Version + "\x00" + query))
+	key := "akvorado-console-" + hex.EncodeToString(sum[:])
+	if value, ok := c.cache.get(key); ok {
+		if err := json.Unmarshal(value, dest); err == nil {
+			c.metrics.cacheHits.Inc()
+			gc.Header("X-Cache", "hit")
+			return nil
+		}
+		// Discard any partially decoded result
+		reflect.ValueOf(dest).Elem().Set(reflect.Zero(reflect.TypeOf(dest).Elem()))
+	}
+	c.metrics.cacheMisses.Inc()
+	gc.Header("X-Cache", "miss")
+
+	if err := c.d.ClickHouseDB.Conn.Select(ctx, dest, query); err != nil {
+		return err
+	}
+	value, err := json.Marshal(dest)
+	if err != nil {
+		c.r.Err(err).Msg("cannot encode results for cache")
+		return nil
+	}
+	ttl := resolution
+	if ttl < c.config.Cache.MinTTL {
+		ttl = c.config.Cache.MinTTL
+	}
+	if ttl > c.config.Cache.MaxTTL {
+		ttl = c.config.Cache.MaxTTL
+	}
+	c.cache.set(key, value, ttl)
+	return nil
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	netHTTP "net/http"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestQueryCacheEviction(t *testing.T) {
+	r := reporter.NewMock(t)
+	mockClock := clock.NewMock()
+	qc := newQueryCache(r, mockClock, CacheConfiguration{Size: 2})
+
+	qc.set("key1", []byte("value1"), 10*time.Second)
+	qc.set("key2", []byte("value2"), 20*time.Second)
+	qc.set("key3", []byte("value3"), 30*time.Second)
+	if _, ok := qc.get("key1"); ok {
+		t.Error("get(key1) should have been evicted")
+	}
+	for _, key := range []string{"key2", "key3"} {
+		if _, ok := qc.get(key); !ok {
+			t.Errorf("get(%s) should be present", key)
+		}
+	}
+
+	mockClock.Add(25 * time.Second)
+	if _, ok := qc.get("key2"); ok {
+		t.Error("get(key2) should have expired")
+	}
+	qc.set("key4", []byte("value4"), 10*time.Second)
+	for _, key := range []string{"key3", "key4"} {
+		if value, ok := qc.get(key); !ok {
+			t.Errorf("get(%s) should be present", key)
+		} else if diff := helpers.Diff(string(value), "value"+key[3:]); diff != "" {
+			t.Errorf("get(%s) (-got, +want):\n%s", key, diff)
+		}
+	}
+}
+
+func TestQueryCacheDisabled(t *testing.T) {
+	r := reporter.NewMock(t)
+	if qc := newQueryCache(r, clock.NewMock(), CacheConfiguration{}); qc != nil {
+		t.Fatal("newQueryCache() should return nil when disabled")
+	}
+}
+
+func TestCachedSelect(t *testing.T) {
+	_, h, mockConn, mockClock := NewMock(t, DefaultConfiguration())
+
+	query := `SELECT ExporterName FROM exporters GROUP BY ExporterName ORDER BY ExporterName`
+	expected := []struct {
+		ExporterName string
+	}{
+		{"exporter1"},
+		{"exporter2"},
+	}
+	// Only three queries should reach the database: the first one,
+	// the one bypassing the cache and the one after expiration.
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), query).
+		SetArg(1, expected).
+		Return(nil).
+		Times(3)
+
+	noCache := make(netHTTP.Header)
+	noCache.Add("Cache-Control", "no-cache")
+	output := gin.H{"exporters": []string{"exporter1", "exporter2"}}
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "first query",
+			URL:         "/api/v0/console/widget/exporters",
+			JSONOutput:  output,
+		}, {
+			Description: "cached query",
+			URL:         "/api/v0/console/widget/exporters",
+			JSONOutput:  output,
+		}, {
+			Description: "bypass cache",
+			URL:         "/api/v0/console/widget/exporters",
+			Header:      noCache,
+			JSONOutput:  output,
+		},
+	})
+
+	mockClock.Add(11 * time.Second)
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "expired query",
+			URL:         "/api/v0/console/widget/exporters",
+			JSONOutput:  output,
+		},
+	})
+}
@@ -8,70 +8,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"sort"
 	"strings"
 	"text/template"
 	"time"
+
+	"akvorado/common/schema"
 )
 
 // flowsTable describe a consolidated or unconsolidated flows table.
-type flowsTable struct {
-	Name       string
-	Resolution time.Duration
-	Oldest     time.Time
-}
+type flowsTable = schema.Table
 
 // refreshFlowsTables refreshes the information we have about flows
 // tables (live one and consolidated ones). This information includes
 // the consolidation interval and the oldest available data.
 func (c *Component) refreshFlowsTables() error {
-	ctx := c.t.Context(nil)
-	var tables []struct {
-		Name string `ch:"name"`
-	}
-	err := c.d.ClickHouseDB.Select(ctx, &tables, `
-SELECT name
-FROM system.tables
-WHERE database=currentDatabase()
-AND table LIKE 'flows%'
-AND engine LIKE '%MergeTree'
-`)
+	newFlowsTables, err := schema.ListTables(c.t.Context(nil), c.r, c.d.ClickHouseDB)
 	if err != nil {
-		return fmt.Errorf("cannot query flows table metadata: %w", err)
+		return err
 	}
-
-	newFlowsTables := []flowsTable{}
-	for _, table := range tables {
-		// Parse resolution
-		resolution := time.Duration(0)
-		if strings.HasPrefix(table.Name, "flows_") {
-			var err error
-			resolution, err = time.ParseDuration(strings.TrimPrefix(table.Name, "flows_"))
-			if err != nil {
-				c.r.Err(err).Msgf("cannot parse duration for table %s", table.Name)
-				continue
-			}
-		}
-		// Get oldest timestamp
-		var oldest []struct {
-			T time.Time `ch:"t"`
-		}
-		err := c.d.ClickHouseDB.Conn.Select(ctx, &oldest,
-			fmt.Sprintf(`SELECT MIN(TimeReceived) AS t FROM %s`, table.Name))
-		if err != nil {
-			return fmt.Errorf("cannot query table %s for oldest timestamp: %w", table.Name, err)
-		}
-
-		newFlowsTables = append(newFlowsTables, flowsTable{
-			Name:       table.Name,
-			Resolution: resolution,
-			Oldest:     oldest[0].T,
-		})
-	}
-	if len(newFlowsTables) == 0 {
-		return errors.New("no flows table present (yet?)")
-	}
-
 	c.flowsTablesLock.Lock()
 	c.flowsTables = newFlowsTables
 	c.flowsTablesLock.Unlock()
@@ -80,11 +34,19 @@ AND engine LIKE '%MergeTree'
 
 // finalizeQuery builds the finalized query. A single "context"
 // function is provided to return a `Context` struct with all the
-// information needed.
-func (c *Component) finalizeQuery(query string) string {
+// information needed. It also returns the finest resolution of the
+// selected tables, to know how long the results can be cached.
+func (c *Component) finalizeQuery(query string) (string, time.Duration) {
+	var resolution time.Duration
 	t := template.Must(template.New("query").
 		Funcs(template.FuncMap{
-			"context": c.contextFunc,
+			"context": func(input string) context {
+				result := c.contextFunc(input)
+				if resolution == 0 || result.resolution < resolution {
+					resolution = result.resolution
+				}
+				return result
+			},
 		}).
 		Option("missingkey=error").
 		Parse(strings.TrimSpace(query)))
@@ -93,7 +55,7 @@ func (c *Component) finalizeQuery(query string) string {
 		c.r.Err(err).Str("query", query).Msg("invalid query")
 		panic(err)
 	}
-	return buf.String()
+	return buf.String(), resolution
 }
 
 type inputContext struct {
@@ -113,6 +75,8 @@ type context struct {
 	Units             string
 	Interval          uint64
 	ToStartOfInterval func(string) string
+
+	resolution time.Duration
 }
 
 // templateEscape escapes `{{` and `}}` from a string. In fact, only
@@ -149,12 +113,14 @@ func (c *Component) contextFunc(inputStr string) context {
 		targetInterval = time.Second
 	}
 
-	// Select table
+	// Select table. Flows are merged in consolidated tables, so
+	// they can only be counted from the main table.
 	targetIntervalForTableSelection := targetInterval
-	if input.MainTableRequired {
+	if input.MainTableRequired || input.Units == "flows" {
 		targetIntervalForTableSelection = time.Second
 	}
 	table, computedInterval := c.getBestTable(input.Start, targetIntervalForTableSelection)
+	resolution := computedInterval
 	if input.StartForInterval != nil {
 		_, computedInterval = c.getBestTable(*input.StartForInterval, targetIntervalForTableSelection)
 	}
@@ -189,6 +155,13 @@ func (c *Component) contextFunc(inputStr string) context {
 		units = `SUM(Bytes*SamplingRate*8)`
 	case "l2bps":
 		units = `SUM((Bytes+18*Packets)*SamplingRate*8)`
+	case "flows":
+		units = `SUM(SamplingRate)`
+	case "inl2%":
+		// Interface speed is in Mbps
+		units = `SUM(if(InIfSpeed > 0, (Bytes+18*Packets)*SamplingRate*8*100/(InIfSpeed*1000000), 0))`
+	case "outl2%":
+		units = `SUM(if(OutIfSpeed > 0, (Bytes+18*Packets)*SamplingRate*8*100/(OutIfSpeed*1000000), 0))`
 	}
 
 	c.metrics.clickhouseQueries.WithLabelValues(table).Inc()
@@ -207,61 +180,49 @@ func (c *Component) contextFunc(inputStr string) context {
 				uint64(computedInterval.Seconds()),
 				diffOffset)
 		},
+		resolution: resolution,
 	}
 }
 
-// Get the best table starting at the specified time.
-func (c *Component) getBestTable(start time.Time, targetInterval time.Duration) (string, time.Duration) {
-	c.flowsTablesLock.RLock()
-	defer c.flowsTablesLock.RUnlock()
-
-	table := "flows"
-	computedInterval := time.Second
-	if len(c.flowsTables) > 0 {
-		// We can use the consolidated data. The first
-		// criteria is to find the tables matching the time
-		// criteria.
-		candidates := []int{}
-		for idx, table := range c.flowsTables {
-			if start.After(table.Oldest.Add(table.Resolution)) {
-				candidates = append(candidates, idx)
-			}
-		}
-		if len(candidates) == 0 {
-			// No candidate, fallback to the one with oldest data
-			best := 0
-			for idx, table := range c.flowsTables {
-				if c.flowsTables[best].Oldest.After(table.Oldest.Add(table.Resolution)) {
-					best = idx
-				}
-			}
-			candidates = []int{best}
-			// Add other candidates that are not far off in term of oldest data
-			for idx, table := range c.flowsTables {
-				if idx == best {
-					continue
-				}
-				if c.flowsTables[best].Oldest.After(table.Oldest) {
-					candidates = append(candidates, idx)
-				}
-			}
+// checkUnits checks if the provided units can be used with the
+// provided dimensions. Flows cannot be counted when the inlet
+// aggregates them. Interface utilization is computed for each flow
+// from the speed of its interface: the dimensions should identify the
+// interface or the percentages of several interfaces would be summed.
+func (c *Component) checkUnits(units string, dimensions []queryColumn) error {
+	var ifName queryColumn
+	switch units {
+	case "flows":
+		if c.config.AggregatedFlows {
+			return errors.New("flows cannot be counted as the inlet aggregates them")
 		}
-		sort.Slice(candidates, func(i, j int) bool {
-			return c.flowsTables[candidates[i]].Resolution < c.flowsTables[candidates[j]].Resolution
-		})
-		// If possible, use the first resolution before the target interval
-		for len(candidates) > 1 {
-			if c.flowsTables[candidates[1]].Resolution < targetInterval {
-				candidates = candidates[1:]
-			} else {
-				break
-			}
+		return nil
+	case "inl2%":
+		ifName = queryColumnInIfName
+	case "outl2%":
+		ifName = queryColumnOutIfName
+	default:
+		return nil
+	}
+	hasIfName, hasExporter := false, false
+	for _, column := range dimensions {
+		switch column {
+		case ifName:
+			hasIfName = true
+		case queryColumnExporterAddress, queryColumnExporterName:
+			hasExporter = true
 		}
-		table = c.flowsTables[candidates[0]].Name
-		computedInterval = c.flowsTables[candidates[0]].Resolution
 	}
-	if computedInterval < time.Second {
-		computedInterval = time.Second
+	if !hasIfName || !hasExporter {
+		return fmt.Errorf("units %s require the %s dimension and the ExporterName or ExporterAddress dimension",
+			units, ifName)
 	}
-	return table, computedInterval
+	return nil
+}
+
+// Get the best table starting at the specified time.
+func (c *Component) getBestTable(start time.Time, targetInterval time.Duration) (string, time.Duration) {
+	c.flowsTablesLock.RLock()
+	defer c.flowsTablesLock.RUnlock()
+	return schema.BestTable(c.flowsTables, start, targetInterval)
 }
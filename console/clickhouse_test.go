@@ -21,7 +21,8 @@ SELECT name
 FROM system.tables
 WHERE database=currentDatabase()
 AND table LIKE 'flows%'
-AND engine LIKE '%MergeTree'
+AND NOT endsWith(table, '_local')
+AND (engine LIKE '%MergeTree' OR engine = 'Distributed')
 `).
 		Return(nil).
 		SetArg(1, []struct {
@@ -61,10 +62,10 @@ AND engine LIKE '%MergeTree'
 	}
 
 	expected := []flowsTable{
-		{"flows", time.Duration(0), time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC)},
-		{"flows_1h0m0s", time.Hour, time.Date(2022, 01, 10, 15, 45, 10, 0, time.UTC)},
-		{"flows_1m0s", time.Minute, time.Date(2022, 04, 20, 15, 45, 10, 0, time.UTC)},
-		{"flows_5m0s", 5 * time.Minute, time.Date(2022, 02, 10, 15, 45, 10, 0, time.UTC)},
+		{Name: "flows", Resolution: time.Duration(0), Oldest: time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC)},
+		{Name: "flows_1h0m0s", Resolution: time.Hour, Oldest: time.Date(2022, 01, 10, 15, 45, 10, 0, time.UTC)},
+		{Name: "flows_1m0s", Resolution: time.Minute, Oldest: time.Date(2022, 04, 20, 15, 45, 10, 0, time.UTC)},
+		{Name: "flows_5m0s", Resolution: 5 * time.Minute, Oldest: time.Date(2022, 02, 10, 15, 45, 10, 0, time.UTC)},
 	}
 	if diff := helpers.Diff(c.flowsTables, expected); diff != "" {
 		t.Fatalf("refreshFlowsTables() diff:\n%s", diff)
@@ -100,7 +101,7 @@ func TestFinalizeQuery(t *testing.T) {
 			Expected: "SELECT TimeReceived, SrcPort FROM flows WHERE TimeReceived BETWEEN toDateTime('2022-04-10 15:45:10', 'UTC') AND toDateTime('2022-04-11 15:45:10', 'UTC')",
 		}, {
 			Description: "only flows table available",
-			Tables:      []flowsTable{{"flows", 0, time.Date(2022, 03, 10, 15, 45, 10, 0, time.UTC)}},
+			Tables:      []flowsTable{{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 03, 10, 15, 45, 10, 0, time.UTC)}},
 			Query:       "SELECT 1 FROM {{ .Table }} WHERE {{ .Timefilter }}",
 			Context: inputContext{
 				Start:  time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC),
@@ -110,7 +111,7 @@ func TestFinalizeQuery(t *testing.T) {
 			Expected: "SELECT 1 FROM flows WHERE TimeReceived BETWEEN toDateTime('2022-04-10 15:45:10', 'UTC') AND toDateTime('2022-04-11 15:45:10', 'UTC')",
 		}, {
 			Description: "timefilter.Start and timefilter.Stop",
-			Tables:      []flowsTable{{"flows", 0, time.Date(2022, 03, 10, 15, 45, 10, 0, time.UTC)}},
+			Tables:      []flowsTable{{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 03, 10, 15, 45, 10, 0, time.UTC)}},
 			Query:       "SELECT {{ .TimefilterStart }}, {{ .TimefilterEnd }}",
 			Context: inputContext{
 				Start:  time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC),
@@ -120,7 +121,7 @@ func TestFinalizeQuery(t *testing.T) {
 			Expected: "SELECT toDateTime('2022-04-10 15:45:10', 'UTC'), toDateTime('2022-04-11 15:45:10', 'UTC')",
 		}, {
 			Description: "only flows table and out of range request",
-			Tables:      []flowsTable{{"flows", 0, time.Date(2022, 04, 10, 22, 45, 10, 0, time.UTC)}},
+			Tables:      []flowsTable{{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 04, 10, 22, 45, 10, 0, time.UTC)}},
 			Query:       "SELECT 1 FROM {{ .Table }} WHERE {{ .Timefilter }}",
 			Context: inputContext{
 				Start:  time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC),
@@ -131,8 +132,8 @@ func TestFinalizeQuery(t *testing.T) {
 		}, {
 			Description: "select consolidated table",
 			Tables: []flowsTable{
-				{"flows", 0, time.Date(2022, 03, 10, 22, 45, 10, 0, time.UTC)},
-				{"flows_1m0s", time.Minute, time.Date(2022, 04, 2, 22, 45, 10, 0, time.UTC)},
+				{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 03, 10, 22, 45, 10, 0, time.UTC)},
+				{Name: "flows_1m0s", Resolution: time.Minute, Oldest: time.Date(2022, 04, 2, 22, 45, 10, 0, time.UTC)},
 			},
 			Query: "SELECT 1 FROM {{ .Table }} WHERE {{ .Timefilter }} // {{ .Interval }}",
 			Context: inputContext{
@@ -144,8 +145,8 @@ func TestFinalizeQuery(t *testing.T) {
 		}, {
 			Description: "select consolidated table out of range",
 			Tables: []flowsTable{
-				{"flows", 0, time.Date(2022, 04, 10, 22, 45, 10, 0, time.UTC)},
-				{"flows_1m0s", time.Minute, time.Date(2022, 04, 10, 17, 45, 10, 0, time.UTC)},
+				{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 04, 10, 22, 45, 10, 0, time.UTC)},
+				{Name: "flows_1m0s", Resolution: time.Minute, Oldest: time.Date(2022, 04, 10, 17, 45, 10, 0, time.UTC)},
 			},
 			Query: "SELECT 1 FROM {{ .Table }} WHERE {{ .Timefilter }}",
 			Context: inputContext{
@@ -157,8 +158,8 @@ func TestFinalizeQuery(t *testing.T) {
 		}, {
 			Description: "select flows table out of range",
 			Tables: []flowsTable{
-				{"flows", 0, time.Date(2022, 04, 10, 16, 45, 10, 0, time.UTC)},
-				{"flows_1m0s", time.Minute, time.Date(2022, 04, 10, 17, 45, 10, 0, time.UTC)},
+				{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 04, 10, 16, 45, 10, 0, time.UTC)},
+				{Name: "flows_1m0s", Resolution: time.Minute, Oldest: time.Date(2022, 04, 10, 17, 45, 10, 0, time.UTC)},
 			},
 			Query: "SELECT 1 FROM {{ .Table }} WHERE {{ .Timefilter }}",
 			Context: inputContext{
@@ -170,8 +171,8 @@ func TestFinalizeQuery(t *testing.T) {
 		}, {
 			Description: "use flows table for resolution (control for next case)",
 			Tables: []flowsTable{
-				{"flows", 0, time.Date(2022, 04, 10, 10, 45, 10, 0, time.UTC)},
-				{"flows_1m0s", time.Minute, time.Date(2022, 03, 10, 10, 45, 10, 0, time.UTC)},
+				{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 04, 10, 10, 45, 10, 0, time.UTC)},
+				{Name: "flows_1m0s", Resolution: time.Minute, Oldest: time.Date(2022, 03, 10, 10, 45, 10, 0, time.UTC)},
 			},
 			Query: "SELECT 1 FROM {{ .Table }} WHERE {{ .Timefilter }} // {{ .Interval }}",
 			Context: inputContext{
@@ -183,8 +184,8 @@ func TestFinalizeQuery(t *testing.T) {
 		}, {
 			Description: "use flows table for resolution (but flows_1m0s for data)",
 			Tables: []flowsTable{
-				{"flows", 0, time.Date(2022, 04, 10, 10, 45, 10, 0, time.UTC)},
-				{"flows_1m0s", time.Minute, time.Date(2022, 03, 10, 10, 45, 10, 0, time.UTC)},
+				{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 04, 10, 10, 45, 10, 0, time.UTC)},
+				{Name: "flows_1m0s", Resolution: time.Minute, Oldest: time.Date(2022, 03, 10, 10, 45, 10, 0, time.UTC)},
 			},
 			Query: "SELECT 1 FROM {{ .Table }} WHERE {{ .Timefilter }} // {{ .Interval }}",
 			Context: inputContext{
@@ -200,8 +201,8 @@ func TestFinalizeQuery(t *testing.T) {
 		}, {
 			Description: "select flows table with better resolution",
 			Tables: []flowsTable{
-				{"flows", 0, time.Date(2022, 03, 10, 16, 45, 10, 0, time.UTC)},
-				{"flows_1m0s", time.Minute, time.Date(2022, 03, 10, 17, 45, 10, 0, time.UTC)},
+				{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 03, 10, 16, 45, 10, 0, time.UTC)},
+				{Name: "flows_1m0s", Resolution: time.Minute, Oldest: time.Date(2022, 03, 10, 17, 45, 10, 0, time.UTC)},
 			},
 			Query: "SELECT 1 FROM {{ .Table }} WHERE {{ .Timefilter }} // {{ .Interval }}",
 			Context: inputContext{
@@ -213,9 +214,9 @@ func TestFinalizeQuery(t *testing.T) {
 		}, {
 			Description: "select consolidated table with better resolution",
 			Tables: []flowsTable{
-				{"flows", 0, time.Date(2022, 03, 10, 22, 45, 10, 0, time.UTC)},
-				{"flows_5m0s", 5 * time.Minute, time.Date(2022, 04, 2, 22, 45, 10, 0, time.UTC)},
-				{"flows_1m0s", time.Minute, time.Date(2022, 04, 2, 22, 45, 10, 0, time.UTC)},
+				{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 03, 10, 22, 45, 10, 0, time.UTC)},
+				{Name: "flows_5m0s", Resolution: 5 * time.Minute, Oldest: time.Date(2022, 04, 2, 22, 45, 10, 0, time.UTC)},
+				{Name: "flows_1m0s", Resolution: time.Minute, Oldest: time.Date(2022, 04, 2, 22, 45, 10, 0, time.UTC)},
 			},
 			Query: "SELECT 1 FROM {{ .Table }} WHERE {{ .Timefilter }} // {{ .Interval }}",
 			Context: inputContext{
@@ -227,9 +228,9 @@ func TestFinalizeQuery(t *testing.T) {
 		}, {
 			Description: "select consolidated table with better range",
 			Tables: []flowsTable{
-				{"flows", 0, time.Date(2022, 04, 10, 22, 45, 10, 0, time.UTC)},
-				{"flows_5m0s", 5 * time.Minute, time.Date(2022, 04, 2, 22, 45, 10, 0, time.UTC)},
-				{"flows_1m0s", time.Minute, time.Date(2022, 04, 10, 22, 45, 10, 0, time.UTC)},
+				{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 04, 10, 22, 45, 10, 0, time.UTC)},
+				{Name: "flows_5m0s", Resolution: 5 * time.Minute, Oldest: time.Date(2022, 04, 2, 22, 45, 10, 0, time.UTC)},
+				{Name: "flows_1m0s", Resolution: time.Minute, Oldest: time.Date(2022, 04, 10, 22, 45, 10, 0, time.UTC)},
 			},
 			Query: "SELECT 1 FROM {{ .Table }} WHERE {{ .Timefilter }}",
 			Context: inputContext{
@@ -241,9 +242,9 @@ func TestFinalizeQuery(t *testing.T) {
 		}, {
 			Description: "select best resolution when equality for oldest data",
 			Tables: []flowsTable{
-				{"flows", 0, time.Date(2022, 04, 10, 22, 40, 55, 0, time.UTC)},
-				{"flows_1m0s", time.Minute, time.Date(2022, 04, 10, 22, 40, 00, 0, time.UTC)},
-				{"flows_1h0m0s", time.Hour, time.Date(2022, 04, 10, 22, 00, 10, 0, time.UTC)},
+				{Name: "flows", Resolution: 0, Oldest: time.Date(2022, 04, 10, 22, 40, 55, 0, time.UTC)},
+				{Name: "flows_1m0s", Resolution: time.Minute, Oldest: time.Date(2022, 04, 10, 22, 40, 00, 0, time.UTC)},
+				{Name: "flows_1h0m0s", Resolution: time.Hour, Oldest: time.Date(2022, 04, 10, 22, 00, 10, 0, time.UTC)},
 			},
 			Query: "SELECT 1 FROM {{ .Table }} WHERE {{ .Timefilter }}",
 			Context: inputContext{
@@ -274,10 +275,10 @@ func TestFinalizeQuery(t *testing.T) {
 			Description: "Small interval outside main table expiration",
 			Query:       "SELECT InIfProvider FROM {{ .Table }}",
 			Tables: []flowsTable{
-				{"flows", time.Duration(0), time.Date(2022, 11, 06, 12, 0, 0, 0, time.UTC)},
-				{"flows_1h0m0s", time.Hour, time.Date(2022, 04, 25, 18, 0, 0, 0, time.UTC)},
-				{"flows_1m0s", time.Minute, time.Date(2022, 11, 14, 12, 0, 0, 0, time.UTC)},
-				{"flows_5m0s", 5 * time.Minute, time.Date(2022, 8, 23, 12, 0, 0, 0, time.UTC)},
+				{Name: "flows", Resolution: time.Duration(0), Oldest: time.Date(2022, 11, 06, 12, 0, 0, 0, time.UTC)},
+				{Name: "flows_1h0m0s", Resolution: time.Hour, Oldest: time.Date(2022, 04, 25, 18, 0, 0, 0, time.UTC)},
+				{Name: "flows_1m0s", Resolution: time.Minute, Oldest: time.Date(2022, 11, 14, 12, 0, 0, 0, time.UTC)},
+				{Name: "flows_5m0s", Resolution: 5 * time.Minute, Oldest: time.Date(2022, 8, 23, 12, 0, 0, 0, time.UTC)},
 			},
 			Context: inputContext{
 				Start:  time.Date(2022, 10, 30, 1, 0, 0, 0, time.UTC),
@@ -285,6 +286,34 @@ func TestFinalizeQuery(t *testing.T) {
 				Points: 200,
 			},
 			Expected: "SELECT InIfProvider FROM flows_5m0s",
+		}, {
+			Description: "flows units require the main table",
+			Query:       "SELECT {{ .Units }} FROM {{ .Table }}",
+			Tables: []flowsTable{
+				{Name: "flows", Resolution: time.Duration(0), Oldest: time.Date(2022, 10, 06, 12, 0, 0, 0, time.UTC)},
+				{Name: "flows_5m0s", Resolution: 5 * time.Minute, Oldest: time.Date(2022, 8, 23, 12, 0, 0, 0, time.UTC)},
+			},
+			Context: inputContext{
+				Start:  time.Date(2022, 10, 30, 1, 0, 0, 0, time.UTC),
+				End:    time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC),
+				Points: 20,
+				Units:  "flows",
+			},
+			Expected: "SELECT SUM(SamplingRate) FROM flows",
+		}, {
+			Description: "interface utilization units",
+			Query:       "SELECT {{ .Units }} FROM {{ .Table }}",
+			Tables: []flowsTable{
+				{Name: "flows", Resolution: time.Duration(0), Oldest: time.Date(2022, 10, 06, 12, 0, 0, 0, time.UTC)},
+				{Name: "flows_5m0s", Resolution: 5 * time.Minute, Oldest: time.Date(2022, 8, 23, 12, 0, 0, 0, time.UTC)},
+			},
+			Context: inputContext{
+				Start:  time.Date(2022, 10, 30, 1, 0, 0, 0, time.UTC),
+				End:    time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC),
+				Points: 20,
+				Units:  "inl2%",
+			},
+			Expected: "SELECT SUM(if(InIfSpeed > 0, (Bytes+18*Packets)*SamplingRate*8*100/(InIfSpeed*1000000), 0)) FROM flows_5m0s",
 		},
 	}
 
@@ -292,7 +321,7 @@ func TestFinalizeQuery(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.Description, func(t *testing.T) {
 			c.flowsTables = tc.Tables
-			got := c.finalizeQuery(
+			got, _ := c.finalizeQuery(
 				fmt.Sprintf(`{{ with %s }}%s{{ end }}`, templateContext(tc.Context), tc.Query))
 			if diff := helpers.Diff(got, tc.Expected); diff != "" {
 				t.Fatalf("finalizeQuery(): (-got, +want):\n%s", diff)
@@ -300,3 +329,35 @@ func TestFinalizeQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckUnits(t *testing.T) {
+	c, _, _, _ := NewMock(t, DefaultConfiguration())
+	config := DefaultConfiguration()
+	config.AggregatedFlows = true
+	cAggregated, _, _, _ := NewMock(t, config)
+	if err := cAggregated.checkUnits("flows", nil); err == nil {
+		t.Error("checkUnits(\"flows\") did not error with aggregated flows")
+	}
+
+	cases := []struct {
+		Units      string
+		Dimensions []queryColumn
+		Error      bool
+	}{
+		{"l3bps", nil, false},
+		{"flows", []queryColumn{queryColumnSrcAS}, false},
+		{"inl2%", nil, true},
+		{"inl2%", []queryColumn{queryColumnInIfName}, true},
+		{"inl2%", []queryColumn{queryColumnExporterName, queryColumnOutIfName}, true},
+		{"inl2%", []queryColumn{queryColumnExporterName, queryColumnInIfName}, false},
+		{"outl2%", []queryColumn{queryColumnOutIfName, queryColumnExporterAddress, queryColumnSrcAS}, false},
+	}
+	for _, tc := range cases {
+		err := c.checkUnits(tc.Units, tc.Dimensions)
+		if err != nil && !tc.Error {
+			t.Errorf("checkUnits(%q, %v) error:\n%+v", tc.Units, tc.Dimensions, err)
+		} else if err == nil && tc.Error {
+			t.Errorf("checkUnits(%q, %v) did not error", tc.Units, tc.Dimensions)
+		}
+	}
+}
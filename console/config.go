@@ -5,6 +5,7 @@ package console
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,6 +22,46 @@ type Configuration struct {
 	HomepageTopWidgets []string `validate:"dive,oneof=src-as dst-as src-country dst-country exporter protocol etype src-port dst-port"`
 	// DimensionsLimit put an upper limit to the number of dimensions to return.
 	DimensionsLimit int `validate:"min=10"`
+	// AggregatedFlows tells if the inlet aggregates flows. Flows
+	// cannot be counted in this case.
+	AggregatedFlows bool
+	// Policies restricts the traffic some users or groups can see
+	Policies []PolicyConfiguration `validate:"dive"`
+	// Cache defines how results from ClickHouse are cached
+	Cache CacheConfiguration
+	// Billing defines commit rates for 95th percentile billing reports
+	Billing BillingConfiguration
+}
+
+// BillingConfiguration defines commit rates for billing reports.
+type BillingConfiguration struct {
+	// Providers maps providers to their commit rate in bits per second
+	Providers map[string]uint64
+	// Interfaces maps interfaces (as "exporter/interface") to their
+	// commit rate in bits per second
+	Interfaces map[string]uint64
+}
+
+// CacheConfiguration defines the cache for results of ClickHouse queries.
+type CacheConfiguration struct {
+	// Size is the maximum number of results to keep in memory (0 to disable)
+	Size int `validate:"min=0"`
+	// Memcached is a list of memcached servers to use as a shared cache
+	Memcached []string `validate:"dive,hostname_port"`
+	// MinTTL is the minimum duration results are kept in cache
+	MinTTL time.Duration `validate:"min=1s"`
+	// MaxTTL is the maximum duration results are kept in cache
+	MaxTTL time.Duration `validate:"gtefield=MinTTL"`
+}
+
+// PolicyConfiguration maps users and groups to a mandatory filter.
+type PolicyConfiguration struct {
+	// Users is the list of user logins this policy applies to
+	Users []string
+	// Groups is the list of groups this policy applies to
+	Groups []string
+	// Filter is the filter enforced for matching users
+	Filter string `validate:"required"`
 }
 
 // VisualizeOptionsConfiguration defines options for the "visualize" tab.
@@ -52,6 +93,11 @@ func DefaultConfiguration() Configuration {
 		},
 		HomepageTopWidgets: []string{"src-as", "src-port", "protocol", "src-country", "etype"},
 		DimensionsLimit:    50,
+		Cache: CacheConfiguration{
+			Size:   1000,
+			MinTTL: 10 * time.Second,
+			MaxTTL: 10 * time.Minute,
+		},
 	}
 }
 
@@ -61,5 +107,6 @@ func (c *Component) configHandlerFunc(gc *gin.Context) {
 		"defaultVisualizeOptions": c.config.DefaultVisualizeOptions,
 		"dimensionsLimit":         c.config.DimensionsLimit,
 		"homepageTopWidgets":      c.config.HomepageTopWidgets,
+		"aggregatedFlows":         c.config.AggregatedFlows,
 	})
 }
@@ -30,6 +30,7 @@ func TestConfigHandler(t *testing.T) {
 				},
 				"dimensionsLimit":    50,
 				"homepageTopWidgets": []string{"src-as", "src-port", "protocol", "src-country", "etype"},
+				"aggregatedFlows":    false,
 			},
 		},
 	})
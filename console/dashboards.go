@@ -0,0 +1,266 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"akvorado/common/helpers"
+	"akvorado/console/authentication"
+	"akvorado/console/database"
+)
+
+// bindSavedVisualization binds and validates a saved visualization
+// from the request.
+func bindSavedVisualization(gc *gin.Context) (database.SavedVisualization, bool) {
+	var v database.SavedVisualization
+	if err := gc.ShouldBindJSON(&v); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return v, false
+	}
+	for _, dimension := range v.Dimensions {
+		var column queryColumn
+		if err := column.UnmarshalText([]byte(dimension)); err != nil {
+			gc.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Unknown dimension %q", dimension)})
+			return v, false
+		}
+	}
+	return v, true
+}
+
+// bindID parses the ID from the URL.
+func bindID(gc *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(gc.Param("id"), 10, 64)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": "bad ID format"})
+		return 0, false
+	}
+	return id, true
+}
+
+func (c *Component) visualizationSavedListHandlerFunc(gc *gin.Context) {
+	ctx := c.t.Context(gc.Request.Context())
+	user := gc.MustGet("user").(authentication.UserInformation).Login
+	visualizations, err := c.d.Database.ListSavedVisualizations(ctx, user)
+	if err != nil {
+		c.r.Err(err).Msg("unable to list visualizations")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "unable to list visualizations"})
+		return
+	}
+	gc.JSON(http.StatusOK, gin.H{"visualizations": visualizations})
+}
+
+func (c *Component) visualizationSavedGetHandlerFunc(gc *gin.Context) {
+	ctx := c.t.Context(gc.Request.Context())
+	user := gc.MustGet("user").(authentication.UserInformation).Login
+	id, ok := bindID(gc)
+	if !ok {
+		return
+	}
+	visualization, err := c.d.Database.GetSavedVisualization(ctx, user, id)
+	if errors.Is(err, database.ErrNotFound) {
+		gc.JSON(http.StatusNotFound, gin.H{"message": "visualization not found"})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("unable to get visualization")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "unable to get visualization"})
+		return
+	}
+	gc.JSON(http.StatusOK, visualization)
+}
+
+func (c *Component) visualizationSavedAddHandlerFunc(gc *gin.Context) {
+	ctx := c.t.Context(gc.Request.Context())
+	user := gc.MustGet("user").(authentication.UserInformation).Login
+	visualization, ok := bindSavedVisualization(gc)
+	if !ok {
+		return
+	}
+	visualization.User = user
+	id, err := c.d.Database.CreateSavedVisualization(ctx, visualization)
+	if err != nil {
+		c.r.Err(err).Msg("cannot create saved visualization")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "cannot create new visualization"})
+		return
+	}
+	gc.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+func (c *Component) visualizationSavedUpdateHandlerFunc(gc *gin.Context) {
+	ctx := c.t.Context(gc.Request.Context())
+	user := gc.MustGet("user").(authentication.UserInformation).Login
+	id, ok := bindID(gc)
+	if !ok {
+		return
+	}
+	visualization, ok := bindSavedVisualization(gc)
+	if !ok {
+		return
+	}
+	visualization.ID = id
+	visualization.User = user
+	err := c.d.Database.UpdateSavedVisualization(ctx, visualization)
+	if errors.Is(err, database.ErrNotFound) {
+		gc.JSON(http.StatusNotFound, gin.H{"message": "visualization not found"})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("cannot update saved visualization")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "cannot update visualization"})
+		return
+	}
+	gc.JSON(http.StatusNoContent, nil)
+}
+
+func (c *Component) visualizationSavedDeleteHandlerFunc(gc *gin.Context) {
+	ctx := c.t.Context(gc.Request.Context())
+	user := gc.MustGet("user").(authentication.UserInformation).Login
+	id, ok := bindID(gc)
+	if !ok {
+		return
+	}
+	err := c.d.Database.DeleteSavedVisualization(ctx, database.SavedVisualization{
+		ID:   id,
+		User: user,
+	})
+	if errors.Is(err, database.ErrNotFound) {
+		gc.JSON(http.StatusNotFound, gin.H{"message": "visualization not found"})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("cannot delete saved visualization")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "cannot delete visualization"})
+		return
+	}
+	gc.JSON(http.StatusNoContent, nil)
+}
+
+// dashboardGetHandlerOutput describes the output of the
+// /dashboard/saved/:id endpoint. Visualizations that cannot be seen
+// by the user are omitted.
+type dashboardGetHandlerOutput struct {
+	database.Dashboard
+	Visualizations []database.SavedVisualization `json:"visualizations"`
+}
+
+func (c *Component) dashboardSavedListHandlerFunc(gc *gin.Context) {
+	ctx := c.t.Context(gc.Request.Context())
+	user := gc.MustGet("user").(authentication.UserInformation).Login
+	dashboards, err := c.d.Database.ListDashboards(ctx, user)
+	if err != nil {
+		c.r.Err(err).Msg("unable to list dashboards")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "unable to list dashboards"})
+		return
+	}
+	gc.JSON(http.StatusOK, gin.H{"dashboards": dashboards})
+}
+
+func (c *Component) dashboardSavedGetHandlerFunc(gc *gin.Context) {
+	ctx := c.t.Context(gc.Request.Context())
+	user := gc.MustGet("user").(authentication.UserInformation).Login
+	id, ok := bindID(gc)
+	if !ok {
+		return
+	}
+	dashboard, err := c.d.Database.GetDashboard(ctx, user, id)
+	if errors.Is(err, database.ErrNotFound) {
+		gc.JSON(http.StatusNotFound, gin.H{"message": "dashboard not found"})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("unable to get dashboard")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "unable to get dashboard"})
+		return
+	}
+	output := dashboardGetHandlerOutput{
+		Dashboard:      dashboard,
+		Visualizations: []database.SavedVisualization{},
+	}
+	for _, id := range dashboard.Visualizations {
+		visualization, err := c.d.Database.GetSavedVisualization(ctx, user, id)
+		if errors.Is(err, database.ErrNotFound) {
+			continue
+		} else if err != nil {
+			c.r.Err(err).Msg("unable to get visualization")
+			gc.JSON(http.StatusInternalServerError, gin.H{"message": "unable to get dashboard"})
+			return
+		}
+		output.Visualizations = append(output.Visualizations, visualization)
+	}
+	gc.JSON(http.StatusOK, output)
+}
+
+func (c *Component) dashboardSavedAddHandlerFunc(gc *gin.Context) {
+	ctx := c.t.Context(gc.Request.Context())
+	user := gc.MustGet("user").(authentication.UserInformation).Login
+	var dashboard database.Dashboard
+	if err := gc.ShouldBindJSON(&dashboard); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	dashboard.User = user
+	id, err := c.d.Database.CreateDashboard(ctx, dashboard)
+	if errors.Is(err, database.ErrUnknownVisualization) {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": "unknown visualization"})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("cannot create dashboard")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "cannot create new dashboard"})
+		return
+	}
+	gc.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+func (c *Component) dashboardSavedUpdateHandlerFunc(gc *gin.Context) {
+	ctx := c.t.Context(gc.Request.Context())
+	user := gc.MustGet("user").(authentication.UserInformation).Login
+	id, ok := bindID(gc)
+	if !ok {
+		return
+	}
+	var dashboard database.Dashboard
+	if err := gc.ShouldBindJSON(&dashboard); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	dashboard.ID = id
+	dashboard.User = user
+	err := c.d.Database.UpdateDashboard(ctx, dashboard)
+	if errors.Is(err, database.ErrNotFound) {
+		gc.JSON(http.StatusNotFound, gin.H{"message": "dashboard not found"})
+		return
+	} else if errors.Is(err, database.ErrUnknownVisualization) {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": "unknown visualization"})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("cannot update dashboard")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "cannot update dashboard"})
+		return
+	}
+	gc.JSON(http.StatusNoContent, nil)
+}
+
+func (c *Component) dashboardSavedDeleteHandlerFunc(gc *gin.Context) {
+	ctx := c.t.Context(gc.Request.Context())
+	user := gc.MustGet("user").(authentication.UserInformation).Login
+	id, ok := bindID(gc)
+	if !ok {
+		return
+	}
+	err := c.d.Database.DeleteDashboard(ctx, database.Dashboard{
+		ID:   id,
+		User: user,
+	})
+	if errors.Is(err, database.ErrNotFound) {
+		gc.JSON(http.StatusNotFound, gin.H{"message": "dashboard not found"})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("cannot delete dashboard")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "cannot delete dashboard"})
+		return
+	}
+	gc.JSON(http.StatusNoContent, nil)
+}
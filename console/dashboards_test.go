@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	netHTTP "net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"akvorado/common/helpers"
+)
+
+func TestDashboardHandlers(t *testing.T) {
+	_, h, _, _ := NewMock(t, DefaultConfiguration())
+	alfred := func() netHTTP.Header {
+		headers := make(netHTTP.Header)
+		headers.Add("Remote-User", "alfred")
+		return headers
+	}
+	visualization := gin.H{
+		"description": "test 1",
+		"graphType":   "stacked",
+		"start":       "6 hours ago",
+		"end":         "now",
+		"filter":      "InIfBoundary = external",
+		"dimensions":  []string{"SrcAS", "ExporterName"},
+		"limit":       10,
+		"units":       "l3bps",
+	}
+	withUnits := func(visualization gin.H, units string) gin.H {
+		result := gin.H{}
+		for k, v := range visualization {
+			result[k] = v
+		}
+		result["units"] = units
+		return result
+	}
+	storedVisualization := gin.H{
+		"id":             1,
+		"user":           "__default",
+		"shared":         false,
+		"description":    "test 1",
+		"graphType":      "stacked",
+		"start":          "6 hours ago",
+		"end":            "now",
+		"filter":         "InIfBoundary = external",
+		"dimensions":     []string{"SrcAS", "ExporterName"},
+		"limit":          10,
+		"units":          "l3bps",
+		"bidirectional":  false,
+		"previousPeriod": false,
+	}
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "list, no visualizations",
+			URL:         "/api/v0/console/visualization/saved",
+			JSONOutput:  gin.H{"visualizations": []gin.H{}},
+		}, {
+			Description: "store visualization with invalid dimension",
+			URL:         "/api/v0/console/visualization/saved",
+			JSONInput: gin.H{
+				"description": "test 1",
+				"graphType":   "stacked",
+				"start":       "6 hours ago",
+				"end":         "now",
+				"dimensions":  []string{"Unknown"},
+				"limit":       10,
+				"units":       "l3bps",
+			},
+			StatusCode: 400,
+			JSONOutput: gin.H{"message": `Unknown dimension "Unknown"`},
+		}, {
+			Description: "store one visualization",
+			URL:         "/api/v0/console/visualization/saved",
+			JSONInput:   visualization,
+			StatusCode:  201,
+			JSONOutput:  gin.H{"id": 1},
+		}, {
+			Description: "list stored visualizations",
+			URL:         "/api/v0/console/visualization/saved",
+			JSONOutput:  gin.H{"visualizations": []gin.H{storedVisualization}},
+		}, {
+			Description: "get stored visualization",
+			URL:         "/api/v0/console/visualization/saved/1",
+			JSONOutput:  storedVisualization,
+		}, {
+			Description: "get stored visualization as another user",
+			URL:         "/api/v0/console/visualization/saved/1",
+			Header:      alfred(),
+			StatusCode:  404,
+			JSONOutput:  gin.H{"message": "visualization not found"},
+		}, {
+			Description: "update stored visualization as another user",
+			Method:      "PUT",
+			URL:         "/api/v0/console/visualization/saved/1",
+			Header:      alfred(),
+			JSONInput:   visualization,
+			StatusCode:  404,
+			JSONOutput:  gin.H{"message": "visualization not found"},
+		}, {
+			Description: "store dashboard with a private visualization of another user",
+			URL:         "/api/v0/console/dashboard/saved",
+			Header:      alfred(),
+			JSONInput: gin.H{
+				"description":    "dashboard 1",
+				"visualizations": []int{1},
+			},
+			StatusCode: 400,
+			JSONOutput: gin.H{"message": "unknown visualization"},
+		}, {
+			Description: "store one dashboard",
+			URL:         "/api/v0/console/dashboard/saved",
+			JSONInput: gin.H{
+				"description":    "dashboard 1",
+				"shared":         true,
+				"visualizations": []int{1},
+			},
+			StatusCode: 201,
+			JSONOutput: gin.H{"id": 1},
+		}, {
+			Description: "list dashboards as another user",
+			URL:         "/api/v0/console/dashboard/saved",
+			Header:      alfred(),
+			JSONOutput: gin.H{"dashboards": []gin.H{
+				{
+					"id":             1,
+					"user":           "__default",
+					"shared":         true,
+					"description":    "dashboard 1",
+					"visualizations": []int{1},
+				},
+			}},
+		}, {
+			Description: "get dashboard",
+			URL:         "/api/v0/console/dashboard/saved/1",
+			JSONOutput: gin.H{
+				"id":             1,
+				"user":           "__default",
+				"shared":         true,
+				"description":    "dashboard 1",
+				"visualizations": []gin.H{storedVisualization},
+			},
+		}, {
+			Description: "get dashboard as another user without access to the visualization",
+			URL:         "/api/v0/console/dashboard/saved/1",
+			Header:      alfred(),
+			JSONOutput: gin.H{
+				"id":             1,
+				"user":           "__default",
+				"shared":         true,
+				"description":    "dashboard 1",
+				"visualizations": []gin.H{},
+			},
+		}, {
+			Description: "delete dashboard as another user",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/dashboard/saved/1",
+			Header:      alfred(),
+			StatusCode:  404,
+			JSONOutput:  gin.H{"message": "dashboard not found"},
+		}, {
+			Description: "delete dashboard",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/dashboard/saved/1",
+			StatusCode:  204,
+			ContentType: "application/json; charset=utf-8",
+		}, {
+			Description: "update stored visualization with flows units",
+			Method:      "PUT",
+			URL:         "/api/v0/console/visualization/saved/1",
+			JSONInput:   withUnits(visualization, "flows"),
+			StatusCode:  204,
+			ContentType: "application/json; charset=utf-8",
+		}, {
+			Description: "update stored visualization with inl2% units",
+			Method:      "PUT",
+			URL:         "/api/v0/console/visualization/saved/1",
+			JSONInput:   withUnits(visualization, "inl2%"),
+			StatusCode:  204,
+			ContentType: "application/json; charset=utf-8",
+		}, {
+			Description: "update stored visualization with outl2% units",
+			Method:      "PUT",
+			URL:         "/api/v0/console/visualization/saved/1",
+			JSONInput:   withUnits(visualization, "outl2%"),
+			StatusCode:  204,
+			ContentType: "application/json; charset=utf-8",
+		}, {
+			Description: "get updated visualization",
+			URL:         "/api/v0/console/visualization/saved/1",
+			JSONOutput:  withUnits(storedVisualization, "outl2%"),
+		}, {
+			Description: "delete visualization",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/visualization/saved/1",
+			StatusCode:  204,
+			ContentType: "application/json; charset=utf-8",
+		}, {
+			Description: "delete visualization with invalid ID",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/visualization/saved/kjgdfhgh",
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": "bad ID format"},
+		}, {
+			Description: "list visualizations after delete",
+			URL:         "/api/v0/console/visualization/saved",
+			JSONOutput:  gin.H{"visualizations": []gin.H{}},
+		},
+	})
+}
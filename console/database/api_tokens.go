@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// apiTokenPrefix is prepended to all generated API tokens to make
+// them easy to recognize.
+const apiTokenPrefix = "akvorado_"
+
+// APIToken represents a personal API token in database. Only a hash
+// of the token is stored. The groups of the user when the token was
+// created are stored alongside it.
+type APIToken struct {
+	ID          uint64    `json:"id"`
+	User        string    `gorm:"index" json:"user"`
+	Groups      []string  `gorm:"serializer:json" json:"groups,omitempty"`
+	Description string    `json:"description" binding:"required"`
+	Hash        string    `gorm:"size:64;uniqueIndex" json:"-"`
+	CreatedAt   time.Time `json:"created-at"`
+}
+
+// hashAPIToken returns the hash of the provided API token.
+func hashAPIToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateAPIToken creates a new API token for the provided user. The
+// token is returned in clear and cannot be retrieved later.
+func (c *Component) CreateAPIToken(ctx context.Context, t APIToken) (APIToken, string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return APIToken{}, "", fmt.Errorf("unable to generate API token: %w", err)
+	}
+	token := apiTokenPrefix + hex.EncodeToString(random)
+	t.ID = 0
+	t.Hash = hashAPIToken(token)
+	if result := c.db.WithContext(ctx).Create(&t); result.Error != nil {
+		return APIToken{}, "", fmt.Errorf("unable to create new API token: %w", result.Error)
+	}
+	return t, token, nil
+}
+
+// ListAPITokens list all API tokens for the provided user.
+func (c *Component) ListAPITokens(ctx context.Context, user string) ([]APIToken, error) {
+	results := []APIToken{}
+	result := c.db.WithContext(ctx).
+		Where(&APIToken{User: user}).
+		Order("id").
+		Find(&results)
+	if result.Error != nil {
+		return nil, fmt.Errorf("unable to retrieve API tokens: %w", result.Error)
+	}
+	return results, nil
+}
+
+// DeleteAPIToken revokes the provided API token.
+func (c *Component) DeleteAPIToken(ctx context.Context, t APIToken) error {
+	result := c.db.WithContext(ctx).Where(&APIToken{User: t.User}).Delete(&t)
+	if result.Error != nil {
+		return fmt.Errorf("cannot delete API token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// LookupAPIToken returns the API token matching the provided clear
+// token.
+func (c *Component) LookupAPIToken(ctx context.Context, token string) (APIToken, error) {
+	var t APIToken
+	result := c.db.WithContext(ctx).
+		Where(&APIToken{Hash: hashAPIToken(token)}).
+		Take(&t)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return t, ErrNotFound
+	} else if result.Error != nil {
+		return t, fmt.Errorf("unable to retrieve API token: %w", result.Error)
+	}
+	return t, nil
+}
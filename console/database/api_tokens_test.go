@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestAPIToken(t *testing.T) {
+	r := reporter.NewMock(t)
+	c := NewMock(t, r, DefaultConfiguration())
+	ctx := context.Background()
+
+	created, token, err := c.CreateAPIToken(ctx, APIToken{
+		User:        "marty",
+		Groups:      []string{"time-travellers"},
+		Description: "automation",
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIToken() error:\n%+v", err)
+	}
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		t.Fatalf("CreateAPIToken() token %q should start with %q", token, apiTokenPrefix)
+	}
+	if created.Hash == token || strings.Contains(created.Hash, token[len(apiTokenPrefix):]) {
+		t.Fatal("CreateAPIToken() should not store the token in clear")
+	}
+
+	// Lookup
+	got, err := c.LookupAPIToken(ctx, token)
+	if err != nil {
+		t.Fatalf("LookupAPIToken() error:\n%+v", err)
+	}
+	got.CreatedAt = time.Time{}
+	expected := APIToken{
+		ID:          created.ID,
+		User:        "marty",
+		Groups:      []string{"time-travellers"},
+		Description: "automation",
+		Hash:        created.Hash,
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("LookupAPIToken() (-got, +want):\n%s", diff)
+	}
+	if _, err := c.LookupAPIToken(ctx, token+"0"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LookupAPIToken(invalid) error:\n%+v", err)
+	}
+
+	// List
+	tokens, err := c.ListAPITokens(ctx, "marty")
+	if err != nil {
+		t.Fatalf("ListAPITokens() error:\n%+v", err)
+	}
+	if len(tokens) != 1 || tokens[0].ID != created.ID {
+		t.Fatalf("ListAPITokens() got %+v", tokens)
+	}
+
+	// Delete
+	if err := c.DeleteAPIToken(ctx, APIToken{ID: created.ID, User: "judith"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("DeleteAPIToken(judith, not owned) error:\n%+v", err)
+	}
+	if err := c.DeleteAPIToken(ctx, APIToken{ID: created.ID, User: "marty"}); err != nil {
+		t.Fatalf("DeleteAPIToken() error:\n%+v", err)
+	}
+	if _, err := c.LookupAPIToken(ctx, token); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LookupAPIToken(revoked) error:\n%+v", err)
+	}
+}
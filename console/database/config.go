@@ -6,7 +6,7 @@ package database
 // Configuration describes the configuration for the authentication component.
 type Configuration struct {
 	// Driver defines the driver for the database
-	Driver string `validate:"required"`
+	Driver string `validate:"required,oneof=sqlite postgres mysql"`
 	// DSN defines the DSN to connect to the database
 	DSN string `validate:"required"`
 	// SavedFilters is a list of saved filters to include for all users
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownVisualization is returned when a dashboard references a
+// visualization which cannot be seen by the user.
+var ErrUnknownVisualization = errors.New("unknown visualization")
+
+// Dashboard represents a named and ordered list of saved
+// visualizations in database.
+type Dashboard struct {
+	ID             uint64   `json:"id"`
+	User           string   `gorm:"index" json:"user"`
+	Shared         bool     `json:"shared"`
+	Description    string   `json:"description" binding:"required"`
+	Visualizations []uint64 `gorm:"serializer:json" json:"visualizations"`
+}
+
+// checkVisualizations checks that all the provided visualizations can
+// be seen by the user.
+func checkVisualizations(tx *gorm.DB, user string, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	unique := map[uint64]bool{}
+	for _, id := range ids {
+		unique[id] = true
+	}
+	var count int64
+	result := tx.Model(&SavedVisualization{}).
+		Where(tx.Where(&SavedVisualization{User: user}).Or(&SavedVisualization{Shared: true})).
+		Where("id IN ?", ids).
+		Count(&count)
+	if result.Error != nil {
+		return fmt.Errorf("unable to check visualizations: %w", result.Error)
+	}
+	if count != int64(len(unique)) {
+		return ErrUnknownVisualization
+	}
+	return nil
+}
+
+// CreateDashboard creates a new dashboard in database and returns its
+// ID. All visualizations should be visible to the user.
+func (c *Component) CreateDashboard(ctx context.Context, d Dashboard) (uint64, error) {
+	d.ID = 0
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkVisualizations(tx, d.User, d.Visualizations); err != nil {
+			return err
+		}
+		if result := tx.Create(&d); result.Error != nil {
+			return fmt.Errorf("unable to create new dashboard: %w", result.Error)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return d.ID, nil
+}
+
+// ListDashboards list all dashboards for the provided user.
+func (c *Component) ListDashboards(ctx context.Context, user string) ([]Dashboard, error) {
+	results := []Dashboard{}
+	result := c.db.WithContext(ctx).
+		Where(&Dashboard{User: user}).
+		Or(&Dashboard{Shared: true}).
+		Order("id").
+		Find(&results)
+	if result.Error != nil {
+		return nil, fmt.Errorf("unable to retrieve dashboards: %w", result.Error)
+	}
+	return results, nil
+}
+
+// GetDashboard retrieves the dashboard with the provided ID if it is
+// owned by the user or shared.
+func (c *Component) GetDashboard(ctx context.Context, user string, id uint64) (Dashboard, error) {
+	var d Dashboard
+	result := c.db.WithContext(ctx).
+		Where(c.db.Where(&Dashboard{User: user}).Or(&Dashboard{Shared: true})).
+		Take(&d, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return d, ErrNotFound
+	} else if result.Error != nil {
+		return d, fmt.Errorf("unable to retrieve dashboard: %w", result.Error)
+	}
+	return d, nil
+}
+
+// UpdateDashboard replaces the dashboard with the same ID. It should
+// be owned by the same user.
+func (c *Component) UpdateDashboard(ctx context.Context, d Dashboard) error {
+	return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current Dashboard
+		result := tx.Where(&Dashboard{User: d.User}).Take(&current, d.ID)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		} else if result.Error != nil {
+			return fmt.Errorf("unable to retrieve dashboard: %w", result.Error)
+		}
+		if err := checkVisualizations(tx, d.User, d.Visualizations); err != nil {
+			return err
+		}
+		if result := tx.Save(&d); result.Error != nil {
+			return fmt.Errorf("unable to update dashboard: %w", result.Error)
+		}
+		return nil
+	})
+}
+
+// DeleteDashboard deletes the provided dashboard. Visualizations are
+// kept.
+func (c *Component) DeleteDashboard(ctx context.Context, d Dashboard) error {
+	result := c.db.WithContext(ctx).Where(&Dashboard{User: d.User}).Delete(&d)
+	if result.Error != nil {
+		return fmt.Errorf("cannot delete dashboard: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
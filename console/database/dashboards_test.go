@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestDashboard(t *testing.T) {
+	r := reporter.NewMock(t)
+	c := NewMock(t, r, DefaultConfiguration())
+	ctx := context.Background()
+
+	visualization := func(user string, shared bool) uint64 {
+		id, err := c.CreateSavedVisualization(ctx, SavedVisualization{
+			User:        user,
+			Shared:      shared,
+			Description: "visualization",
+			GraphType:   "stacked",
+			Start:       "6 hours ago",
+			End:         "now",
+			Limit:       10,
+			Units:       "l3bps",
+		})
+		if err != nil {
+			t.Fatalf("CreateSavedVisualization() error:\n%+v", err)
+		}
+		return id
+	}
+	martyPrivate := visualization("marty", false)
+	martyShared := visualization("marty", true)
+	judithPrivate := visualization("judith", false)
+
+	// Create
+	if _, err := c.CreateDashboard(ctx, Dashboard{
+		User:           "judith",
+		Description:    "judith's dashboard",
+		Visualizations: []uint64{judithPrivate, martyPrivate},
+	}); !errors.Is(err, ErrUnknownVisualization) {
+		t.Fatalf("CreateDashboard(private visualization) error:\n%+v", err)
+	}
+	judithDashboard := Dashboard{
+		User:           "judith",
+		Shared:         true,
+		Description:    "judith's dashboard",
+		Visualizations: []uint64{judithPrivate, martyShared},
+	}
+	id, err := c.CreateDashboard(ctx, judithDashboard)
+	if err != nil {
+		t.Fatalf("CreateDashboard() error:\n%+v", err)
+	}
+	judithDashboard.ID = id
+	martyDashboard := Dashboard{
+		User:           "marty",
+		Description:    "marty's dashboard",
+		Visualizations: []uint64{martyPrivate, martyShared, martyPrivate},
+	}
+	id, err = c.CreateDashboard(ctx, martyDashboard)
+	if err != nil {
+		t.Fatalf("CreateDashboard() error:\n%+v", err)
+	}
+	martyDashboard.ID = id
+
+	// List and get
+	got, err := c.ListDashboards(ctx, "marty")
+	if err != nil {
+		t.Fatalf("ListDashboards() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(got, []Dashboard{judithDashboard, martyDashboard}); diff != "" {
+		t.Fatalf("ListDashboards(marty) (-got, +want):\n%s", diff)
+	}
+	if _, err := c.GetDashboard(ctx, "judith", martyDashboard.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetDashboard(judith, private) error:\n%+v", err)
+	}
+
+	// Update
+	martyDashboard.Visualizations = []uint64{martyShared}
+	martyDashboard.Shared = true
+	if err := c.UpdateDashboard(ctx, martyDashboard); err != nil {
+		t.Fatalf("UpdateDashboard() error:\n%+v", err)
+	}
+	gotDashboard, err := c.GetDashboard(ctx, "judith", martyDashboard.ID)
+	if err != nil {
+		t.Fatalf("GetDashboard(judith, shared) error:\n%+v", err)
+	}
+	if diff := helpers.Diff(gotDashboard, martyDashboard); diff != "" {
+		t.Fatalf("GetDashboard() after update (-got, +want):\n%s", diff)
+	}
+	martyDashboard.User = "judith"
+	if err := c.UpdateDashboard(ctx, martyDashboard); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("UpdateDashboard(judith, not owned) error:\n%+v", err)
+	}
+
+	// Delete
+	if err := c.DeleteDashboard(ctx, Dashboard{ID: judithDashboard.ID, User: "marty"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("DeleteDashboard(marty, not owned) error:\n%+v", err)
+	}
+	if err := c.DeleteDashboard(ctx, Dashboard{ID: judithDashboard.ID, User: "judith"}); err != nil {
+		t.Fatalf("DeleteDashboard() error:\n%+v", err)
+	}
+	got, err = c.ListDashboards(ctx, "judith")
+	if err != nil {
+		t.Fatalf("ListDashboards() error:\n%+v", err)
+	}
+	martyDashboard.User = "marty"
+	if diff := helpers.Diff(got, []Dashboard{martyDashboard}); diff != "" {
+		t.Fatalf("ListDashboards(judith) after delete (-got, +want):\n%s", diff)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"fmt"
 
 	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
 	"akvorado/common/reporter"
@@ -28,25 +30,31 @@ func New(r *reporter.Reporter, configuration Configuration) (*Component, error)
 		r:      r,
 		config: configuration,
 	}
+	var dialector gorm.Dialector
 	switch c.config.Driver {
 	case "sqlite":
-		db, err := gorm.Open(sqlite.Open(c.config.DSN), &gorm.Config{
-			Logger: &logger{r},
-		})
-		if err != nil {
-			return nil, fmt.Errorf("unable to open database: %w", err)
-		}
-		c.db = db
+		dialector = sqlite.Open(c.config.DSN)
+	case "postgres":
+		dialector = postgres.Open(c.config.DSN)
+	case "mysql":
+		dialector = mysql.Open(c.config.DSN)
 	default:
-		return nil, fmt.Errorf("%q is not a supporter driver", c.config.Driver)
+		return nil, fmt.Errorf("%q is not a supported driver", c.config.Driver)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: &logger{r},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database: %w", err)
 	}
+	c.db = db
 	return &c, nil
 }
 
 // Start starts the database component
 func (c *Component) Start() error {
 	c.r.Info().Msg("starting database component")
-	if err := c.db.AutoMigrate(&SavedFilter{}); err != nil {
+	if err := c.db.AutoMigrate(&SavedFilter{}, &SavedVisualization{}, &Dashboard{}, &APIToken{}); err != nil {
 		return fmt.Errorf("cannot migrate database: %w", err)
 	}
 	if err := c.populate(); err != nil {
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestUnknownDriver(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.Driver = "oracle"
+	if _, err := New(r, config); err == nil {
+		t.Fatal("New() did not error")
+	}
+}
+
+func TestPersistentDatabase(t *testing.T) {
+	config := DefaultConfiguration()
+	config.DSN = fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "console.sqlite"))
+
+	// First instance creates the schema and stores a filter
+	r := reporter.NewMock(t)
+	c, err := New(r, config)
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start() error:\n%+v", err)
+	}
+	if err := c.CreateSavedFilter(context.Background(), SavedFilter{
+		User:        "marty",
+		Description: "marty's filter",
+		Content:     "SrcAS = 12322",
+	}); err != nil {
+		t.Fatalf("CreateSavedFilter() error:\n%+v", err)
+	}
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error:\n%+v", err)
+	}
+
+	// Second instance migrates the existing schema and gets the filter back
+	r = reporter.NewMock(t)
+	c = NewMock(t, r, config)
+	got, err := c.ListSavedFilters(context.Background(), "marty")
+	if err != nil {
+		t.Fatalf("ListSavedFilters() error:\n%+v", err)
+	}
+	expected := []SavedFilter{{
+		ID:          1,
+		User:        "marty",
+		Description: "marty's filter",
+		Content:     "SrcAS = 12322",
+	}}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("ListSavedFilters() (-got, +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when the requested object does not exist or
+// is not owned by the user.
+var ErrNotFound = errors.New("not found")
+
+// SavedVisualization represents a saved state of the visualize tab in
+// database.
+type SavedVisualization struct {
+	ID             uint64   `json:"id"`
+	User           string   `gorm:"index" json:"user"`
+	Shared         bool     `json:"shared"`
+	Description    string   `json:"description" binding:"required"`
+	GraphType      string   `json:"graphType" binding:"required,oneof=stacked stacked100 lines grid sankey"`
+	Start          string   `json:"start" binding:"required"`
+	End            string   `json:"end" binding:"required"`
+	Filter         string   `json:"filter"`
+	Dimensions     []string `gorm:"serializer:json" json:"dimensions"`
+	Limit          int      `json:"limit" binding:"min=1"`
+	Units          string   `json:"units" binding:"required,oneof=pps l2bps l3bps flows inl2% outl2%"`
+	Bidirectional  bool     `json:"bidirectional"`
+	PreviousPeriod bool     `json:"previousPeriod"`
+}
+
+// CreateSavedVisualization creates a new saved visualization in
+// database and returns its ID.
+func (c *Component) CreateSavedVisualization(ctx context.Context, v SavedVisualization) (uint64, error) {
+	v.ID = 0
+	result := c.db.WithContext(ctx).Create(&v)
+	if result.Error != nil {
+		return 0, fmt.Errorf("unable to create new saved visualization: %w", result.Error)
+	}
+	return v.ID, nil
+}
+
+// ListSavedVisualizations list all saved visualizations for the
+// provided user.
+func (c *Component) ListSavedVisualizations(ctx context.Context, user string) ([]SavedVisualization, error) {
+	results := []SavedVisualization{}
+	result := c.db.WithContext(ctx).
+		Where(&SavedVisualization{User: user}).
+		Or(&SavedVisualization{Shared: true}).
+		Order("id").
+		Find(&results)
+	if result.Error != nil {
+		return nil, fmt.Errorf("unable to retrieve saved visualizations: %w", result.Error)
+	}
+	return results, nil
+}
+
+// GetSavedVisualization retrieves the saved visualization with the
+// provided ID if it is owned by the user or shared.
+func (c *Component) GetSavedVisualization(ctx context.Context, user string, id uint64) (SavedVisualization, error) {
+	var v SavedVisualization
+	result := c.db.WithContext(ctx).
+		Where(c.db.Where(&SavedVisualization{User: user}).Or(&SavedVisualization{Shared: true})).
+		Take(&v, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return v, ErrNotFound
+	} else if result.Error != nil {
+		return v, fmt.Errorf("unable to retrieve saved visualization: %w", result.Error)
+	}
+	return v, nil
+}
+
+// UpdateSavedVisualization replaces the saved visualization with the
+// same ID. It should be owned by the same user.
+func (c *Component) UpdateSavedVisualization(ctx context.Context, v SavedVisualization) error {
+	return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current SavedVisualization
+		result := tx.Where(&SavedVisualization{User: v.User}).Take(&current, v.ID)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		} else if result.Error != nil {
+			return fmt.Errorf("unable to retrieve saved visualization: %w", result.Error)
+		}
+		if result := tx.Save(&v); result.Error != nil {
+			return fmt.Errorf("unable to update saved visualization: %w", result.Error)
+		}
+		return nil
+	})
+}
+
+// DeleteSavedVisualization deletes the provided saved visualization.
+func (c *Component) DeleteSavedVisualization(ctx context.Context, v SavedVisualization) error {
+	result := c.db.WithContext(ctx).Where(&SavedVisualization{User: v.User}).Delete(&v)
+	if result.Error != nil {
+		return fmt.Errorf("cannot delete saved visualization: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestSavedVisualization(t *testing.T) {
+	r := reporter.NewMock(t)
+	c := NewMock(t, r, DefaultConfiguration())
+	ctx := context.Background()
+
+	// Create
+	martyViz := SavedVisualization{
+		User:        "marty",
+		Description: "marty's visualization",
+		GraphType:   "stacked",
+		Start:       "6 hours ago",
+		End:         "now",
+		Filter:      "InIfBoundary = external",
+		Dimensions:  []string{"SrcAS", "ExporterName"},
+		Limit:       10,
+		Units:       "l3bps",
+	}
+	id, err := c.CreateSavedVisualization(ctx, martyViz)
+	if err != nil {
+		t.Fatalf("CreateSavedVisualization() error:\n%+v", err)
+	}
+	martyViz.ID = id
+	judithViz := SavedVisualization{
+		User:        "judith",
+		Shared:      true,
+		Description: "judith's visualization",
+		GraphType:   "sankey",
+		Start:       "1 day ago",
+		End:         "now",
+		Dimensions:  []string{"SrcCountry", "DstCountry"},
+		Limit:       5,
+		Units:       "pps",
+	}
+	id, err = c.CreateSavedVisualization(ctx, judithViz)
+	if err != nil {
+		t.Fatalf("CreateSavedVisualization() error:\n%+v", err)
+	}
+	judithViz.ID = id
+
+	// List and get
+	got, err := c.ListSavedVisualizations(ctx, "marty")
+	if err != nil {
+		t.Fatalf("ListSavedVisualizations() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(got, []SavedVisualization{martyViz, judithViz}); diff != "" {
+		t.Fatalf("ListSavedVisualizations(marty) (-got, +want):\n%s", diff)
+	}
+	got, err = c.ListSavedVisualizations(ctx, "judith")
+	if err != nil {
+		t.Fatalf("ListSavedVisualizations() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(got, []SavedVisualization{judithViz}); diff != "" {
+		t.Fatalf("ListSavedVisualizations(judith) (-got, +want):\n%s", diff)
+	}
+	if _, err := c.GetSavedVisualization(ctx, "judith", martyViz.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetSavedVisualization(judith, private) error:\n%+v", err)
+	}
+	gotViz, err := c.GetSavedVisualization(ctx, "marty", judithViz.ID)
+	if err != nil {
+		t.Fatalf("GetSavedVisualization(marty, shared) error:\n%+v", err)
+	}
+	if diff := helpers.Diff(gotViz, judithViz); diff != "" {
+		t.Fatalf("GetSavedVisualization(marty, shared) (-got, +want):\n%s", diff)
+	}
+
+	// Update
+	judithViz.User = "marty"
+	judithViz.Limit = 20
+	if err := c.UpdateSavedVisualization(ctx, judithViz); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("UpdateSavedVisualization(marty, not owned) error:\n%+v", err)
+	}
+	judithViz.User = "judith"
+	if err := c.UpdateSavedVisualization(ctx, judithViz); err != nil {
+		t.Fatalf("UpdateSavedVisualization() error:\n%+v", err)
+	}
+	gotViz, err = c.GetSavedVisualization(ctx, "judith", judithViz.ID)
+	if err != nil {
+		t.Fatalf("GetSavedVisualization() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(gotViz, judithViz); diff != "" {
+		t.Fatalf("GetSavedVisualization() after update (-got, +want):\n%s", diff)
+	}
+
+	// Delete
+	if err := c.DeleteSavedVisualization(ctx, SavedVisualization{ID: judithViz.ID, User: "marty"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("DeleteSavedVisualization(marty, not owned) error:\n%+v", err)
+	}
+	if err := c.DeleteSavedVisualization(ctx, SavedVisualization{ID: judithViz.ID, User: "judith"}); err != nil {
+		t.Fatalf("DeleteSavedVisualization() error:\n%+v", err)
+	}
+	got, err = c.ListSavedVisualizations(ctx, "marty")
+	if err != nil {
+		t.Fatalf("ListSavedVisualizations() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(got, []SavedVisualization{martyViz}); diff != "" {
+		t.Fatalf("ListSavedVisualizations(marty) after delete (-got, +want):\n%s", diff)
+	}
+}
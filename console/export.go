@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/gin-gonic/gin"
+)
+
+// exportFlushEvery is the number of records after which the output is
+// flushed to the client when streaming an export.
+const exportFlushEvery = 1000
+
+// exportFormat returns the export format requested with the "format"
+// query parameter. An empty format means the regular output is
+// requested. On error, a response is sent and false is returned.
+func exportFormat(gc *gin.Context) (string, bool) {
+	format := gc.Query("format")
+	switch format {
+	case "", "csv", "json":
+		return format, true
+	}
+	gc.JSON(http.StatusBadRequest,
+		gin.H{"message": fmt.Sprintf("Unknown export format %q.", format)})
+	return "", false
+}
+
+// tabularWriter writes records either as CSV or as an array of flat
+// JSON objects. Output is flushed regularly to not buffer large
+// results.
+type tabularWriter struct {
+	w       gin.ResponseWriter
+	format  string
+	columns []string
+	csv     *csv.Writer
+	records int
+}
+
+// newTabularWriter sends the headers for the provided format and
+// returns a writer for records with the provided columns.
+func newTabularWriter(gc *gin.Context, format string, name string, columns []string) *tabularWriter {
+	tw := &tabularWriter{
+		w:       gc.Writer,
+		format:  format,
+		columns: columns,
+	}
+	switch format {
+	case "csv":
+		gc.Header("Content-Type", "text/csv; charset=utf-8")
+		gc.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, name))
+		gc.Status(http.StatusOK)
+		tw.csv = csv.NewWriter(gc.Writer)
+		tw.csv.Write(columns)
+	case "json":
+		gc.Header("Content-Type", "application/json; charset=utf-8")
+		gc.Status(http.StatusOK)
+		gc.Writer.WriteString("[")
+	}
+	return tw
+}
+
+// write writes a record. There should be one value for each column.
+func (tw *tabularWriter) write(values ...interface{}) error {
+	switch tw.format {
+	case "csv":
+		record := make([]string, len(values))
+		for idx, value := range values {
+			switch v := value.(type) {
+			case time.Time:
+				record[idx] = v.UTC().Format(time.RFC3339)
+			case float64:
+				record[idx] = strconv.FormatFloat(v, 'f', -1, 64)
+			case string:
+				record[idx] = csvEscape(v)
+			default:
+				record[idx] = csvEscape(fmt.Sprint(v))
+			}
+		}
+		if err := tw.csv.Write(record); err != nil {
+			return err
+		}
+	case "json":
+		var buf bytes.Buffer
+		if tw.records > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n{")
+		for idx, value := range values {
+			if idx > 0 {
+				buf.WriteString(",")
+			}
+			key, _ := json.Marshal(tw.columns[idx])
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteString(":")
+			buf.Write(encoded)
+		}
+		buf.WriteString("}")
+		if _, err := tw.w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	tw.records++
+	if tw.records%exportFlushEvery == 0 {
+		tw.flush()
+	}
+	return nil
+}
+
+// csvEscape prevents a cell to be interpreted as a formula by
+// spreadsheet applications by prefixing it with a single quote.
+func csvEscape(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + value
+	}
+	return value
+}
+
+// flush sends buffered records to the client.
+func (tw *tabularWriter) flush() {
+	if tw.csv != nil {
+		tw.csv.Flush()
+	}
+	tw.w.Flush()
+}
+
+// close terminates the output.
+func (tw *tabularWriter) close() error {
+	if tw.format == "json" {
+		tw.w.WriteString("\n]\n")
+	}
+	tw.flush()
+	if tw.csv != nil {
+		return tw.csv.Error()
+	}
+	return nil
+}
+
+// streamExport executes the provided query and streams the result as
+// records with the provided columns. The scan function turns the
+// current row into a record. Results are not cached.
+func (c *Component) streamExport(gc *gin.Context, format string, name string,
+	query string, columns []string, scan func(driver.Rows) ([]interface{}, error)) {
+	ctx := c.t.Context(gc.Request.Context())
+	rows, err := c.d.ClickHouseDB.Conn.Query(ctx, query)
+	if err != nil {
+		c.r.Err(err).Msg("unable to query database")
+		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to query database."})
+		return
+	}
+	defer rows.Close()
+
+	// From now on, we cannot report errors to the client.
+	tw := newTabularWriter(gc, format, name, columns)
+	for rows.Next() {
+		record, err := scan(rows)
+		if err != nil {
+			c.r.Err(err).Msg("unable to parse row")
+			break
+		}
+		if err := tw.write(record...); err != nil {
+			c.r.Err(err).Msg("unable to export row")
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		c.r.Err(err).Msg("unable to query database")
+	}
+	if err := tw.close(); err != nil {
+		c.r.Err(err).Msg("unable to export rows")
+	}
+}
@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+
+	"akvorado/common/clickhousedb/mocks"
+	"akvorado/common/helpers"
+)
+
+func TestExport(t *testing.T) {
+	_, h, mockConn, _ := NewMock(t, DefaultConfiguration())
+	ctrl := gomock.NewController(t)
+
+	// Graph
+	graphRows := mocks.NewMockRows(ctrl)
+	graphResults := []struct {
+		axis       uint8
+		time       time.Time
+		xps        float64
+		dimensions []string
+	}{
+		{1, time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC), 1000, []string{"router1", "provider1"}},
+		{1, time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC), 500.5, []string{"router1", "provider, 2"}},
+		{1, time.Date(2009, 11, 10, 23, 1, 0, 0, time.UTC), 0, []string{}},
+		{2, time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC), 100, []string{"router1", "provider1"}},
+	}
+	for _, result := range graphResults {
+		result := result
+		graphRows.EXPECT().Next().Return(true)
+		graphRows.EXPECT().Scan(gomock.Any()).
+			DoAndReturn(func(args ...interface{}) interface{} {
+				*args[0].(*uint8) = result.axis
+				*args[1].(*time.Time) = result.time
+				*args[2].(*float64) = result.xps
+				*args[3].(*[]string) = result.dimensions
+				return nil
+			})
+	}
+	graphRows.EXPECT().Next().Return(false)
+	graphRows.EXPECT().Err().Return(nil)
+	graphRows.EXPECT().Close()
+
+	// Sankey
+	sankeyRows := mocks.NewMockRows(ctrl)
+	sankeyResults := []struct {
+		xps        float64
+		dimensions []string
+	}{
+		{9677, []string{"AS100", "router1"}},
+		{9472, []string{"AS300", "Other"}},
+	}
+	for _, result := range sankeyResults {
+		result := result
+		sankeyRows.EXPECT().Next().Return(true)
+		sankeyRows.EXPECT().Scan(gomock.Any()).
+			DoAndReturn(func(args ...interface{}) interface{} {
+				*args[0].(*float64) = result.xps
+				*args[1].(*[]string) = result.dimensions
+				return nil
+			})
+	}
+	sankeyRows.EXPECT().Next().Return(false)
+	sankeyRows.EXPECT().Err().Return(nil)
+	sankeyRows.EXPECT().Close()
+
+	gomock.InOrder(
+		mockConn.EXPECT().Query(gomock.Any(), gomock.Any()).Return(graphRows, nil),
+		mockConn.EXPECT().Query(gomock.Any(), gomock.Any()).Return(sankeyRows, nil),
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).
+			SetArg(1, []topResult{
+				{"TCP", float64(75)},
+				{"UDP", float64(24.5)},
+			}),
+	)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "graph as CSV",
+			URL:         "/api/v0/console/graph?format=csv",
+			JSONInput: gin.H{
+				"start":         time.Date(2009, 11, 10, 23, 0, 0, 0, time.UTC),
+				"end":           time.Date(2009, 11, 11, 23, 0, 0, 0, time.UTC),
+				"points":        100,
+				"limit":         20,
+				"dimensions":    []string{"ExporterName", "InIfProvider"},
+				"filter":        "DstCountry = 'FR' AND SrcCountry = 'US'",
+				"units":         "l3bps",
+				"bidirectional": true,
+			},
+			ContentType: "text/csv; charset=utf-8",
+			FirstLines: []string{
+				"time,axis,ExporterName,InIfProvider,l3bps",
+				"2009-11-10T23:00:00Z,Direct,router1,provider1,1000",
+				`2009-11-10T23:00:00Z,Direct,router1,"provider, 2",500.5`,
+				"2009-11-10T23:01:00Z,Direct,Other,Other,0",
+				"2009-11-10T23:00:00Z,Reverse,router1,provider1,100",
+			},
+		}, {
+			Description: "sankey as JSON",
+			URL:         "/api/v0/console/sankey?format=json",
+			JSONInput: gin.H{
+				"start":      time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC),
+				"end":        time.Date(2022, 04, 11, 15, 45, 10, 0, time.UTC),
+				"dimensions": []string{"SrcAS", "ExporterName"},
+				"limit":      10,
+				"filter":     "DstCountry = 'FR'",
+				"units":      "pps",
+			},
+			ContentType: "application/json; charset=utf-8",
+			FirstLines: []string{
+				"[",
+				`{"SrcAS":"AS100","ExporterName":"router1","pps":9677},`,
+				`{"SrcAS":"AS300","ExporterName":"Other","pps":9472}`,
+				"]",
+			},
+		}, {
+			Description: "top widget as CSV",
+			URL:         "/api/v0/console/widget/top/protocol?format=csv",
+			ContentType: "text/csv; charset=utf-8",
+			FirstLines: []string{
+				"name,percent",
+				"TCP,75",
+				"UDP,24.5",
+			},
+		}, {
+			Description: "unknown format",
+			URL:         "/api/v0/console/widget/top/protocol?format=xml",
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": `Unknown export format "xml".`},
+		},
+	})
+}
+
+func TestCSVEscape(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Expected string
+	}{
+		{"", ""},
+		{"router1", "router1"},
+		{"AS65000: ACME", "AS65000: ACME"},
+		{"=HYPERLINK(\"http://example.com\")", "'=HYPERLINK(\"http://example.com\")"},
+		{"+33 1 23", "'+33 1 23"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1", "'\t=1"},
+	}
+	for _, tc := range cases {
+		if got := csvEscape(tc.Input); got != tc.Expected {
+			t.Errorf("csvEscape(%q) == %q but expected %q", tc.Input, got, tc.Expected)
+		}
+	}
+}
@@ -82,6 +82,7 @@ func (c *Component) filterCompleteHandlerFunc(gc *gin.Context) {
 	}
 
 	completions := []filterCompletion{}
+	scope := c.scopeSQL(gc)
 	switch input.What {
 	case "column":
 		_, err := filter.Parse("", []byte{},
@@ -145,6 +146,26 @@ func (c *Component) filterCompleteHandlerFunc(gc *gin.Context) {
 				Label:  "IPv6",
 				Detail: "ethernet type",
 			})
+		case "tcpflags":
+			for _, flag := range []string{"fin", "syn", "rst", "psh", "ack", "urg", "ece", "cwr", "ns"} {
+				completions = append(completions, filterCompletion{
+					Label:  flag,
+					Detail: "TCP flag",
+				})
+			}
+		case "dscp":
+			completions = append(completions, filterCompletion{
+				Label:  "ef",
+				Detail: "DSCP class",
+			})
+			for _, class := range []string{"af11", "af12", "af13", "af21", "af22", "af23",
+				"af31", "af32", "af33", "af41", "af42", "af43",
+				"cs0", "cs1", "cs2", "cs3", "cs4", "cs5", "cs6", "cs7"} {
+				completions = append(completions, filterCompletion{
+					Label:  class,
+					Detail: "DSCP class",
+				})
+			}
 		case "proto":
 			// Do not complete from Clickhouse, we want a subset of options
 			completions = append(completions,
@@ -168,7 +189,7 @@ func (c *Component) filterCompleteHandlerFunc(gc *gin.Context) {
 				Label  string `ch:"label"`
 				Detail string `ch:"detail"`
 			}{}
-			sqlQuery := `
+			sqlQuery := fmt.Sprintf(`
 SELECT label, detail FROM (
  SELECT
   'community' AS detail,
@@ -176,7 +197,7 @@ SELECT label, detail FROM (
  FROM (
   SELECT arrayJoin(DstCommunities) AS c
   FROM flows
-  WHERE TimeReceived > date_sub(minute, 1, now())
+  WHERE TimeReceived > date_sub(minute, 1, now())%s
   GROUP BY c
   ORDER BY COUNT(*) DESC
  )
@@ -189,13 +210,13 @@ SELECT label, detail FROM (
  FROM (
   SELECT arrayJoin(DstLargeCommunities) AS c
   FROM flows
-  WHERE TimeReceived > date_sub(minute, 1, now())
+  WHERE TimeReceived > date_sub(minute, 1, now())%s
   GROUP BY c
   ORDER BY COUNT(*) DESC
  )
 )
 WHERE startsWith(label, $1)
-LIMIT 20`
+LIMIT 20`, scope, scope)
 			if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery, input.Prefix); err != nil {
 				c.r.Err(err).Msg("unable to query database")
 				break
@@ -221,7 +242,7 @@ LIMIT 20`
 SELECT label, detail FROM (
  SELECT concat('AS', toString(%s)) AS label, dictGet('asns', 'name', %s) AS detail, 1 AS rank
  FROM flows
- WHERE TimeReceived > date_sub(minute, 1, now())
+ WHERE TimeReceived > date_sub(minute, 1, now())%s
  AND detail != ''
  AND positionCaseInsensitive(detail, $1) >= 1
  GROUP BY %s
@@ -234,7 +255,7 @@ UNION DISTINCT
  ORDER BY positionCaseInsensitive(name, $1) ASC, asn ASC
  LIMIT 20
 ) GROUP BY label, detail ORDER BY MIN(rank) ASC, MIN(rowNumberInBlock()) ASC LIMIT 20`,
-				columnName, columnName, columnName)
+				columnName, columnName, scope, columnName)
 			if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery, input.Prefix); err != nil {
 				c.r.Err(err).Msg("unable to query database")
 				break
@@ -248,6 +269,12 @@ UNION DISTINCT
 			}
 			input.Prefix = "" // We have handled this internally
 		case "srcnetname", "dstnetname", "srcnetrole", "dstnetrole", "srcnetsite", "dstnetsite", "srcnetregion", "dstnetregion", "srcnettenant", "dstnettenant":
+			if scope != "" {
+				// The list of networks may leak information
+				column = fixQueryColumnName(inputColumn)
+				detail = "network name"
+				break
+			}
 			attributeName := inputColumn[6:]
 			results := []struct {
 				Attribute string `ch:"attribute"`
@@ -294,6 +321,18 @@ WHERE positionCaseInsensitive(%s, $1) >= 1
 GROUP BY %s
 ORDER BY positionCaseInsensitive(%s, $1) ASC, %s ASC
 LIMIT 20`, column, column, column, column, column)
+			if scope != "" {
+				// Only complete from visible traffic
+				column = fixQueryColumnName(inputColumn)
+				sqlQuery = fmt.Sprintf(`
+SELECT %s AS label
+FROM flows
+WHERE TimeReceived > date_sub(hour, 1, now())%s
+AND positionCaseInsensitive(%s, $1) >= 1
+GROUP BY %s
+ORDER BY positionCaseInsensitive(%s, $1) ASC, %s ASC
+LIMIT 20`, column, scope, column, column, column, column)
+			}
 			results := []struct {
 				Label string `ch:"label"`
 			}{}
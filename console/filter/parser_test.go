@@ -153,6 +153,38 @@ func TestValidFilter(t *testing.T) {
 			MetaOut: Meta{ReverseDirection: true, MainTableRequired: true}},
 		{Input: `DstPort > 1024`, Output: `DstPort > 1024`,
 			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `SrcVlan = 100`, Output: `SrcVlan = 100`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `SrcVlan = 100`, Output: `DstVlan = 100`,
+			MetaIn:  Meta{ReverseDirection: true},
+			MetaOut: Meta{ReverseDirection: true, MainTableRequired: true}},
+		{Input: `DstMAC = 00:1B:21:3C:4D:5E`, Output: `DstMAC = MACStringToNum('00:1b:21:3c:4d:5e')`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `SrcMAC != 00:1b:21:3c:4d:5e`, Output: `DstMAC != MACStringToNum('00:1b:21:3c:4d:5e')`,
+			MetaIn:  Meta{ReverseDirection: true},
+			MetaOut: Meta{ReverseDirection: true, MainTableRequired: true}},
+		{Input: `MPLS1stLabel = 16001`, Output: `MPLS1stLabel = 16001`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `IPTTL < 5`, Output: `IPTTL < 5`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `DSCP = ef`, Output: `DSCP = 46`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `DSCP != AF41`, Output: `DSCP != 34`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `DSCP = cs6`, Output: `DSCP = 48`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `DSCP = 10`, Output: `DSCP = 10`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `TCPFlags HAS syn`, Output: `bitAnd(TCPFlags, 2) = 2`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `TCPFlags has SYN|ACK`, Output: `bitAnd(TCPFlags, 18) = 18`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `TCPFlags = syn`, Output: `TCPFlags = 2`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `TCPFlags != 18`, Output: `TCPFlags != 18`,
+			MetaOut: Meta{MainTableRequired: true}},
+		{Input: `IcmpType = 8 AND IcmpCode = 0`, Output: `IcmpType = 8 AND IcmpCode = 0`,
+			MetaOut: Meta{MainTableRequired: true}},
 		{Input: `ForwardingStatus >= 128`, Output: `ForwardingStatus >= 128`},
 		{Input: `PacketSize > 1500`, Output: `Bytes/Packets > 1500`},
 		{Input: `DstPort > 1024 AND SrcPort < 1024`, Output: `DstPort > 1024 AND SrcPort < 1024`,
@@ -249,6 +281,17 @@ func TestInvalidFilter(t *testing.T) {
 		{`Proto = 1000`},
 		{`SrcPort = 1000000`},
 		{`ForwardingStatus >= 900`},
+		{`SrcVlan = 100000`},
+		{`SrcMAC = 00:1b:21:3c:4d`},
+		{`DstMAC = 00:1b:21:3c:4d:5g`},
+		{`DSCP = af51`},
+		{`DSCP = cs8`},
+		{`DSCP > ef`},
+		{`TCPFlags HAS syn|`},
+		{`TCPFlags HAS sin`},
+		{`TCPFlags HAS 2`},
+		{`IPTTL = 256`},
+		{`IcmpType = echo`},
 		{`Proto = 100 AND`},
 		{`AND Proto = 100`},
 		{`Proto = 100AND Proto = 100`},
@@ -164,6 +164,7 @@ LIMIT 20`, "6540").
 				{"label": "DstAddr", "detail": "column name", "quoted": false},
 				{"label": "DstCommunities", "detail": "column name", "quoted": false},
 				{"label": "DstCountry", "detail": "column name", "quoted": false},
+				{"label": "DstMAC", "detail": "column name", "quoted": false},
 				{"label": "DstNetName", "detail": "column name", "quoted": false},
 				{"label": "DstNetPrefix", "detail": "column name", "quoted": false},
 				{"label": "DstNetRegion", "detail": "column name", "quoted": false},
@@ -171,6 +172,7 @@ LIMIT 20`, "6540").
 				{"label": "DstNetSite", "detail": "column name", "quoted": false},
 				{"label": "DstNetTenant", "detail": "column name", "quoted": false},
 				{"label": "DstPort", "detail": "column name", "quoted": false},
+				{"label": "DstVlan", "detail": "column name", "quoted": false},
 			}},
 		}, {
 			URL:        "/api/v0/console/filter/complete",
@@ -203,6 +205,24 @@ LIMIT 20`, "6540").
 				{"label": "IPv4", "detail": "ethernet type", "quoted": false},
 				{"label": "IPv6", "detail": "ethernet type", "quoted": false},
 			}},
+		}, {
+			URL:        "/api/v0/console/filter/complete",
+			StatusCode: 200,
+			JSONInput:  gin.H{"what": "operator", "column": "TCPFlags"},
+			JSONOutput: gin.H{"completions": []gin.H{
+				{"label": "!=", "detail": "comparison operator", "quoted": false},
+				{"label": "=", "detail": "comparison operator", "quoted": false},
+				{"label": "HAS", "detail": "comparison operator", "quoted": false},
+			}},
+		}, {
+			URL:        "/api/v0/console/filter/complete",
+			StatusCode: 200,
+			JSONInput:  gin.H{"what": "value", "column": "dscp", "prefix": "af4"},
+			JSONOutput: gin.H{"completions": []gin.H{
+				{"label": "af41", "detail": "DSCP class", "quoted": false},
+				{"label": "af42", "detail": "DSCP class", "quoted": false},
+				{"label": "af43", "detail": "DSCP class", "quoted": false},
+			}},
 		}, {
 			URL:        "/api/v0/console/filter/complete",
 			StatusCode: 200,
@@ -1,8 +1,9 @@
 // SPDX-FileCopyrightText: 2022 Free Mobile
 // SPDX-License-Identifier: AGPL-3.0-only
 
-export function formatXps(value: number) {
+export function formatXps(value: number, units?: string) {
   value = Math.abs(value);
+  if (units?.endsWith("%")) return `${value.toFixed(2)}%`;
   const suffixes = ["", "K", "M", "G", "T"];
   let idx = 0;
   while (value >= 1000 && idx < suffixes.length) {
@@ -12,6 +13,19 @@ export function formatXps(value: number) {
   return `${value.toFixed(2)}${suffixes[idx]}`;
 }
 
+// Suffix to append to a value formatted with formatXps()
+export function unitsSuffix(units: string) {
+  switch (units) {
+    case "flows":
+      return "fps";
+    case "inl2%":
+    case "outl2%":
+      return "";
+    default:
+      return units.slice(-3);
+  }
+}
+
 // Order function for field names
 export function compareFields(f1: string, f2: string) {
   const metric: { [prefix: string]: number } = {
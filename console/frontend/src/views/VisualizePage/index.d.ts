@@ -3,7 +3,13 @@
 
 import type { GraphType } from "./graphtypes";
 
-export type Units = "l3bps" | "l2bps" | "pps";
+export type Units =
+  | "l3bps"
+  | "l2bps"
+  | "pps"
+  | "flows"
+  | "inl2%"
+  | "outl2%";
 export type SankeyHandlerInput = {
   start: string;
   end: string;
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/gin-gonic/gin"
 
 	"akvorado/common/helpers"
@@ -23,7 +24,7 @@ type graphHandlerInput struct {
 	Dimensions     []queryColumn `json:"dimensions"`                               // group by ...
 	Limit          int           `json:"limit" binding:"min=1"`                    // limit product of dimensions
 	Filter         queryFilter   `json:"filter"`                                   // where ...
-	Units          string        `json:"units" binding:"required,oneof=pps l2bps l3bps"`
+	Units          string        `json:"units" binding:"required,oneof=pps l2bps l3bps flows inl2% outl2%"`
 	Bidirectional  bool          `json:"bidirectional"`
 	PreviousPeriod bool          `json:"previous-period"`
 }
@@ -52,6 +53,12 @@ func (input graphHandlerInput) reverseDirection() graphHandlerInput {
 	for i := range dimensions {
 		input.Dimensions[i] = dimensions[i].reverseDirection()
 	}
+	switch input.Units {
+	case "inl2%":
+		input.Units = "outl2%"
+	case "outl2%":
+		input.Units = "inl2%"
+	}
 	return input
 }
 
@@ -124,7 +131,7 @@ func (input graphHandlerInput) toSQL1(axis int, options toSQL1Options) string {
 	for _, column := range input.Dimensions {
 		field := column.toSQLSelect()
 		selectFields = append(selectFields, field)
-		dimensions = append(dimensions, column.String())
+		dimensions = append(dimensions, column.toSQLKey())
 		others = append(others, "'Other'")
 	}
 	if len(dimensions) > 0 {
@@ -204,31 +211,88 @@ func (input graphHandlerInput) toSQL() string {
 	return strings.Join(parts, "\nUNION ALL\n")
 }
 
+// axisName returns the name of the provided axis.
+func (input graphHandlerInput) axisName(axis int) string {
+	switch axis {
+	case 1:
+		return "Direct"
+	case 2:
+		return "Reverse"
+	case 3, 4:
+		diff := input.End.Sub(input.Start)
+		_, name := nearestPeriod(diff)
+		return fmt.Sprintf("Previous %s", name)
+	}
+	return ""
+}
+
 func (c *Component) graphHandlerFunc(gc *gin.Context) {
-	ctx := c.t.Context(gc.Request.Context())
 	var input graphHandlerInput
 	if err := gc.ShouldBindJSON(&input); err != nil {
 		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
 		return
 	}
+	format, ok := exportFormat(gc)
+	if !ok {
+		return
+	}
 	if input.Limit > c.config.DimensionsLimit {
 		gc.JSON(http.StatusBadRequest,
 			gin.H{"message": fmt.Sprintf("Limit is set beyond maximum value (%d)",
 				c.config.DimensionsLimit)})
 		return
 	}
+	if err := c.checkUnits(input.Units, input.Dimensions); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if input.PreviousPeriod && (input.Units == "inl2%" || input.Units == "outl2%") {
+		gc.JSON(http.StatusBadRequest,
+			gin.H{"message": "Previous period is not available for interface utilization"})
+		return
+	}
+	input.Filter = input.Filter.and(c.scopeFilter(gc))
 
-	sqlQuery := input.toSQL()
-	sqlQuery = c.finalizeQuery(sqlQuery)
+	sqlQuery, resolution := c.finalizeQuery(input.toSQL())
 	gc.Header("X-SQL-Query", strings.ReplaceAll(sqlQuery, "\n", "  "))
 
+	if format != "" {
+		columns := []string{"time", "axis"}
+		for _, column := range input.Dimensions {
+			columns = append(columns, column.String())
+		}
+		columns = append(columns, input.Units)
+		c.streamExport(gc, format, "graph", sqlQuery, columns, func(rows driver.Rows) ([]interface{}, error) {
+			var (
+				axis       uint8
+				t          time.Time
+				xps        float64
+				dimensions []string
+			)
+			if err := rows.Scan(&axis, &t, &xps, &dimensions); err != nil {
+				return nil, err
+			}
+			record := []interface{}{t, input.axisName(int(axis))}
+			for idx := range input.Dimensions {
+				// Interpolated rows come without dimensions
+				if idx < len(dimensions) {
+					record = append(record, dimensions[idx])
+				} else {
+					record = append(record, "Other")
+				}
+			}
+			return append(record, xps), nil
+		})
+		return
+	}
+
 	results := []struct {
 		Axis       uint8     `ch:"axis"`
 		Time       time.Time `ch:"time"`
 		Xps        float64   `ch:"xps"`
 		Dimensions []string  `ch:"dimensions"`
 	}{}
-	if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
+	if err := c.cachedSelect(gc, &results, sqlQuery, resolution); err != nil {
 		c.r.Err(err).Msg("unable to query database")
 		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to query database."})
 		return
@@ -388,16 +452,7 @@ func (c *Component) graphHandlerFunc(gc *gin.Context) {
 	}
 
 	for _, axis := range output.Axis {
-		switch axis {
-		case 1:
-			output.AxisNames[axis] = "Direct"
-		case 2:
-			output.AxisNames[axis] = "Reverse"
-		case 3, 4:
-			diff := input.End.Sub(input.Start)
-			_, name := nearestPeriod(diff)
-			output.AxisNames[axis] = fmt.Sprintf("Previous %s", name)
-		}
+		output.AxisNames[axis] = input.axisName(axis)
 	}
 	gc.JSON(http.StatusOK, output)
 }
@@ -53,6 +53,12 @@ func TestGraphInputReverseDirection(t *testing.T) {
 	if original1 != original2 {
 		t.Fatalf("reverseDirection() modified original to:\n-%s\n+%s", original1, original2)
 	}
+
+	// Interface utilization is computed on the other interface
+	input.Units = "inl2%"
+	if got := input.reverseDirection().Units; got != "outl2%" {
+		t.Fatalf("reverseDirection().Units == %q but expected %q", got, "outl2%")
+	}
 }
 
 func TestGraphPreviousPeriod(t *testing.T) {
@@ -850,6 +856,37 @@ func TestGraphHandler(t *testing.T) {
 					3: "Previous day",
 				},
 			},
+		}, {
+			Description: "interface utilization without interface dimensions",
+			URL:         "/api/v0/console/graph",
+			JSONInput: gin.H{
+				"start":      time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC),
+				"end":        time.Date(2022, 04, 11, 15, 45, 10, 0, time.UTC),
+				"points":     100,
+				"limit":      20,
+				"dimensions": []string{"ExporterName", "InIfProvider"},
+				"units":      "inl2%",
+			},
+			StatusCode: 400,
+			JSONOutput: gin.H{
+				"message": "Units inl2% require the InIfName dimension and the ExporterName or ExporterAddress dimension",
+			},
+		}, {
+			Description: "interface utilization with previous period",
+			URL:         "/api/v0/console/graph",
+			JSONInput: gin.H{
+				"start":           time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC),
+				"end":             time.Date(2022, 04, 11, 15, 45, 10, 0, time.UTC),
+				"points":          100,
+				"limit":           20,
+				"dimensions":      []string{"ExporterName", "OutIfName"},
+				"units":           "outl2%",
+				"previous-period": true,
+			},
+			StatusCode: 400,
+			JSONOutput: gin.H{
+				"message": "Previous period is not available for interface utilization",
+			},
 		},
 	})
 }
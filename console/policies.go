@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"akvorado/console/authentication"
+)
+
+// policy is the compiled version of a policy from the configuration.
+type policy struct {
+	users  map[string]struct{}
+	groups map[string]struct{}
+	filter queryFilter
+}
+
+// compilePolicies parses the filters of the provided policies.
+func compilePolicies(configs []PolicyConfiguration) ([]policy, error) {
+	policies := make([]policy, 0, len(configs))
+	for idx, config := range configs {
+		p := policy{
+			users:  map[string]struct{}{},
+			groups: map[string]struct{}{},
+		}
+		for _, user := range config.Users {
+			p.users[user] = struct{}{}
+		}
+		for _, group := range config.Groups {
+			p.groups[group] = struct{}{}
+		}
+		if err := p.filter.UnmarshalText([]byte(config.Filter)); err != nil {
+			return nil, fmt.Errorf("invalid filter for policy %d: %w", idx, err)
+		}
+		if p.filter.Filter == "" {
+			return nil, fmt.Errorf("empty filter for policy %d", idx)
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// matches tells if the policy applies to the provided user.
+func (p policy) matches(user authentication.UserInformation) bool {
+	if _, ok := p.users[user.Login]; ok {
+		return true
+	}
+	for _, group := range user.Groups {
+		if _, ok := p.groups[group]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeFilter returns the filter the current user is restricted to.
+// When several policies apply, the user can see the traffic matching
+// any of them. The filter is empty when the user is not restricted.
+// The scope does not depend on the direction of the traffic: the
+// reverse filter is the same as the direct one.
+func (c *Component) scopeFilter(gc *gin.Context) queryFilter {
+	user := gc.MustGet("user").(authentication.UserInformation)
+	var scope queryFilter
+	matched := false
+	for _, p := range c.policies {
+		if !p.matches(user) {
+			continue
+		}
+		if !matched {
+			scope = p.filter
+			matched = true
+			continue
+		}
+		scope = scope.or(p.filter)
+	}
+	scope.ReverseFilter = scope.Filter
+	return scope
+}
+
+// scopeSQL returns an SQL condition to append to a WHERE clause to
+// restrict the current user to its scope.
+func (c *Component) scopeSQL(gc *gin.Context) string {
+	scope := c.scopeFilter(gc)
+	if scope.Filter == "" {
+		return ""
+	}
+	return fmt.Sprintf(" AND (%s)", scope.Filter)
+}
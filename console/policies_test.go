@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	stdContext "context"
+	netHTTP "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+
+	"akvorado/common/clickhousedb/mocks"
+	"akvorado/common/helpers"
+	"akvorado/console/authentication"
+	"akvorado/console/database"
+)
+
+func TestCompilePolicies(t *testing.T) {
+	cases := []struct {
+		Description string
+		Policies    []PolicyConfiguration
+		Error       bool
+	}{
+		{
+			Description: "no policy",
+		}, {
+			Description: "valid policies",
+			Policies: []PolicyConfiguration{
+				{Users: []string{"alfred"}, Filter: `ExporterTenant = "acme"`},
+				{Groups: []string{"globex"}, Filter: `InIfProvider = "globex"`},
+			},
+		}, {
+			Description: "invalid filter",
+			Policies: []PolicyConfiguration{
+				{Users: []string{"alfred"}, Filter: `ExporterTenant = `},
+			},
+			Error: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			_, err := compilePolicies(tc.Policies)
+			if err != nil && !tc.Error {
+				t.Fatalf("compilePolicies() error:\n%+v", err)
+			} else if err == nil && tc.Error {
+				t.Fatal("compilePolicies() did not error")
+			}
+		})
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	config := DefaultConfiguration()
+	config.Policies = []PolicyConfiguration{
+		{Users: []string{"alfred"}, Filter: `ExporterTenant = "acme"`},
+		{Groups: []string{"globex"}, Filter: `InIfProvider = "globex"`},
+		{Users: []string{"bruce"}, Filter: `ExporterTenant = "wayne"`},
+		{Groups: []string{"justice"}, Filter: `ExporterTenant = "league"`},
+	}
+	c, h, mockConn, _ := NewMock(t, config)
+	_, token, err := c.d.Database.CreateAPIToken(stdContext.Background(), database.APIToken{
+		User:        "lucius",
+		Groups:      []string{"globex"},
+		Description: "automation",
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIToken() error:\n%+v", err)
+	}
+
+	ctrl := gomock.NewController(t)
+	mockRow := mocks.NewMockRow(ctrl)
+	mockRow.EXPECT().Err().Return(nil).AnyTimes()
+	mockRow.EXPECT().Scan(gomock.Any()).SetArg(0, float64(100.1)).Return(nil).AnyTimes()
+	gomock.InOrder(
+		mockConn.EXPECT().
+			QueryRow(gomock.Any(),
+				`SELECT COUNT(*)/300 AS rate FROM flows WHERE TimeReceived > date_sub(minute, 5, now())`).
+			Return(mockRow),
+		mockConn.EXPECT().
+			QueryRow(gomock.Any(),
+				`SELECT COUNT(*)/300 AS rate FROM flows WHERE TimeReceived > date_sub(minute, 5, now()) AND (ExporterTenant = 'acme')`).
+			Return(mockRow),
+		mockConn.EXPECT().
+			QueryRow(gomock.Any(),
+				`SELECT COUNT(*)/300 AS rate FROM flows WHERE TimeReceived > date_sub(minute, 5, now()) AND ((ExporterTenant = 'wayne') OR (ExporterTenant = 'league'))`).
+			Return(mockRow),
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(),
+				`SELECT ExporterName FROM flows WHERE TimeReceived > date_sub(hour, 1, now()) AND (InIfProvider = 'globex') GROUP BY ExporterName ORDER BY ExporterName`).
+			SetArg(1, []struct{ ExporterName string }{{"exporter1"}}).
+			Return(nil),
+	)
+
+	asUser := func(user string, groups string) netHTTP.Header {
+		headers := make(netHTTP.Header)
+		headers.Add("Remote-User", user)
+		if groups != "" {
+			headers.Add("Remote-Groups", groups)
+		}
+		return headers
+	}
+	withToken := func(token string) netHTTP.Header {
+		headers := make(netHTTP.Header)
+		headers.Add("Authorization", "Bearer "+token)
+		return headers
+	}
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "unrestricted user",
+			URL:         "/api/v0/console/widget/flow-rate",
+			Header:      asUser("lucius", ""),
+			JSONOutput:  gin.H{"period": "second", "rate": 100.1},
+		}, {
+			Description: "user restricted by login",
+			URL:         "/api/v0/console/widget/flow-rate",
+			Header:      asUser("alfred", ""),
+			JSONOutput:  gin.H{"period": "second", "rate": 100.1},
+		}, {
+			Description: "user restricted by login and group",
+			URL:         "/api/v0/console/widget/flow-rate",
+			Header:      asUser("bruce", "justice"),
+			JSONOutput:  gin.H{"period": "second", "rate": 100.1},
+		}, {
+			Description: "user restricted by group",
+			URL:         "/api/v0/console/widget/exporters",
+			Header:      asUser("lucius", "globex"),
+			JSONOutput:  gin.H{"exporters": []string{"exporter1"}},
+		}, {
+			// Same query as above: served from the cache
+			Description: "user restricted by group with API token",
+			URL:         "/api/v0/console/widget/exporters",
+			Header:      withToken(token),
+			JSONOutput:  gin.H{"exporters": []string{"exporter1"}},
+		},
+	})
+}
+
+func TestPoliciesBidirectional(t *testing.T) {
+	config := DefaultConfiguration()
+	config.Policies = []PolicyConfiguration{
+		{Groups: []string{"globex"}, Filter: `InIfProvider = "globex"`},
+	}
+	c, _, _, _ := NewMock(t, config)
+	gc, _ := gin.CreateTestContext(httptest.NewRecorder())
+	gc.Set("user", authentication.UserInformation{Login: "lucius", Groups: []string{"globex"}})
+
+	input := graphHandlerInput{
+		Start:         time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC),
+		End:           time.Date(2022, 04, 11, 15, 45, 10, 0, time.UTC),
+		Points:        100,
+		Units:         "l3bps",
+		Bidirectional: true,
+	}
+	input.Filter = input.Filter.and(c.scopeFilter(gc))
+	sqlQuery := input.toSQL()
+	if got := strings.Count(sqlQuery, "(InIfProvider = 'globex')"); got != 2 {
+		t.Errorf("toSQL() should restrict both axes to the scope, got %d occurrences:\n%s",
+			got, sqlQuery)
+	}
+	if strings.Contains(sqlQuery, "OutIfProvider") {
+		t.Errorf("toSQL() should not reverse the scope:\n%s", sqlQuery)
+	}
+}
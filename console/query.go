@@ -6,45 +6,117 @@ package console
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
-	"akvorado/common/helpers"
+	"akvorado/common/schema"
 	"akvorado/console/filter"
 )
 
+// queryColumn is a column usable as a dimension. The lowest byte is
+// the column itself (see query_consts.go). For address columns, the
+// two next bytes are the IPv4 and IPv6 prefix lengths to truncate
+// addresses to (0 when they are not truncated).
 type queryColumn int
 
+const (
+	queryColumnKeyMask    = 0xff
+	queryColumnIPv4Offset = 8
+	queryColumnIPv6Offset = 16
+)
+
+// queryColumnsTruncatable lists query columns that can be truncated
+// to a prefix length, like SrcAddr/24 or SrcAddr/24/48.
+var queryColumnsTruncatable = map[queryColumn]struct{}{
+	queryColumnSrcAddr: {},
+	queryColumnDstAddr: {},
+}
+
+// key returns the column without the truncation.
+func (qc queryColumn) key() queryColumn {
+	return qc & queryColumnKeyMask
+}
+
+// truncation returns the IPv4 and IPv6 prefix lengths to truncate
+// the column to (0 when not truncated).
+func (qc queryColumn) truncation() (int, int) {
+	return int(qc>>queryColumnIPv4Offset) & 0xff, int(qc>>queryColumnIPv6Offset) & 0xff
+}
+
+// truncate returns the column truncated to the provided IPv4 and
+// IPv6 prefix lengths.
+func (qc queryColumn) truncate(ipv4, ipv6 int) queryColumn {
+	return qc.key() | queryColumn(ipv4<<queryColumnIPv4Offset) | queryColumn(ipv6<<queryColumnIPv6Offset)
+}
+
 func (qc queryColumn) MarshalText() ([]byte, error) {
-	got, ok := queryColumnMap.LoadValue(qc)
+	got, ok := queryColumnMap.LoadValue(qc.key())
 	if ok {
-		return []byte(got), nil
+		return []byte(got + qc.truncationSuffix()), nil
 	}
 	return nil, errors.New("unknown field")
 }
 func (qc queryColumn) String() string {
-	got, _ := queryColumnMap.LoadValue(qc)
-	return got
+	got, _ := queryColumnMap.LoadValue(qc.key())
+	return got + qc.truncationSuffix()
 }
 func (qc *queryColumn) UnmarshalText(input []byte) error {
-	got, ok := queryColumnMap.LoadKey(string(input))
-	if ok {
+	parts := strings.Split(string(input), "/")
+	got, ok := queryColumnMap.LoadKey(parts[0])
+	if !ok {
+		return errors.New("unknown field")
+	}
+	if len(parts) == 1 {
 		*qc = got
 		return nil
 	}
-	return errors.New("unknown field")
+	if _, ok := queryColumnsTruncatable[got]; !ok || len(parts) > 3 {
+		return fmt.Errorf("field %s cannot be truncated", parts[0])
+	}
+	lengths := make([]int, len(parts)-1)
+	for idx, part := range parts[1:] {
+		length, err := strconv.Atoi(part)
+		if err != nil || length < 1 || length > 128 {
+			return fmt.Errorf("invalid prefix length %q for %s", part, parts[0])
+		}
+		lengths[idx] = length
+	}
+	var ipv4, ipv6 int
+	switch {
+	case len(lengths) == 2:
+		ipv4, ipv6 = lengths[0], lengths[1]
+	case lengths[0] <= 32:
+		ipv4 = lengths[0]
+	default:
+		ipv6 = lengths[0]
+	}
+	if ipv4 > 32 {
+		return fmt.Errorf("invalid IPv4 prefix length %d for %s", ipv4, parts[0])
+	}
+	*qc = got.truncate(ipv4, ipv6)
+	return nil
+}
+
+// truncationSuffix returns the suffix to append to the column name
+// when the column is truncated.
+func (qc queryColumn) truncationSuffix() string {
+	ipv4, ipv6 := qc.truncation()
+	switch {
+	case ipv4 == 0 && ipv6 == 0:
+		return ""
+	case ipv6 == 0:
+		return fmt.Sprintf("/%d", ipv4)
+	case ipv4 == 0 && ipv6 > 32:
+		return fmt.Sprintf("/%d", ipv6)
+	case ipv4 == 0:
+		return fmt.Sprintf("/32/%d", ipv6)
+	}
+	return fmt.Sprintf("/%d/%d", ipv4, ipv6)
 }
 
-// queryColumnsRequiringMainTable lists query columns only present in
-// the main table. Also check filter/parser.peg.
-var queryColumnsRequiringMainTable = map[queryColumn]struct{}{
-	queryColumnSrcAddr:        {},
-	queryColumnDstAddr:        {},
-	queryColumnSrcNetPrefix:   {},
-	queryColumnDstNetPrefix:   {},
-	queryColumnSrcPort:        {},
-	queryColumnDstPort:        {},
-	queryColumnDstASPath:      {},
-	queryColumnDstCommunities: {},
+// truncated tells if the column is truncated.
+func (qc queryColumn) truncated() bool {
+	return qc != qc.key()
 }
 
 func requireMainTable(qcs []queryColumn, qf queryFilter) bool {
@@ -52,7 +124,7 @@ func requireMainTable(qcs []queryColumn, qf queryFilter) bool {
 		return true
 	}
 	for _, qc := range qcs {
-		if _, ok := queryColumnsRequiringMainTable[qc]; ok {
+		if schema.ColumnRequiresMainTable(qc.key().String()) {
 			return true
 		}
 	}
@@ -94,39 +166,86 @@ func (qf *queryFilter) UnmarshalText(input []byte) error {
 	return nil
 }
 
+// and combines two filters: both of them should match.
+func (qf queryFilter) and(other queryFilter) queryFilter {
+	if other.Filter == "" {
+		return qf
+	}
+	if qf.Filter == "" {
+		return other
+	}
+	return queryFilter{
+		Filter:            fmt.Sprintf("(%s) AND (%s)", qf.Filter, other.Filter),
+		ReverseFilter:     fmt.Sprintf("(%s) AND (%s)", qf.ReverseFilter, other.ReverseFilter),
+		MainTableRequired: qf.MainTableRequired || other.MainTableRequired,
+	}
+}
+
+// or combines two filters: any of them should match.
+func (qf queryFilter) or(other queryFilter) queryFilter {
+	if other.Filter == "" || qf.Filter == "" {
+		return queryFilter{}
+	}
+	return queryFilter{
+		Filter:            fmt.Sprintf("(%s) OR (%s)", qf.Filter, other.Filter),
+		ReverseFilter:     fmt.Sprintf("(%s) OR (%s)", qf.ReverseFilter, other.ReverseFilter),
+		MainTableRequired: qf.MainTableRequired || other.MainTableRequired,
+	}
+}
+
+// prefixLengths returns the IPv4 and IPv6 prefix lengths to truncate
+// the column to, using the full length when not truncated.
+func (qc queryColumn) prefixLengths() (int, int) {
+	ipv4, ipv6 := qc.truncation()
+	if ipv4 == 0 {
+		ipv4 = 32
+	}
+	if ipv6 == 0 {
+		ipv6 = 128
+	}
+	return ipv4, ipv6
+}
+
+// truncatedAddrSQL returns an expression telling if an address is
+// an IPv4 address and an expression truncating the address.
+func (qc queryColumn) truncatedAddrSQL() (string, string) {
+	ipv4, ipv6 := qc.prefixLengths()
+	isIPv4 := fmt.Sprintf(`tupleElement(IPv6CIDRToRange(%s, 96), 1) = toIPv6('::ffff:0.0.0.0')`, qc.key())
+	return isIPv4, fmt.Sprintf(`tupleElement(IPv6CIDRToRange(%s, if(%s, %d, %d)), 1)`,
+		qc.key(), isIPv4, 96+ipv4, ipv6)
+}
+
+// toSQLKey transforms a column into an expression to use in GROUP BY
+func (qc queryColumn) toSQLKey() string {
+	if qc.truncated() {
+		_, key := qc.truncatedAddrSQL()
+		return key
+	}
+	return qc.String()
+}
+
 // toSQLSelect transforms a column into an expression to use in SELECT
 func (qc queryColumn) toSQLSelect() string {
-	var strValue string
-	switch qc {
-	case queryColumnExporterAddress, queryColumnSrcAddr, queryColumnDstAddr:
-		strValue = fmt.Sprintf("replaceRegexpOne(IPv6NumToString(%s), '^::ffff:', '')", qc)
-	case queryColumnSrcAS, queryColumnDstAS, queryColumnDst1stAS, queryColumnDst2ndAS, queryColumnDst3rdAS:
-		strValue = fmt.Sprintf(`concat(toString(%s), ': ', dictGetOrDefault('asns', 'name', %s, '???'))`,
-			qc, qc)
-	case queryColumnEType:
-		strValue = fmt.Sprintf(`if(EType = %d, 'IPv4', if(EType = %d, 'IPv6', '???'))`,
-			helpers.ETypeIPv4, helpers.ETypeIPv6)
-	case queryColumnProto:
-		strValue = `dictGetOrDefault('protocols', 'name', Proto, '???')`
-	case queryColumnInIfSpeed, queryColumnOutIfSpeed, queryColumnSrcPort, queryColumnDstPort, queryColumnForwardingStatus, queryColumnInIfBoundary, queryColumnOutIfBoundary:
-		strValue = fmt.Sprintf("toString(%s)", qc)
-	case queryColumnDstASPath:
-		strValue = `arrayStringConcat(DstASPath, ' ')`
-	case queryColumnDstCommunities:
-		strValue = `arrayStringConcat(arrayConcat(arrayMap(c -> concat(toString(bitShiftRight(c, 16)), ':', toString(bitAnd(c, 0xffff))), DstCommunities), arrayMap(c -> concat(toString(bitAnd(bitShiftRight(c, 64), 0xffffffff)), ':', toString(bitAnd(bitShiftRight(c, 32), 0xffffffff)), ':', toString(bitAnd(c, 0xffffffff))), DstLargeCommunities)), ' ')`
-	default:
-		strValue = qc.String()
+	if qc.truncated() {
+		ipv4, ipv6 := qc.prefixLengths()
+		isIPv4, key := qc.truncatedAddrSQL()
+		return fmt.Sprintf(`concat(replaceRegexpOne(IPv6NumToString(%s), '^::ffff:', ''), if(%s, '/%d', '/%d'))`,
+			key, isIPv4, ipv4, ipv6)
+	}
+	strValue, err := schema.ColumnToSQL(qc.String())
+	if err != nil {
+		panic(err)
 	}
 	return strValue
 }
 
 // reverseDirection reverse the direction of a column (src/dst, in/out)
 func (qc queryColumn) reverseDirection() queryColumn {
-	value, ok := queryColumnMap.LoadKey(filter.ReverseColumnDirection(qc.String()))
+	value, ok := queryColumnMap.LoadKey(filter.ReverseColumnDirection(qc.key().String()))
 	if !ok {
 		panic("unknown reverse column")
 	}
-	return value
+	return value | (qc &^ queryColumnKeyMask)
 }
 
 // fixQueryColumnName fix capitalization of the provided column name
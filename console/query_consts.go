@@ -31,6 +31,8 @@ const (
 	queryColumnSrcPort
 	queryColumnSrcAddr
 	queryColumnSrcNetPrefix
+	queryColumnSrcVlan
+	queryColumnSrcMAC
 	queryColumnDstAS
 	queryColumnDstASPath
 	queryColumnDst1stAS
@@ -52,6 +54,14 @@ const (
 	queryColumnDstAddr
 	queryColumnDstNetPrefix
 	queryColumnDstPort
+	queryColumnDstVlan
+	queryColumnDstMAC
+	queryColumnMPLS1stLabel
+	queryColumnIPTTL
+	queryColumnDSCP
+	queryColumnTCPFlags
+	queryColumnIcmpType
+	queryColumnIcmpCode
 	queryColumnForwardingStatus
 	queryColumnPacketSizeBucket
 )
@@ -105,4 +115,14 @@ var queryColumnMap = bimap.New(map[queryColumn]string{
 	queryColumnDstPort:           "DstPort",
 	queryColumnForwardingStatus:  "ForwardingStatus",
 	queryColumnPacketSizeBucket:  "PacketSizeBucket",
+	queryColumnSrcVlan:           "SrcVlan",
+	queryColumnDstVlan:           "DstVlan",
+	queryColumnSrcMAC:            "SrcMAC",
+	queryColumnDstMAC:            "DstMAC",
+	queryColumnMPLS1stLabel:      "MPLS1stLabel",
+	queryColumnIPTTL:             "IPTTL",
+	queryColumnDSCP:              "DSCP",
+	queryColumnTCPFlags:          "TCPFlags",
+	queryColumnIcmpType:          "IcmpType",
+	queryColumnIcmpCode:          "IcmpCode",
 })
@@ -4,11 +4,23 @@
 package console
 
 import (
+	"sort"
 	"testing"
 
 	"akvorado/common/helpers"
+	"akvorado/common/schema"
 )
 
+func TestQueryColumnsMatchSchema(t *testing.T) {
+	got := queryColumnMap.Values()
+	expected := append([]string{}, schema.Columns...)
+	sort.Strings(got)
+	sort.Strings(expected)
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("queryColumnMap.Values() (-got, +want):\n%s", diff)
+	}
+}
+
 func TestRequireMainTable(t *testing.T) {
 	cases := []struct {
 		Columns  []queryColumn
@@ -22,8 +34,13 @@ func TestRequireMainTable(t *testing.T) {
 		{[]queryColumn{queryColumnSrcAddr}, queryFilter{}, true},
 		{[]queryColumn{queryColumnDstPort}, queryFilter{}, true},
 		{[]queryColumn{queryColumnDstAddr}, queryFilter{}, true},
+		{[]queryColumn{queryColumnDstAddr.truncate(24, 48)}, queryFilter{}, true},
 		{[]queryColumn{queryColumnSrcAS, queryColumnDstAddr}, queryFilter{}, true},
 		{[]queryColumn{queryColumnDstAddr, queryColumnSrcAS}, queryFilter{}, true},
+		{[]queryColumn{queryColumnSrcMAC}, queryFilter{}, true},
+		{[]queryColumn{queryColumnMPLS1stLabel}, queryFilter{}, true},
+		{[]queryColumn{queryColumnTCPFlags}, queryFilter{}, true},
+		{[]queryColumn{queryColumnDSCP}, queryFilter{}, true},
 		{[]queryColumn{}, queryFilter{MainTableRequired: true}, true},
 	}
 	for idx, tc := range cases {
@@ -42,6 +59,9 @@ func TestQueryColumnSQLSelect(t *testing.T) {
 		{
 			Input:    queryColumnSrcAddr,
 			Expected: `replaceRegexpOne(IPv6NumToString(SrcAddr), '^::ffff:', '')`,
+		}, {
+			Input:    queryColumnSrcAddr.truncate(24, 48),
+			Expected: `concat(replaceRegexpOne(IPv6NumToString(tupleElement(IPv6CIDRToRange(SrcAddr, if(tupleElement(IPv6CIDRToRange(SrcAddr, 96), 1) = toIPv6('::ffff:0.0.0.0'), 120, 48)), 1)), '^::ffff:', ''), if(tupleElement(IPv6CIDRToRange(SrcAddr, 96), 1) = toIPv6('::ffff:0.0.0.0'), '/24', '/48'))`,
 		}, {
 			Input:    queryColumnDstAS,
 			Expected: `concat(toString(DstAS), ': ', dictGetOrDefault('asns', 'name', DstAS, '???'))`,
@@ -66,6 +86,18 @@ func TestQueryColumnSQLSelect(t *testing.T) {
 		}, {
 			Input:    queryColumnDstASPath,
 			Expected: `arrayStringConcat(DstASPath, ' ')`,
+		}, {
+			Input:    queryColumnSrcMAC,
+			Expected: `MACNumToString(SrcMAC)`,
+		}, {
+			Input:    queryColumnDstVlan,
+			Expected: `toString(DstVlan)`,
+		}, {
+			Input:    queryColumnDSCP,
+			Expected: `toString(DSCP)`,
+		}, {
+			Input:    queryColumnTCPFlags,
+			Expected: `arrayStringConcat(arrayFilter((f, b) -> bitTest(TCPFlags, b), ['fin', 'syn', 'rst', 'psh', 'ack', 'urg', 'ece', 'cwr', 'ns'], range(9)), '|')`,
 		}, {
 			Input:    queryColumnDstCommunities,
 			Expected: `arrayStringConcat(arrayConcat(arrayMap(c -> concat(toString(bitShiftRight(c, 16)), ':', toString(bitAnd(c, 0xffff))), DstCommunities), arrayMap(c -> concat(toString(bitAnd(bitShiftRight(c, 64), 0xffffffff)), ':', toString(bitAnd(bitShiftRight(c, 32), 0xffffffff)), ':', toString(bitAnd(c, 0xffffffff))), DstLargeCommunities)), ' ')`,
@@ -81,6 +113,53 @@ func TestQueryColumnSQLSelect(t *testing.T) {
 	}
 }
 
+func TestQueryColumnTruncation(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Expected queryColumn
+		String   string
+		Error    bool
+	}{
+		{Input: "SrcAddr", Expected: queryColumnSrcAddr, String: "SrcAddr"},
+		{Input: "SrcAddr/24", Expected: queryColumnSrcAddr.truncate(24, 0), String: "SrcAddr/24"},
+		{Input: "DstAddr/48", Expected: queryColumnDstAddr.truncate(0, 48), String: "DstAddr/48"},
+		{Input: "DstAddr/24/48", Expected: queryColumnDstAddr.truncate(24, 48), String: "DstAddr/24/48"},
+		{Input: "SrcAddr/32/32", Expected: queryColumnSrcAddr.truncate(32, 32), String: "SrcAddr/32/32"},
+		{Input: "SrcAddr/0", Error: true},
+		{Input: "SrcAddr/129", Error: true},
+		{Input: "SrcAddr/48/48", Error: true},
+		{Input: "SrcAddr/24/48/64", Error: true},
+		{Input: "SrcAddr/", Error: true},
+		{Input: "SrcAS/24", Error: true},
+		{Input: "Unknown/24", Error: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Input, func(t *testing.T) {
+			var got queryColumn
+			err := got.UnmarshalText([]byte(tc.Input))
+			if err != nil && !tc.Error {
+				t.Fatalf("UnmarshalText(%q) error:\n%+v", tc.Input, err)
+			} else if err == nil && tc.Error {
+				t.Fatalf("UnmarshalText(%q) did not error", tc.Input)
+			}
+			if tc.Error {
+				return
+			}
+			if got != tc.Expected {
+				t.Fatalf("UnmarshalText(%q) == %d but expected %d", tc.Input, got, tc.Expected)
+			}
+			if got.String() != tc.String {
+				t.Fatalf("String() == %q but expected %q", got.String(), tc.String)
+			}
+		})
+	}
+
+	reversed := queryColumnSrcAddr.truncate(24, 48).reverseDirection()
+	if reversed != queryColumnDstAddr.truncate(24, 48) {
+		t.Errorf("reverseDirection() == %s but expected DstAddr/24/48", reversed)
+	}
+}
+
 func TestUnmarshalFilter(t *testing.T) {
 	cases := []struct {
 		Input    string
@@ -34,9 +34,13 @@ type Component struct {
 
 	flowsTables     []flowsTable
 	flowsTablesLock sync.RWMutex
+	policies        []policy
+	cache           *queryCache
 
 	metrics struct {
 		clickhouseQueries *reporter.CounterVec
+		cacheHits         reporter.Counter
+		cacheMisses       reporter.Counter
 	}
 }
 
@@ -55,11 +59,17 @@ func New(r *reporter.Reporter, config Configuration, dependencies Dependencies)
 	if dependencies.Clock == nil {
 		dependencies.Clock = clock.New()
 	}
+	policies, err := compilePolicies(config.Policies)
+	if err != nil {
+		return nil, err
+	}
 	c := Component{
 		r:           r,
 		d:           &dependencies,
 		config:      config,
-		flowsTables: []flowsTable{{"flows", 0, time.Time{}}},
+		flowsTables: []flowsTable{{Name: "flows", Resolution: 0, Oldest: time.Time{}}},
+		policies:    policies,
+		cache:       newQueryCache(r, dependencies.Clock, config.Cache),
 	}
 
 	c.d.Daemon.Track(&c.t, "console")
@@ -70,6 +80,16 @@ func New(r *reporter.Reporter, config Configuration, dependencies Dependencies)
 			Help: "Number of requests to ClickHouse.",
 		}, []string{"table"},
 	)
+	c.metrics.cacheHits = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "cache_hits_total",
+			Help: "Number of ClickHouse results retrieved from cache.",
+		})
+	c.metrics.cacheMisses = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "cache_misses_total",
+			Help: "Number of ClickHouse results not found in cache.",
+		})
 	return &c, nil
 }
 
@@ -78,6 +98,10 @@ func (c *Component) Start() error {
 	c.r.Info().Msg("starting console component")
 
 	c.d.HTTP.AddHandler("/", netHTTP.HandlerFunc(c.assetsHandlerFunc))
+	auth := c.d.HTTP.GinRouter.Group("/api/v0/console/auth")
+	auth.GET("/login", c.d.Auth.OIDCLoginHandlerFunc)
+	auth.GET("/callback", c.d.Auth.OIDCCallbackHandlerFunc)
+	auth.POST("/logout", c.d.Auth.OIDCLogoutHandlerFunc)
 	endpoint := c.d.HTTP.GinRouter.Group("/api/v0/console", c.d.Auth.UserAuthentication())
 	endpoint.GET("/configuration", c.configHandlerFunc)
 	endpoint.GET("/docs/:name", c.docsHandlerFunc)
@@ -88,13 +112,27 @@ func (c *Component) Start() error {
 	endpoint.GET("/widget/graph", c.widgetGraphHandlerFunc)
 	endpoint.POST("/graph", c.graphHandlerFunc)
 	endpoint.POST("/sankey", c.sankeyHandlerFunc)
+	endpoint.GET("/billing", c.billingHandlerFunc)
 	endpoint.POST("/filter/validate", c.filterValidateHandlerFunc)
 	endpoint.POST("/filter/complete", c.filterCompleteHandlerFunc)
 	endpoint.GET("/filter/saved", c.filterSavedListHandlerFunc)
 	endpoint.DELETE("/filter/saved/:id", c.filterSavedDeleteHandlerFunc)
 	endpoint.POST("/filter/saved", c.filterSavedAddHandlerFunc)
+	endpoint.GET("/visualization/saved", c.visualizationSavedListHandlerFunc)
+	endpoint.GET("/visualization/saved/:id", c.visualizationSavedGetHandlerFunc)
+	endpoint.PUT("/visualization/saved/:id", c.visualizationSavedUpdateHandlerFunc)
+	endpoint.DELETE("/visualization/saved/:id", c.visualizationSavedDeleteHandlerFunc)
+	endpoint.POST("/visualization/saved", c.visualizationSavedAddHandlerFunc)
+	endpoint.GET("/dashboard/saved", c.dashboardSavedListHandlerFunc)
+	endpoint.GET("/dashboard/saved/:id", c.dashboardSavedGetHandlerFunc)
+	endpoint.PUT("/dashboard/saved/:id", c.dashboardSavedUpdateHandlerFunc)
+	endpoint.DELETE("/dashboard/saved/:id", c.dashboardSavedDeleteHandlerFunc)
+	endpoint.POST("/dashboard/saved", c.dashboardSavedAddHandlerFunc)
 	endpoint.GET("/user/info", c.d.Auth.UserInfoHandlerFunc)
 	endpoint.GET("/user/avatar", c.d.Auth.UserAvatarHandlerFunc)
+	endpoint.GET("/user/tokens", c.d.Auth.APITokenListHandlerFunc)
+	endpoint.POST("/user/tokens", c.d.Auth.APITokenCreateHandlerFunc)
+	endpoint.DELETE("/user/tokens/:id", c.d.Auth.APITokenDeleteHandlerFunc)
 
 	c.t.Go(func() error {
 		ticker := time.NewTicker(10 * time.Second)
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/gin-gonic/gin"
 
 	"akvorado/common/helpers"
@@ -22,7 +23,7 @@ type sankeyHandlerInput struct {
 	Dimensions []queryColumn `json:"dimensions" binding:"required,min=2"` // group by ...
 	Limit      int           `json:"limit" binding:"min=1,max=50"`        // limit product of dimensions
 	Filter     queryFilter   `json:"filter"`                              // where ...
-	Units      string        `json:"units" binding:"required,oneof=pps l3bps l2bps"`
+	Units      string        `json:"units" binding:"required,oneof=pps l3bps l2bps flows inl2% outl2%"`
 }
 
 // sankeyHandlerOutput describes the output for the /sankey endpoint.
@@ -46,13 +47,24 @@ func (input sankeyHandlerInput) toSQL() (string, error) {
 
 	// Select
 	arrayFields := []string{}
+	rowsFields := []string{}
 	dimensions := []string{}
 	for _, column := range input.Dimensions {
+		key := column.toSQLKey()
+		rowsKey := key
+		if column.truncated() {
+			// Use an alias to be able to select the
+			// truncated address from rows.
+			rowsKey = fmt.Sprintf("`%s`", column)
+			rowsFields = append(rowsFields, fmt.Sprintf("%s AS %s", key, rowsKey))
+		} else {
+			rowsFields = append(rowsFields, key)
+		}
 		arrayFields = append(arrayFields, fmt.Sprintf(`if(%s IN (SELECT %s FROM rows), %s, 'Other')`,
-			column.String(),
-			column.String(),
+			key,
+			rowsKey,
 			column.toSQLSelect()))
-		dimensions = append(dimensions, column.String())
+		dimensions = append(dimensions, key)
 	}
 	fields := []string{
 		`{{ .Units }}/range AS xps`,
@@ -64,7 +76,7 @@ func (input sankeyHandlerInput) toSQL() (string, error) {
 		fmt.Sprintf(`(SELECT MAX(TimeReceived) - MIN(TimeReceived) FROM {{ .Table }} WHERE %s) AS range`, where),
 		fmt.Sprintf(
 			"rows AS (SELECT %s FROM {{ .Table }} WHERE %s GROUP BY %s ORDER BY SUM(Bytes) DESC LIMIT %d)",
-			strings.Join(dimensions, ", "),
+			strings.Join(rowsFields, ", "),
 			where,
 			strings.Join(dimensions, ", "),
 			input.Limit),
@@ -93,12 +105,20 @@ ORDER BY xps DESC
 }
 
 func (c *Component) sankeyHandlerFunc(gc *gin.Context) {
-	ctx := c.t.Context(gc.Request.Context())
 	var input sankeyHandlerInput
 	if err := gc.ShouldBindJSON(&input); err != nil {
 		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
 		return
 	}
+	format, ok := exportFormat(gc)
+	if !ok {
+		return
+	}
+	if err := c.checkUnits(input.Units, input.Dimensions); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	input.Filter = input.Filter.and(c.scopeFilter(gc))
 
 	sqlQuery, err := input.toSQL()
 	if err != nil {
@@ -107,13 +127,35 @@ func (c *Component) sankeyHandlerFunc(gc *gin.Context) {
 	}
 
 	// Prepare and execute query
-	sqlQuery = c.finalizeQuery(sqlQuery)
+	sqlQuery, resolution := c.finalizeQuery(sqlQuery)
 	gc.Header("X-SQL-Query", strings.ReplaceAll(sqlQuery, "\n", "  "))
+	if format != "" {
+		columns := []string{}
+		for _, column := range input.Dimensions {
+			columns = append(columns, column.String())
+		}
+		columns = append(columns, input.Units)
+		c.streamExport(gc, format, "sankey", sqlQuery, columns, func(rows driver.Rows) ([]interface{}, error) {
+			var (
+				xps        float64
+				dimensions []string
+			)
+			if err := rows.Scan(&xps, &dimensions); err != nil {
+				return nil, err
+			}
+			record := make([]interface{}, 0, len(columns))
+			for _, dimension := range dimensions {
+				record = append(record, dimension)
+			}
+			return append(record, xps), nil
+		})
+		return
+	}
 	results := []struct {
 		Xps        float64  `ch:"xps"`
 		Dimensions []string `ch:"dimensions"`
 	}{}
-	if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
+	if err := c.cachedSelect(gc, &results, sqlQuery, resolution); err != nil {
 		c.r.Err(err).Msg("unable to query database")
 		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to query database."})
 		return
@@ -69,6 +69,30 @@ GROUP BY dimensions
 ORDER BY xps DESC
 {{ end }}
 `,
+		}, {
+			Description: "truncated address, no filters, l3 bps",
+			Input: sankeyHandlerInput{
+				Start:      time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC),
+				End:        time.Date(2022, 04, 11, 15, 45, 10, 0, time.UTC),
+				Dimensions: []queryColumn{queryColumnSrcAddr.truncate(24, 0), queryColumnExporterName},
+				Limit:      5,
+				Filter:     queryFilter{},
+				Units:      "l3bps",
+			},
+			Expected: `
+{{ with context @@{"start":"2022-04-10T15:45:10Z","end":"2022-04-11T15:45:10Z","main-table-required":true,"points":20,"units":"l3bps"}@@ }}
+WITH
+ (SELECT MAX(TimeReceived) - MIN(TimeReceived) FROM {{ .Table }} WHERE {{ .Timefilter }}) AS range,
+ rows AS (SELECT tupleElement(IPv6CIDRToRange(SrcAddr, if(tupleElement(IPv6CIDRToRange(SrcAddr, 96), 1) = toIPv6('::ffff:0.0.0.0'), 120, 128)), 1) AS @@SrcAddr/24@@, ExporterName FROM {{ .Table }} WHERE {{ .Timefilter }} GROUP BY tupleElement(IPv6CIDRToRange(SrcAddr, if(tupleElement(IPv6CIDRToRange(SrcAddr, 96), 1) = toIPv6('::ffff:0.0.0.0'), 120, 128)), 1), ExporterName ORDER BY SUM(Bytes) DESC LIMIT 5)
+SELECT
+ {{ .Units }}/range AS xps,
+ [if(tupleElement(IPv6CIDRToRange(SrcAddr, if(tupleElement(IPv6CIDRToRange(SrcAddr, 96), 1) = toIPv6('::ffff:0.0.0.0'), 120, 128)), 1) IN (SELECT @@SrcAddr/24@@ FROM rows), concat(replaceRegexpOne(IPv6NumToString(tupleElement(IPv6CIDRToRange(SrcAddr, if(tupleElement(IPv6CIDRToRange(SrcAddr, 96), 1) = toIPv6('::ffff:0.0.0.0'), 120, 128)), 1)), '^::ffff:', ''), if(tupleElement(IPv6CIDRToRange(SrcAddr, 96), 1) = toIPv6('::ffff:0.0.0.0'), '/24', '/128')), 'Other'),
+  if(ExporterName IN (SELECT ExporterName FROM rows), ExporterName, 'Other')] AS dimensions
+FROM {{ .Table }}
+WHERE {{ .Timefilter }}
+GROUP BY dimensions
+ORDER BY xps DESC
+{{ end }}`,
 		}, {
 			Description: "two dimensions, no filters, pps",
 			Input: sankeyHandlerInput{
@@ -293,6 +317,20 @@ func TestSankeyHandler(t *testing.T) {
 						"xps": 975 + 621},
 				},
 			},
+		}, {
+			Description: "interface utilization without exporter dimension",
+			URL:         "/api/v0/console/sankey",
+			JSONInput: gin.H{
+				"start":      time.Date(2022, 04, 10, 15, 45, 10, 0, time.UTC),
+				"end":        time.Date(2022, 04, 11, 15, 45, 10, 0, time.UTC),
+				"dimensions": []string{"SrcAS", "InIfName"},
+				"limit":      10,
+				"units":      "inl2%",
+			},
+			StatusCode: 400,
+			JSONOutput: gin.H{
+				"message": "Units inl2% require the InIfName dimension and the ExporterName or ExporterAddress dimension",
+			},
 		},
 	})
 }
@@ -27,13 +27,14 @@ func NewMock(t *testing.T, config Configuration) (*Component, *http.Component, *
 	h := http.NewMock(t, r)
 	ch, mockConn := clickhousedb.NewMock(t, r)
 	mockClock := clock.NewMock()
+	db := database.NewMock(t, r, database.DefaultConfiguration())
 	c, err := New(r, config, Dependencies{
 		Daemon:       daemon.NewMock(t),
 		HTTP:         h,
 		ClickHouseDB: ch,
 		Clock:        mockClock,
-		Auth:         authentication.NewMock(t, r),
-		Database:     database.NewMock(t, r, database.DefaultConfiguration()),
+		Auth:         authentication.NewMock(t, r, db),
+		Database:     db,
 	})
 	if err != nil {
 		t.Fatalf("New() error:\n%+v", err)
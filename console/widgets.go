@@ -17,7 +17,12 @@ import (
 
 func (c *Component) widgetFlowLastHandlerFunc(gc *gin.Context) {
 	ctx := c.t.Context(gc.Request.Context())
-	query := `
+	where := "TimeReceived=(SELECT MAX(TimeReceived) FROM flows)"
+	if scope := c.scopeFilter(gc); scope.Filter != "" {
+		where = fmt.Sprintf("TimeReceived=(SELECT MAX(TimeReceived) FROM flows WHERE %s) AND (%s)",
+			scope.Filter, scope.Filter)
+	}
+	query := fmt.Sprintf(`
 SELECT *
 EXCEPT (DstCommunities, DstLargeCommunities),
  arrayMap(c -> concat(toString(bitShiftRight(c, 16)), ':',
@@ -26,8 +31,8 @@ EXCEPT (DstCommunities, DstLargeCommunities),
                       toString(bitAnd(bitShiftRight(c, 32), 0xffffffff)), ':',
                       toString(bitAnd(c, 0xffffffff))), DstLargeCommunities) AS DstLargeCommunities
 FROM flows
-WHERE TimeReceived=(SELECT MAX(TimeReceived) FROM flows)
-LIMIT 1`
+WHERE %s
+LIMIT 1`, where)
 	gc.Header("X-SQL-Query", query)
 	// Do not increase counter for this one.
 	rows, err := c.d.ClickHouseDB.Conn.Query(ctx, query)
@@ -64,7 +69,8 @@ LIMIT 1`
 
 func (c *Component) widgetFlowRateHandlerFunc(gc *gin.Context) {
 	ctx := c.t.Context(gc.Request.Context())
-	query := `SELECT COUNT(*)/300 AS rate FROM flows WHERE TimeReceived > date_sub(minute, 5, now())`
+	query := fmt.Sprintf(`SELECT COUNT(*)/300 AS rate FROM flows WHERE TimeReceived > date_sub(minute, 5, now())%s`,
+		c.scopeSQL(gc))
 	gc.Header("X-SQL-Query", query)
 	// Do not increase counter for this one.
 	row := c.d.ClickHouseDB.Conn.QueryRow(ctx, query)
@@ -86,15 +92,19 @@ func (c *Component) widgetFlowRateHandlerFunc(gc *gin.Context) {
 }
 
 func (c *Component) widgetExportersHandlerFunc(gc *gin.Context) {
-	ctx := c.t.Context(gc.Request.Context())
 	query := `SELECT ExporterName FROM exporters GROUP BY ExporterName ORDER BY ExporterName`
+	if scope := c.scopeSQL(gc); scope != "" {
+		// Only list exporters with visible traffic
+		query = fmt.Sprintf(`SELECT ExporterName FROM flows WHERE TimeReceived > date_sub(hour, 1, now())%s GROUP BY ExporterName ORDER BY ExporterName`,
+			scope)
+	}
 	gc.Header("X-SQL-Query", query)
 	// Do not increase counter for this one.
 
 	exporters := []struct {
 		ExporterName string
 	}{}
-	err := c.d.ClickHouseDB.Conn.Select(ctx, &exporters, query)
+	err := c.cachedSelect(gc, &exporters, query, 0)
 	if err != nil {
 		c.r.Err(err).Msg("unable to query database")
 		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to query database."})
@@ -114,7 +124,10 @@ type topResult struct {
 }
 
 func (c *Component) widgetTopHandlerFunc(gc *gin.Context) {
-	ctx := c.t.Context(gc.Request.Context())
+	format, ok := exportFormat(gc)
+	if !ok {
+		return
+	}
 	var (
 		selector          string
 		groupby           string
@@ -160,9 +173,13 @@ func (c *Component) widgetTopHandlerFunc(gc *gin.Context) {
 	if groupby == "" {
 		groupby = selector
 	}
+	if scope := c.scopeFilter(gc); scope.Filter != "" {
+		filter = fmt.Sprintf("%s AND (%s)", filter, templateEscape(scope.Filter))
+		mainTableRequired = mainTableRequired || scope.MainTableRequired
+	}
 
 	now := c.d.Clock.Now()
-	query := c.finalizeQuery(fmt.Sprintf(`
+	query, resolution := c.finalizeQuery(fmt.Sprintf(`
 {{ with %s }}
 WITH
  (SELECT SUM(Bytes*SamplingRate) FROM {{ .Table }} WHERE {{ .Timefilter }} %s) AS Total
@@ -186,38 +203,65 @@ LIMIT 5
 	gc.Header("X-SQL-Query", query)
 
 	results := []topResult{}
-	err := c.d.ClickHouseDB.Conn.Select(ctx, &results, strings.TrimSpace(query))
+	err := c.cachedSelect(gc, &results, strings.TrimSpace(query), resolution)
 	if err != nil {
 		c.r.Err(err).Msg("unable to query database")
 		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to query database."})
 		return
 	}
+	if format != "" {
+		tw := newTabularWriter(gc, format, fmt.Sprintf("top-%s", gc.Param("name")),
+			[]string{"name", "percent"})
+		for _, result := range results {
+			if err := tw.write(result.Name, result.Percent); err != nil {
+				c.r.Err(err).Msg("unable to export row")
+				break
+			}
+		}
+		if err := tw.close(); err != nil {
+			c.r.Err(err).Msg("unable to export rows")
+		}
+		return
+	}
 	gc.JSON(http.StatusOK, gin.H{"top": results})
 }
 
 type widgetParameters struct {
-	Points uint `form:"points" binding:"isdefault|min=5,max=1000"`
+	Points uint   `form:"points" binding:"isdefault|min=5,max=1000"`
+	Units  string `form:"units" binding:"isdefault|oneof=pps l2bps l3bps flows"`
 }
 
 func (c *Component) widgetGraphHandlerFunc(gc *gin.Context) {
-	ctx := c.t.Context(gc.Request.Context())
-
 	var params widgetParameters
 	if err := gc.ShouldBindQuery(&params); err != nil {
 		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
 		return
 	}
+	if err := c.checkUnits(params.Units, nil); err != nil {
+		gc.JSON(http.StatusBadRequest, gin.H{"message": helpers.Capitalize(err.Error())})
+		return
+	}
 	if params.Points == 0 {
 		params.Points = 200
 	}
 	now := c.d.Clock.Now()
-	query := c.finalizeQuery(fmt.Sprintf(`
+	scope := c.scopeFilter(gc)
+	where := `{{ .Timefilter }}`
+	if scope.Filter != "" {
+		where = fmt.Sprintf("%s AND (%s)", where, templateEscape(scope.Filter))
+	}
+	// Without units, the rate is in Gbps
+	selector := `SUM(Bytes*SamplingRate*8/{{ .Interval }})/1000/1000/1000 AS Gbps`
+	if params.Units != "" {
+		selector = `{{ .Units }}/{{ .Interval }} AS Xps`
+	}
+	query, resolution := c.finalizeQuery(fmt.Sprintf(`
 {{ with %s }}
 SELECT
  {{ call .ToStartOfInterval "TimeReceived" }} AS Time,
- SUM(Bytes*SamplingRate*8/{{ .Interval }})/1000/1000/1000 AS Gbps
+ %s
 FROM {{ .Table }}
-WHERE {{ .Timefilter }}
+WHERE %s
 AND InIfBoundary = 'external'
 GROUP BY Time
 ORDER BY Time WITH FILL
@@ -228,16 +272,31 @@ ORDER BY Time WITH FILL
 		templateContext(inputContext{
 			Start:             now.Add(-24 * time.Hour),
 			End:               now,
-			MainTableRequired: false,
+			MainTableRequired: scope.MainTableRequired,
 			Points:            params.Points,
-		})))
+			Units:             params.Units,
+		}), selector, where))
 	gc.Header("X-SQL-Query", query)
 
+	if params.Units != "" {
+		results := []struct {
+			Time time.Time `json:"t"`
+			Xps  float64   `json:"xps"`
+		}{}
+		if err := c.cachedSelect(gc, &results, strings.TrimSpace(query), resolution); err != nil {
+			c.r.Err(err).Msg("unable to query database")
+			gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to query database."})
+			return
+		}
+		gc.JSON(http.StatusOK, gin.H{"data": results, "units": params.Units})
+		return
+	}
+
 	results := []struct {
 		Time time.Time `json:"t"`
 		Gbps float64   `json:"gbps"`
 	}{}
-	err := c.d.ClickHouseDB.Conn.Select(ctx, &results, strings.TrimSpace(query))
+	err := c.cachedSelect(gc, &results, strings.TrimSpace(query), resolution)
 	if err != nil {
 		c.r.Err(err).Msg("unable to query database")
 		gc.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to query database."})
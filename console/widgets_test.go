@@ -261,3 +261,111 @@ ORDER BY Time WITH FILL
 	})
 
 }
+
+func TestWidgetGraphUnits(t *testing.T) {
+	_, h, mockConn, mockClock := NewMock(t, DefaultConfiguration())
+
+	base := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	mockClock.Set(base.Add(24 * time.Hour))
+	expected := []struct {
+		Time time.Time `json:"t"`
+		Xps  float64   `json:"xps"`
+	}{
+		{base, 25.3},
+		{base.Add(time.Minute), 27.8},
+	}
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), strings.TrimSpace(`
+SELECT
+ toStartOfInterval(TimeReceived + INTERVAL 144 second, INTERVAL 864 second) - INTERVAL 144 second AS Time,
+ SUM((Bytes+18*Packets)*SamplingRate*8)/864 AS Xps
+FROM flows
+WHERE TimeReceived BETWEEN toDateTime('2009-11-10 23:00:00', 'UTC') AND toDateTime('2009-11-11 23:00:00', 'UTC')
+AND InIfBoundary = 'external'
+GROUP BY Time
+ORDER BY Time WITH FILL
+ FROM toDateTime('2009-11-10 23:00:00', 'UTC')
+ TO toDateTime('2009-11-11 23:00:00', 'UTC') + INTERVAL 1 second
+ STEP 864`)).
+		SetArg(1, expected).
+		Return(nil)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			URL: "/api/v0/console/widget/graph?points=100&units=l2bps",
+			JSONOutput: gin.H{
+				"units": "l2bps",
+				"data": []gin.H{
+					{"t": "2009-11-10T23:00:00Z", "xps": 25.3},
+					{"t": "2009-11-10T23:01:00Z", "xps": 27.8}},
+			},
+		}, {
+			Description: "interface utilization",
+			URL:         "/api/v0/console/widget/graph?points=100&units=outl2%25",
+			StatusCode:  400,
+			JSONOutput: gin.H{
+				"message": "Key: 'widgetParameters.Units' Error:Field validation for 'Units' failed on the 'isdefault|oneof=pps l2bps l3bps flows' tag",
+			},
+		},
+	})
+}
+
+func TestWidgetGraphFlowsUnits(t *testing.T) {
+	c, h, mockConn, mockClock := NewMock(t, DefaultConfiguration())
+
+	base := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	mockClock.Set(base.Add(24 * time.Hour))
+	// Consolidated tables would be used for other units
+	c.flowsTablesLock.Lock()
+	c.flowsTables = []flowsTable{
+		{Name: "flows", Resolution: 0, Oldest: base.Add(-7 * 24 * time.Hour)},
+		{Name: "flows_5m0s", Resolution: 5 * time.Minute, Oldest: base.Add(-30 * 24 * time.Hour)},
+	}
+	c.flowsTablesLock.Unlock()
+	expected := []struct {
+		Time time.Time `json:"t"`
+		Xps  float64   `json:"xps"`
+	}{
+		{base, 1200},
+		{base.Add(time.Minute), 1300},
+	}
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), strings.TrimSpace(`
+SELECT
+ toStartOfInterval(TimeReceived + INTERVAL 144 second, INTERVAL 864 second) - INTERVAL 144 second AS Time,
+ SUM(SamplingRate)/864 AS Xps
+FROM flows
+WHERE TimeReceived BETWEEN toDateTime('2009-11-10 23:00:00', 'UTC') AND toDateTime('2009-11-11 23:00:00', 'UTC')
+AND InIfBoundary = 'external'
+GROUP BY Time
+ORDER BY Time WITH FILL
+ FROM toDateTime('2009-11-10 23:00:00', 'UTC')
+ TO toDateTime('2009-11-11 23:00:00', 'UTC') + INTERVAL 1 second
+ STEP 864`)).
+		SetArg(1, expected).
+		Return(nil)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			URL: "/api/v0/console/widget/graph?points=100&units=flows",
+			JSONOutput: gin.H{
+				"units": "flows",
+				"data": []gin.H{
+					{"t": "2009-11-10T23:00:00Z", "xps": 1200},
+					{"t": "2009-11-10T23:01:00Z", "xps": 1300}},
+			},
+		},
+	})
+
+	config := DefaultConfiguration()
+	config.AggregatedFlows = true
+	_, h, _, _ = NewMock(t, config)
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "aggregated flows",
+			URL:         "/api/v0/console/widget/graph?points=100&units=flows",
+			StatusCode:  400,
+			JSONOutput:  gin.H{"message": "Flows cannot be counted as the inlet aggregates them"},
+		},
+	})
+}
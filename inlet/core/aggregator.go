@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"akvorado/inlet/flow"
+)
+
+// aggregator merges flows sharing the same dimensions. It is not
+// safe for concurrent use: each worker owns its own aggregator.
+type aggregator struct {
+	maxFlows int
+	excluded []protoreflect.FieldDescriptor
+	flows    map[string]*flow.Message
+	order    []string
+}
+
+// aggregationMandatoryFields are the fields which cannot be excluded
+// from the aggregation key. Counters and timestamps are merged
+// while the exporter address and the sampling rate are always
+// needed downstream.
+var aggregationMandatoryFields = map[protoreflect.Name]bool{
+	"TimeReceived":    true,
+	"SequenceNum":     true,
+	"SamplingRate":    true,
+	"ExporterAddress": true,
+	"TimeFlowStart":   true,
+	"TimeFlowEnd":     true,
+	"Bytes":           true,
+	"Packets":         true,
+}
+
+// aggregationExcludedFields turns a list of field names into field
+// descriptors. It returns an error if a field does not exist or
+// cannot be excluded.
+func aggregationExcludedFields(names []string) ([]protoreflect.FieldDescriptor, error) {
+	fields := (&flow.Message{}).ProtoReflect().Descriptor().Fields()
+	result := make([]protoreflect.FieldDescriptor, 0, len(names))
+	for _, name := range names {
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			return nil, fmt.Errorf("unknown flow field %q", name)
+		}
+		if aggregationMandatoryFields[fd.Name()] {
+			return nil, fmt.Errorf("flow field %q cannot be excluded from aggregation", name)
+		}
+		result = append(result, fd)
+	}
+	return result, nil
+}
+
+// newAggregator creates a new aggregator able to hold up to maxFlows
+// distinct flows. The excluded fields are removed from flows before
+// aggregating them.
+func newAggregator(maxFlows int, excluded []protoreflect.FieldDescriptor) *aggregator {
+	return &aggregator{
+		maxFlows: maxFlows,
+		excluded: excluded,
+		flows:    make(map[string]*flow.Message),
+		order:    []string{},
+	}
+}
+
+// aggregationKey returns the key used to aggregate the provided flow.
+// All remaining fields are part of the key, except counters, sequence
+// number and timestamps. The sampling rate is part of the key: flows
+// with different sampling rates are never merged.
+func aggregationKey(f *flow.Message) (string, error) {
+	timeReceived, sequenceNum := f.TimeReceived, f.SequenceNum
+	timeFlowStart, timeFlowEnd := f.TimeFlowStart, f.TimeFlowEnd
+	bytes, packets := f.Bytes, f.Packets
+	f.TimeReceived, f.SequenceNum = 0, 0
+	f.TimeFlowStart, f.TimeFlowEnd = 0, 0
+	f.Bytes, f.Packets = 0, 0
+	key, err := proto.Marshal(f)
+	f.TimeReceived, f.SequenceNum = timeReceived, sequenceNum
+	f.TimeFlowStart, f.TimeFlowEnd = timeFlowStart, timeFlowEnd
+	f.Bytes, f.Packets = bytes, packets
+	return string(key), err
+}
+
+// add adds a flow to the aggregator. Excluded fields are cleared from
+// the provided flow. It returns true if the provided flow was merged
+// into an existing one and an error if the flow cannot be aggregated.
+func (a *aggregator) add(f *flow.Message) (bool, error) {
+	if len(a.excluded) > 0 {
+		m := f.ProtoReflect()
+		for _, fd := range a.excluded {
+			m.Clear(fd)
+		}
+	}
+	key, err := aggregationKey(f)
+	if err != nil {
+		return false, err
+	}
+	existing, ok := a.flows[key]
+	if !ok {
+		a.flows[key] = f
+		a.order = append(a.order, key)
+		return false, nil
+	}
+	existing.Bytes += f.Bytes
+	existing.Packets += f.Packets
+	if f.TimeFlowStart < existing.TimeFlowStart {
+		existing.TimeFlowStart = f.TimeFlowStart
+	}
+	if f.TimeFlowEnd > existing.TimeFlowEnd {
+		existing.TimeFlowEnd = f.TimeFlowEnd
+	}
+	return true, nil
+}
+
+// full tells if the aggregator should be flushed.
+func (a *aggregator) full() bool {
+	return len(a.flows) >= a.maxFlows
+}
+
+// flush returns the aggregated flows, in the order they were first
+// seen, and resets the aggregator.
+func (a *aggregator) flush() []*flow.Message {
+	flows := make([]*flow.Message, 0, len(a.order))
+	for _, key := range a.order {
+		flows = append(flows, a.flows[key])
+	}
+	a.flows = make(map[string]*flow.Message, len(flows))
+	a.order = a.order[:0]
+	return flows
+}
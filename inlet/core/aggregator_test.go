@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"net"
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/inlet/flow"
+)
+
+func TestAggregator(t *testing.T) {
+	flowMessage := func(srcPort uint32, samplingRate, bytes, packets uint64, start, end uint64) *flow.Message {
+		return &flow.Message{
+			TimeReceived:    end,
+			SequenceNum:     uint32(start),
+			SamplingRate:    samplingRate,
+			ExporterAddress: net.ParseIP("192.0.2.142"),
+			TimeFlowStart:   start,
+			TimeFlowEnd:     end,
+			Bytes:           bytes,
+			Packets:         packets,
+			InIf:            10,
+			OutIf:           20,
+			SrcAddr:         net.ParseIP("67.43.156.77"),
+			DstAddr:         net.ParseIP("2.125.160.216"),
+			Etype:           0x800,
+			Proto:           6,
+			SrcPort:         srcPort,
+			DstPort:         443,
+		}
+	}
+
+	agg := newAggregator(4, nil)
+	for _, f := range []struct {
+		Flow   *flow.Message
+		Merged bool
+	}{
+		{flowMessage(8534, 1000, 1000, 1, 100, 110), false},
+		{flowMessage(8534, 1000, 1500, 2, 105, 120), true},
+		{flowMessage(8535, 1000, 200, 1, 100, 110), false},
+		{flowMessage(8535, 100, 300, 3, 90, 100), false},
+	} {
+		merged, err := agg.add(f.Flow)
+		if err != nil {
+			t.Fatalf("add() error:\n%+v", err)
+		}
+		if merged != f.Merged {
+			t.Errorf("add() == %v but expected %v", merged, f.Merged)
+		}
+	}
+	if agg.full() {
+		t.Error("full() == true but expected false")
+	}
+
+	expected := []*flow.Message{
+		flowMessage(8534, 1000, 2500, 3, 100, 120),
+		flowMessage(8535, 1000, 200, 1, 100, 110),
+		flowMessage(8535, 100, 300, 3, 90, 100),
+	}
+	expected[0].TimeReceived = 110
+	got := agg.flush()
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("flush() (-got, +want):\n%s", diff)
+	}
+	if got := agg.flush(); len(got) != 0 {
+		t.Fatalf("flush() after flush() returned %d flows", len(got))
+	}
+
+	// Fill the aggregator
+	for i := uint32(0); i < 4; i++ {
+		if agg.full() {
+			t.Fatalf("full() == true after %d flows but expected false", i)
+		}
+		if _, err := agg.add(flowMessage(1000+i, 1000, 1000, 1, 100, 110)); err != nil {
+			t.Fatalf("add() error:\n%+v", err)
+		}
+	}
+	if !agg.full() {
+		t.Error("full() == false but expected true")
+	}
+}
+
+func TestAggregatorExcludedFields(t *testing.T) {
+	excluded, err := aggregationExcludedFields([]string{
+		"SrcAddr", "DstAddr", "SrcNetMask", "DstNetMask",
+		"SrcPort", "DstPort", "TCPFlags",
+	})
+	if err != nil {
+		t.Fatalf("aggregationExcludedFields() error:\n%+v", err)
+	}
+	flowMessage := func(srcAddr string, srcPort uint32, bytes, packets uint64) *flow.Message {
+		return &flow.Message{
+			TimeReceived:    110,
+			SamplingRate:    1000,
+			ExporterAddress: net.ParseIP("192.0.2.142"),
+			Bytes:           bytes,
+			Packets:         packets,
+			InIf:            10,
+			OutIf:           20,
+			SrcAddr:         net.ParseIP(srcAddr),
+			DstAddr:         net.ParseIP("2.125.160.216"),
+			Etype:           0x800,
+			Proto:           6,
+			SrcAS:           64476,
+			SrcPort:         srcPort,
+			DstPort:         443,
+			TCPFlags:        2,
+		}
+	}
+
+	agg := newAggregator(10, excluded)
+	for _, f := range []*flow.Message{
+		flowMessage("67.43.156.77", 8534, 1000, 1),
+		flowMessage("67.43.156.78", 8535, 1500, 2),
+		flowMessage("67.43.156.79", 8536, 200, 1),
+	} {
+		if _, err := agg.add(f); err != nil {
+			t.Fatalf("add() error:\n%+v", err)
+		}
+	}
+	expected := []*flow.Message{
+		{
+			TimeReceived:    110,
+			SamplingRate:    1000,
+			ExporterAddress: net.ParseIP("192.0.2.142"),
+			Bytes:           2700,
+			Packets:         4,
+			InIf:            10,
+			OutIf:           20,
+			Etype:           0x800,
+			Proto:           6,
+			SrcAS:           64476,
+		},
+	}
+	if diff := helpers.Diff(agg.flush(), expected); diff != "" {
+		t.Fatalf("flush() (-got, +want):\n%s", diff)
+	}
+
+	for _, names := range [][]string{
+		{"SrcAddr", "Nothing"},
+		{"Bytes"},
+		{"SamplingRate"},
+	} {
+		if _, err := aggregationExcludedFields(names); err == nil {
+			t.Errorf("aggregationExcludedFields(%v) did not error", names)
+		}
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/antonmedv/expr"
 	"github.com/antonmedv/expr/ast"
 	"github.com/antonmedv/expr/vm"
+
+	"akvorado/inlet/snmp"
 )
 
 // Global cache for regular expressions. No boundary.
@@ -27,8 +29,11 @@ type ExporterClassifierRule struct {
 
 // exporterInfo contains the information we want to expose about a exporter.
 type exporterInfo struct {
-	IP   string
-	Name string
+	IP          string
+	Name        string
+	Description string
+	ObjectID    string
+	Location    string
 }
 
 // exporterClassification contains the information about an exporter classification
@@ -125,6 +130,31 @@ type interfaceInfo struct {
 	Name        string
 	Description string
 	Speed       uint32
+	Type        uint32
+	AdminStatus string
+	VRF         string
+}
+
+// newInterfaceInfo builds the information about an interface from
+// the SNMP one.
+func newInterfaceInfo(iface snmp.Interface) interfaceInfo {
+	var adminStatus string
+	switch iface.AdminStatus {
+	case 1:
+		adminStatus = "up"
+	case 2:
+		adminStatus = "down"
+	case 3:
+		adminStatus = "testing"
+	}
+	return interfaceInfo{
+		Name:        iface.Name,
+		Description: iface.Description,
+		Speed:       uint32(iface.Speed),
+		Type:        uint32(iface.Type),
+		AdminStatus: adminStatus,
+		VRF:         iface.VRF,
+	}
 }
 
 // interfaceBoundary tells if an interface is internal or external
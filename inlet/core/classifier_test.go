@@ -47,38 +47,58 @@ func TestExporterClassifier(t *testing.T) {
 		}, {
 			Description:            "access to exporter name",
 			Program:                `Exporter.Name startsWith "expo" && Classify("europe")`,
-			ExporterInfo:           exporterInfo{"127.0.0.1", "exporter"},
+			ExporterInfo:           exporterInfo{IP: "127.0.0.1", Name: "exporter"},
 			ExpectedClassification: exporterClassification{Group: "europe"},
 		}, {
 			Description:            "matches",
 			Program:                `Exporter.Name matches "^e.p.r" && Classify("europe")`,
-			ExporterInfo:           exporterInfo{"127.0.0.1", "exporter"},
+			ExporterInfo:           exporterInfo{IP: "127.0.0.1", Name: "exporter"},
 			ExpectedClassification: exporterClassification{Group: "europe"},
 		}, {
 			Description: "multiline",
 			Program: `Exporter.Name matches "^e.p.r" &&
 Classify("europe")`,
-			ExporterInfo:           exporterInfo{"127.0.0.1", "exporter"},
+			ExporterInfo:           exporterInfo{IP: "127.0.0.1", Name: "exporter"},
 			ExpectedClassification: exporterClassification{Group: "europe"},
 		}, {
 			Description:            "regex",
 			Program:                `ClassifyRegex(Exporter.Name, "^(e.p+).r", "europe-$1")`,
-			ExporterInfo:           exporterInfo{"127.0.0.1", "exporter"},
+			ExporterInfo:           exporterInfo{IP: "127.0.0.1", Name: "exporter"},
 			ExpectedClassification: exporterClassification{Group: "europe-exp"},
 		}, {
 			Description:            "regex with class",
 			Program:                `ClassifyRegex(Exporter.Name, "^(\\w+).r", "europe-$1")`,
-			ExporterInfo:           exporterInfo{"127.0.0.1", "exporter"},
+			ExporterInfo:           exporterInfo{IP: "127.0.0.1", Name: "exporter"},
 			ExpectedClassification: exporterClassification{Group: "europe-export"},
 		}, {
 			Description:            "non-matching regex",
 			Program:                `ClassifyRegex(Exporter.Name, "^(ebp+).r", "europe-$1")`,
-			ExporterInfo:           exporterInfo{"127.0.0.1", "exporter"},
+			ExporterInfo:           exporterInfo{IP: "127.0.0.1", Name: "exporter"},
 			ExpectedClassification: exporterClassification{Group: ""},
+		}, {
+			Description: "access to exporter location",
+			Program:     `ClassifySiteRegex(Exporter.Location, "^([a-z]+)-", "$1")`,
+			ExporterInfo: exporterInfo{
+				IP:       "127.0.0.1",
+				Name:     "exporter",
+				Location: "paris-th2, rack 12",
+			},
+			ExpectedClassification: exporterClassification{Site: "paris"},
+		}, {
+			Description: "access to exporter object ID and description",
+			Program: `Exporter.ObjectID startsWith "1.3.6.1.4.1.2636." &&
+Exporter.Description contains "MX" && ClassifyRole("edge")`,
+			ExporterInfo: exporterInfo{
+				IP:          "127.0.0.1",
+				Name:        "exporter",
+				Description: "Juniper Networks, Inc. MX204 internet router",
+				ObjectID:    "1.3.6.1.4.1.2636.1.1.1.2.151",
+			},
+			ExpectedClassification: exporterClassification{Role: "edge"},
 		}, {
 			Description:  "faulty regex",
 			Program:      `ClassifyRegex(Exporter.Name, "^(ebp+.r", "europe-$1")`,
-			ExporterInfo: exporterInfo{"127.0.0.1", "exporter"},
+			ExporterInfo: exporterInfo{IP: "127.0.0.1", Name: "exporter"},
 			ExpectedErr:  true,
 		}, {
 			Description: "syntax error",
@@ -169,6 +189,26 @@ ClassifyProviderRegex(Interface.Description, "^Transit: ([^ ]+)", "$1")
 				Provider:     "telia",
 				Boundary:     externalBoundary,
 			},
+		}, {
+			Description: "classify using type, admin status and VRF",
+			Program: `
+Interface.Type == 161 && Interface.AdminStatus == "up" &&
+Interface.VRF != "" && ClassifyConnectivity("customer") &&
+ClassifyProvider(Interface.VRF) && ClassifyInternal()
+`,
+			InterfaceInfo: interfaceInfo{
+				Name:        "ae0",
+				Description: "Customer",
+				Speed:       10000,
+				Type:        161,
+				AdminStatus: "up",
+				VRF:         "CUST-A",
+			},
+			ExpectedClassification: interfaceClassification{
+				Connectivity: "customer",
+				Provider:     "cust-a",
+				Boundary:     internalBoundary,
+			},
 		},
 	}
 	for _, tc := range cases {
@@ -31,6 +31,23 @@ type Configuration struct {
 	OverrideSamplingRate helpers.SubnetMap[uint]
 	// ASNProviders defines the source used to get AS numbers
 	ASNProviders []ASNProvider `validate:"dive"`
+	// AggregationWindow defines the duration during which flows
+	// sharing the same dimensions are merged before being sent to
+	// Kafka (0 to disable aggregation)
+	AggregationWindow time.Duration `validate:"isdefault|min=100ms"`
+	// AggregationMaxFlows defines the maximum number of distinct flows
+	// kept by each worker before flushing them
+	AggregationMaxFlows int `validate:"min=1"`
+	// AggregationExcludedFields defines the flow fields removed
+	// before aggregating flows. They are not part of the
+	// aggregation key.
+	AggregationExcludedFields []string
+	// SpreadInterval defines the interval used to split long-lived
+	// flows using their start and end timestamps (0 to disable)
+	SpreadInterval time.Duration `validate:"isdefault|min=1s"`
+	// SpreadMaxDuration defines the maximum duration of a flow when
+	// spreading it (0 for no limit)
+	SpreadMaxDuration time.Duration `validate:"isdefault|min=1s"`
 
 	// Old configuration settings
 	classifierCacheSize uint
@@ -44,6 +61,8 @@ func DefaultConfiguration() Configuration {
 		InterfaceClassifiers:    []InterfaceClassifierRule{},
 		ClassifierCacheDuration: 5 * time.Minute,
 		ASNProviders:            []ASNProvider{ProviderFlow, ProviderBMP, ProviderGeoIP},
+		AggregationMaxFlows:     100000,
+		SpreadMaxDuration:       time.Hour,
 	}
 }
 
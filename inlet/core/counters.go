@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"encoding/json"
+	"time"
+
+	"akvorado/common/reporter"
+	"akvorado/inlet/snmp"
+)
+
+// runCountersWorker starts a worker to forward interface counters to Kafka.
+func (c *Component) runCountersWorker() error {
+	c.r.Debug().Msg("starting counters worker")
+
+	errLogger := c.r.Sample(reporter.BurstSampler(time.Minute, 10))
+	for {
+		select {
+		case <-c.t.Dying():
+			c.r.Debug().Msg("stopping counters worker")
+			return nil
+		case counters := <-c.d.Flow.Counters():
+			if counters == nil {
+				c.r.Info().Msg("no more counters available, stopping")
+				return nil
+			}
+
+			exporter := counters.ExporterAddress.Unmap().String()
+			c.metrics.countersReceived.WithLabelValues(exporter).Inc()
+
+			// Enrichment. When not in cache, counters are sent anyway.
+			exporterInfo, iface, err := c.d.SNMP.Lookup(counters.ExporterAddress, uint(counters.IfIndex))
+			if err == nil {
+				counters.ExporterName = exporterInfo.Name
+				counters.IfName = iface.Name
+				counters.IfDescription = iface.Description
+			} else if err != snmp.ErrCacheMiss {
+				errLogger.Err(err).Str("exporter", exporter).Msg("unable to query SNMP cache")
+			}
+
+			payload, err := json.Marshal(counters)
+			if err != nil {
+				errLogger.Err(err).Str("exporter", exporter).Msg("unable to serialize counters")
+				c.metrics.countersErrors.WithLabelValues(exporter, err.Error()).Inc()
+				continue
+			}
+			c.metrics.countersForwarded.WithLabelValues(exporter).Inc()
+			c.d.Kafka.SendCounters(exporter, payload)
+		}
+	}
+}
@@ -25,8 +25,12 @@ type exporterAndInterfaceInfo struct {
 func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *flow.Message) (skip bool) {
 	errLogger := c.r.Sample(reporter.BurstSampler(time.Minute, 10))
 
+	var (
+		exporter    snmp.Exporter
+		inIf, outIf snmp.Interface
+	)
 	if flow.InIf != 0 {
-		exporterName, iface, err := c.d.SNMP.Lookup(exporterIP, uint(flow.InIf))
+		snmpExporter, iface, err := c.d.SNMP.Lookup(exporterIP, uint(flow.InIf))
 		if err != nil {
 			if err != snmp.ErrCacheMiss {
 				errLogger.Err(err).Str("exporter", exporterStr).Msg("unable to query SNMP cache")
@@ -34,7 +38,8 @@ func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *
 			c.metrics.flowsErrors.WithLabelValues(exporterStr, err.Error()).Inc()
 			skip = true
 		} else {
-			flow.ExporterName = exporterName
+			exporter, inIf = snmpExporter, iface
+			flow.ExporterName = snmpExporter.Name
 			flow.InIfName = iface.Name
 			flow.InIfDescription = iface.Description
 			flow.InIfSpeed = uint32(iface.Speed)
@@ -42,7 +47,7 @@ func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *
 	}
 
 	if flow.OutIf != 0 {
-		exporterName, iface, err := c.d.SNMP.Lookup(exporterIP, uint(flow.OutIf))
+		snmpExporter, iface, err := c.d.SNMP.Lookup(exporterIP, uint(flow.OutIf))
 		if err != nil {
 			// Only register a cache miss if we don't have one.
 			// TODO: maybe we could do one SNMP query for both interfaces.
@@ -54,7 +59,8 @@ func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *
 				skip = true
 			}
 		} else {
-			flow.ExporterName = exporterName
+			exporter, outIf = snmpExporter, iface
+			flow.ExporterName = snmpExporter.Name
 			flow.OutIfName = iface.Name
 			flow.OutIfDescription = iface.Description
 			flow.OutIfSpeed = uint32(iface.Speed)
@@ -84,12 +90,17 @@ func (c *Component) enrichFlow(exporterIP netip.Addr, exporterStr string, flow *
 	}
 
 	// Classification
-	c.classifyExporter(exporterStr, flow)
-	c.classifyInterface(exporterStr, flow,
-		flow.OutIfName, flow.OutIfDescription, flow.OutIfSpeed,
+	si := exporterInfo{
+		IP:          exporterStr,
+		Name:        exporter.Name,
+		Description: exporter.Description,
+		ObjectID:    exporter.ObjectID,
+		Location:    exporter.Location,
+	}
+	c.classifyExporter(si, flow)
+	c.classifyInterface(si, flow, newInterfaceInfo(outIf),
 		&flow.OutIfConnectivity, &flow.OutIfProvider, &flow.OutIfBoundary)
-	c.classifyInterface(exporterStr, flow,
-		flow.InIfName, flow.InIfDescription, flow.InIfSpeed,
+	c.classifyInterface(si, flow, newInterfaceInfo(inIf),
 		&flow.InIfConnectivity, &flow.InIfProvider, &flow.InIfBoundary)
 
 	sourceBMP := c.d.BMP.Lookup(net.IP(flow.SrcAddr), nil)
@@ -145,12 +156,10 @@ func (c *Component) getASNumber(flowAddr net.IP, flowAS, bmpAS uint32) (asn uint
 	return asn
 }
 
-func (c *Component) classifyExporter(ip string, flow *flow.Message) {
+func (c *Component) classifyExporter(si exporterInfo, flow *flow.Message) {
 	if len(c.config.ExporterClassifiers) == 0 {
 		return
 	}
-	name := flow.ExporterName
-	si := exporterInfo{IP: ip, Name: name}
 	if classification, ok := c.classifierExporterCache.Get(si); ok {
 		flow.ExporterGroup = classification.Group
 		flow.ExporterRole = classification.Role
@@ -166,7 +175,7 @@ func (c *Component) classifyExporter(ip string, flow *flow.Message) {
 			c.classifierErrLogger.Err(err).
 				Str("type", "exporter").
 				Int("index", idx).
-				Str("exporter", name).
+				Str("exporter", si.Name).
 				Msg("error executing classifier")
 			c.metrics.classifierErrors.WithLabelValues("exporter", strconv.Itoa(idx)).Inc()
 			c.classifierExporterCache.Set(si, classification)
@@ -185,14 +194,11 @@ func (c *Component) classifyExporter(ip string, flow *flow.Message) {
 	flow.ExporterTenant = classification.Tenant
 }
 
-func (c *Component) classifyInterface(ip string, fl *flow.Message,
-	ifName, ifDescription string, ifSpeed uint32,
+func (c *Component) classifyInterface(si exporterInfo, fl *flow.Message, ii interfaceInfo,
 	connectivity, provider *string, boundary *decoder.FlowMessage_Boundary) {
 	if len(c.config.InterfaceClassifiers) == 0 {
 		return
 	}
-	si := exporterInfo{IP: ip, Name: fl.ExporterName}
-	ii := interfaceInfo{Name: ifName, Description: ifDescription, Speed: ifSpeed}
 	key := exporterAndInterfaceInfo{
 		Exporter:  si,
 		Interface: ii,
@@ -212,7 +218,7 @@ func (c *Component) classifyInterface(ip string, fl *flow.Message,
 				Str("type", "interface").
 				Int("index", idx).
 				Str("exporter", fl.ExporterName).
-				Str("interface", ifName).
+				Str("interface", ii.Name).
 				Msg("error executing classifier")
 			c.metrics.classifierErrors.WithLabelValues("interface", strconv.Itoa(idx)).Inc()
 			c.classifierInterfaceCache.Set(key, classification)
@@ -13,9 +13,15 @@ type metrics struct {
 	flowsReceived       *reporter.CounterVec
 	flowsForwarded      *reporter.CounterVec
 	flowsErrors         *reporter.CounterVec
+	flowsAggregated     *reporter.CounterVec
+	flowsSpread         *reporter.CounterVec
 	flowsHTTPClients    reporter.GaugeFunc
 	flowsProcessingTime reporter.Summary
 
+	countersReceived  *reporter.CounterVec
+	countersForwarded *reporter.CounterVec
+	countersErrors    *reporter.CounterVec
+
 	classifierExporterCacheSize  reporter.CounterFunc
 	classifierInterfaceCacheSize reporter.CounterFunc
 	classifierErrors             *reporter.CounterVec
@@ -43,6 +49,20 @@ func (c *Component) initMetrics() {
 		},
 		[]string{"exporter", "error"},
 	)
+	c.metrics.flowsAggregated = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "flows_aggregated",
+			Help: "Number of flows merged into another flow.",
+		},
+		[]string{"exporter"},
+	)
+	c.metrics.flowsSpread = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "flows_spread",
+			Help: "Number of additional flows created by spreading long-lived flows.",
+		},
+		[]string{"exporter"},
+	)
 	c.metrics.flowsHTTPClients = c.r.GaugeFunc(
 		reporter.GaugeOpts{
 			Name: "flows_http_clients",
@@ -60,6 +80,28 @@ func (c *Component) initMetrics() {
 		},
 	)
 
+	c.metrics.countersReceived = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "counters_received",
+			Help: "Number of incoming interface counters.",
+		},
+		[]string{"exporter"},
+	)
+	c.metrics.countersForwarded = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "counters_forwarded",
+			Help: "Number of interface counters forwarded to Kafka.",
+		},
+		[]string{"exporter"},
+	)
+	c.metrics.countersErrors = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "counters_errors",
+			Help: "Number of interface counters with errors.",
+		},
+		[]string{"exporter", "error"},
+	)
+
 	c.metrics.classifierExporterCacheSize = c.r.CounterFunc(
 		reporter.CounterOpts{
 			Name: "classifier_exporter_cache_size_items",
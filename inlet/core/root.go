@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"gopkg.in/tomb.v2"
 	"zgo.at/zcache/v2"
 
@@ -42,6 +43,8 @@ type Component struct {
 	classifierExporterCache  *zcache.Cache[exporterInfo, exporterClassification]
 	classifierInterfaceCache *zcache.Cache[exporterAndInterfaceInfo, interfaceClassification]
 	classifierErrLogger      reporter.Logger
+
+	aggregationExcludedFields []protoreflect.FieldDescriptor
 }
 
 // Dependencies define the dependencies of the HTTP component.
@@ -71,6 +74,11 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		classifierInterfaceCache: zcache.New[exporterAndInterfaceInfo, interfaceClassification](configuration.ClassifierCacheDuration, 2*configuration.ClassifierCacheDuration),
 		classifierErrLogger:      r.Sample(reporter.BurstSampler(10*time.Second, 3)),
 	}
+	excluded, err := aggregationExcludedFields(configuration.AggregationExcludedFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aggregation configuration: %w", err)
+	}
+	c.aggregationExcludedFields = excluded
 	c.d.Daemon.Track(&c.t, "inlet/core")
 	c.initMetrics()
 	return &c, nil
@@ -85,6 +93,9 @@ func (c *Component) Start() error {
 			return c.runWorker(workerID)
 		})
 	}
+	if c.d.Flow.Counters() != nil {
+		c.t.Go(c.runCountersWorker)
+	}
 
 	c.r.RegisterHealthcheck("core", c.channelHealthcheck())
 	c.d.HTTP.GinRouter.GET("/api/v0/inlet/flows", c.FlowsHTTPHandler)
@@ -96,18 +107,40 @@ func (c *Component) runWorker(workerID int) error {
 	c.r.Debug().Int("worker", workerID).Msg("starting core worker")
 
 	errLogger := c.r.Sample(reporter.BurstSampler(time.Minute, 10))
+	var agg *aggregator
+	var aggregationTicker <-chan time.Time
+	if c.config.AggregationWindow > 0 {
+		agg = newAggregator(c.config.AggregationMaxFlows, c.aggregationExcludedFields)
+		ticker := time.NewTicker(c.config.AggregationWindow)
+		defer ticker.Stop()
+		aggregationTicker = ticker.C
+	}
+	flushAggregator := func() {
+		for _, flow := range agg.flush() {
+			c.forwardFlow(net.IP(flow.ExporterAddress).String(), flow, errLogger)
+		}
+	}
+
 	for {
 		select {
 		case <-c.t.Dying():
 			c.r.Debug().Int("worker", workerID).Msg("stopping core worker")
+			if agg != nil {
+				flushAggregator()
+			}
 			return nil
 		case cb, ok := <-c.healthy:
 			if ok {
 				cb(reporter.HealthcheckOK, fmt.Sprintf("worker %d ok", workerID))
 			}
+		case <-aggregationTicker:
+			flushAggregator()
 		case flow := <-c.d.Flow.Flows():
 			if flow == nil {
 				c.r.Info().Int("worker", workerID).Msg("no more flow available, stopping")
+				if agg != nil {
+					flushAggregator()
+				}
 				return nil
 			}
 
@@ -121,28 +154,60 @@ func (c *Component) runWorker(workerID int) error {
 				continue
 			}
 
-			// Serialize flow (use length-prefixed protobuf)
-			buf := proto.NewBuffer([]byte{})
-			err := buf.EncodeMessage(flow)
+			// Without aggregation, forward the flow right away
+			if agg == nil {
+				c.metrics.flowsProcessingTime.Observe(time.Now().Sub(start).Seconds())
+				c.forwardFlow(exporter, flow, errLogger)
+				continue
+			}
+
+			// Aggregation
+			merged, err := agg.add(flow)
 			if err != nil {
-				errLogger.Err(err).Str("exporter", exporter).Msg("unable to serialize flow")
+				errLogger.Err(err).Str("exporter", exporter).Msg("unable to aggregate flow")
 				c.metrics.flowsErrors.WithLabelValues(exporter, err.Error()).Inc()
 				continue
 			}
+			if merged {
+				c.metrics.flowsAggregated.WithLabelValues(exporter).Inc()
+			}
 			c.metrics.flowsProcessingTime.Observe(time.Now().Sub(start).Seconds())
+			if agg.full() {
+				flushAggregator()
+			}
+		}
+	}
+}
 
-			// Forward to Kafka (this could block)
-			c.metrics.flowsForwarded.WithLabelValues(exporter).Inc()
-			c.d.Kafka.Send(exporter, buf.Bytes())
+// forwardFlow serializes a flow and sends it to Kafka and to the HTTP
+// clients. When enabled, long-lived flows are spread first.
+func (c *Component) forwardFlow(exporter string, flow *flow.Message, errLogger reporter.Logger) {
+	flows := spreadFlow(flow,
+		uint64(c.config.SpreadInterval.Seconds()),
+		uint64(c.config.SpreadMaxDuration.Seconds()))
+	if len(flows) > 1 {
+		c.metrics.flowsSpread.WithLabelValues(exporter).Add(float64(len(flows) - 1))
+	}
+	for _, flow := range flows {
+		// Serialize flow (use length-prefixed protobuf)
+		buf := proto.NewBuffer([]byte{})
+		err := buf.EncodeMessage(flow)
+		if err != nil {
+			errLogger.Err(err).Str("exporter", exporter).Msg("unable to serialize flow")
+			c.metrics.flowsErrors.WithLabelValues(exporter, err.Error()).Inc()
+			continue
+		}
 
-			// If we have HTTP clients, send to them too
-			if atomic.LoadUint32(&c.httpFlowClients) > 0 {
-				select {
-				case c.httpFlowChannel <- flow: // OK
-				default: // Overflow, best effort and ignore
-				}
-			}
+		// Forward to Kafka (this could block)
+		c.metrics.flowsForwarded.WithLabelValues(exporter).Inc()
+		c.d.Kafka.Send(exporter, buf.Bytes(), flow)
 
+		// If we have HTTP clients, send to them too
+		if atomic.LoadUint32(&c.httpFlowClients) > 0 {
+			select {
+			case c.httpFlowChannel <- flow: // OK
+			default: // Overflow, best effort and ignore
+			}
 		}
 	}
 }
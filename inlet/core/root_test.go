@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"net"
 	netHTTP "net/http"
+	"net/netip"
 	"testing"
 	"time"
 
@@ -37,7 +38,9 @@ func TestCore(t *testing.T) {
 	// Prepare all components.
 	daemonComponent := daemon.NewMock(t)
 	snmpComponent := snmp.NewMock(t, r, snmp.DefaultConfiguration(), snmp.Dependencies{Daemon: daemonComponent})
-	flowComponent := flow.NewMock(t, r, flow.DefaultConfiguration())
+	flowConfiguration := flow.DefaultConfiguration()
+	flowConfiguration.InterfaceCounters = true
+	flowComponent := flow.NewMock(t, r, flowConfiguration)
 	geoipComponent := geoip.NewMock(t, r)
 	kafkaComponent, kafkaProducer := kafka.NewMock(t, r, kafka.DefaultConfiguration())
 	httpComponent := http.NewMock(t, r)
@@ -195,6 +198,64 @@ func TestCore(t *testing.T) {
 		}
 	})
 
+	// Test interface counters forwarding
+	t.Run("counters", func(t *testing.T) {
+		received := make(chan bool)
+		kafkaProducer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			defer close(received)
+			if msg.Topic != "flows-counters" {
+				t.Errorf("Kafka message topic (-got, +want):\n-%s\n+%s", msg.Topic, "flows-counters")
+			}
+			b, err := msg.Value.Encode()
+			if err != nil {
+				t.Fatalf("Kafka message encoding error:\n%+v", err)
+			}
+			var got decoder.InterfaceCounters
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("Kafka message decode error:\n%+v", err)
+			}
+			expected := decoder.InterfaceCounters{
+				TimeReceived:    200,
+				ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+				ExporterName:    "192_0_2_142",
+				IfIndex:         434,
+				IfName:          "Gi0/0/434",
+				IfDescription:   "Interface 434",
+				IfSpeed:         1000,
+				IfInOctets:      1000000,
+				IfOutOctets:     2000000,
+				IfOutDiscards:   4,
+			}
+			if diff := helpers.Diff(got, expected); diff != "" {
+				t.Errorf("Kafka message (-got, +want):\n%s", diff)
+			}
+			return nil
+		})
+		flowComponent.InjectCounters(t, &decoder.InterfaceCounters{
+			TimeReceived:    200,
+			ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+			IfIndex:         434,
+			IfSpeed:         1000,
+			IfInOctets:      1000000,
+			IfOutOctets:     2000000,
+			IfOutDiscards:   4,
+		})
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatal("Kafka message not received")
+		}
+
+		gotMetrics := r.GetMetrics("akvorado_inlet_core_", "counters_")
+		expectedMetrics := map[string]string{
+			`counters_received{exporter="192.0.2.142"}`:  "1",
+			`counters_forwarded{exporter="192.0.2.142"}`: "1",
+		}
+		if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+			t.Fatalf("Metrics (-got, +want):\n%s", diff)
+		}
+	})
+
 	// Test the healthcheck function
 	t.Run("healthcheck", func(t *testing.T) {
 		got := r.RunHealthchecks(context.Background())
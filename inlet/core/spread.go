@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"math/bits"
+
+	"github.com/golang/protobuf/proto"
+
+	"akvorado/inlet/flow"
+)
+
+// spreadFlow splits a flow spanning several intervals into one flow
+// per interval, using the flow start and end timestamps. Counters are
+// apportioned according to the time spent in each interval. The
+// timestamp of each resulting flow is the end of its segment, except
+// for the last one which keeps the original timestamp. The flow is
+// returned unmodified if it does not need to be spread or if its
+// timestamps are unusable. The duration of a flow is capped to
+// maxDuration (when not 0). Pieces never have bytes without packets.
+func spreadFlow(f *flow.Message, interval, maxDuration uint64) []*flow.Message {
+	if interval == 0 {
+		return []*flow.Message{f}
+	}
+	start, end := f.TimeFlowStart, f.TimeFlowEnd
+	if end > f.TimeReceived {
+		end = f.TimeReceived
+	}
+	if start == 0 || start >= end {
+		return []*flow.Message{f}
+	}
+	if maxDuration > 0 && end-start > maxDuration {
+		start = end - maxDuration
+	}
+	if start/interval == end/interval {
+		return []*flow.Message{f}
+	}
+
+	duration := end - start
+	firstSlot, lastSlot := start/interval, end/interval
+	results := make([]*flow.Message, 0, lastSlot-firstSlot+1)
+	var bytes, packets uint64
+	for slot := firstSlot; slot <= lastSlot; slot++ {
+		segmentStart, segmentEnd := slot*interval, (slot+1)*interval
+		if segmentStart < start {
+			segmentStart = start
+		}
+		if segmentEnd > end {
+			segmentEnd = end
+		}
+		if segmentEnd <= segmentStart {
+			// Flow ending on an interval boundary
+			continue
+		}
+		var pieceBytes, piecePackets, pieceTimeReceived uint64
+		if segmentEnd == end {
+			// Last segment gets the remainder to keep totals exact
+			pieceBytes = f.Bytes - bytes
+			piecePackets = f.Packets - packets
+			pieceTimeReceived = f.TimeReceived
+		} else {
+			pieceBytes = apportion(f.Bytes, segmentEnd-segmentStart, duration)
+			piecePackets = apportion(f.Packets, segmentEnd-segmentStart, duration)
+			pieceTimeReceived = segmentEnd - 1
+			if piecePackets == 0 {
+				// Do not emit pieces without packets, their
+				// bytes are carried to the last segment
+				continue
+			}
+		}
+		piece := proto.Clone(f).(*flow.Message)
+		piece.TimeFlowStart = segmentStart
+		piece.TimeFlowEnd = segmentEnd
+		piece.TimeReceived = pieceTimeReceived
+		piece.Bytes = pieceBytes
+		piece.Packets = piecePackets
+		bytes += piece.Bytes
+		packets += piece.Packets
+		results = append(results, piece)
+	}
+	return results
+}
+
+// apportion returns value×part/total without overflowing. part should
+// not be greater than total.
+func apportion(value, part, total uint64) uint64 {
+	hi, lo := bits.Mul64(value, part)
+	quo, _ := bits.Div64(hi, lo, total)
+	return quo
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"math"
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/inlet/flow"
+)
+
+func TestSpreadFlow(t *testing.T) {
+	flowMessage := func(received, start, end, bytes, packets uint64) *flow.Message {
+		return &flow.Message{
+			TimeReceived:  received,
+			TimeFlowStart: start,
+			TimeFlowEnd:   end,
+			Bytes:         bytes,
+			Packets:       packets,
+			SrcPort:       443,
+		}
+	}
+	cases := []struct {
+		Description string
+		Interval    uint64
+		MaxDuration uint64
+		Flow        *flow.Message
+		Expected    []*flow.Message
+	}{
+		{
+			Description: "disabled",
+			Interval:    0,
+			Flow:        flowMessage(1000, 700, 1000, 3000, 30),
+			Expected:    []*flow.Message{flowMessage(1000, 700, 1000, 3000, 30)},
+		}, {
+			Description: "single interval",
+			Interval:    60,
+			Flow:        flowMessage(1010, 965, 1000, 3000, 30),
+			Expected:    []*flow.Message{flowMessage(1010, 965, 1000, 3000, 30)},
+		}, {
+			Description: "missing timestamps",
+			Interval:    60,
+			Flow:        flowMessage(1000, 0, 0, 3000, 30),
+			Expected:    []*flow.Message{flowMessage(1000, 0, 0, 3000, 30)},
+		}, {
+			Description: "reversed timestamps",
+			Interval:    60,
+			Flow:        flowMessage(1000, 900, 700, 3000, 30),
+			Expected:    []*flow.Message{flowMessage(1000, 900, 700, 3000, 30)},
+		}, {
+			Description: "aligned flow",
+			Interval:    60,
+			Flow:        flowMessage(1201, 1020, 1200, 3000, 30),
+			Expected: []*flow.Message{
+				flowMessage(1079, 1020, 1080, 1000, 10),
+				flowMessage(1139, 1080, 1140, 1000, 10),
+				flowMessage(1201, 1140, 1200, 1000, 10),
+			},
+		}, {
+			Description: "unaligned flow",
+			Interval:    60,
+			Flow:        flowMessage(1210, 1050, 1200, 1000, 3),
+			Expected: []*flow.Message{
+				flowMessage(1139, 1080, 1140, 400, 1),
+				flowMessage(1210, 1140, 1200, 600, 2),
+			},
+		}, {
+			Description: "more bytes than packets",
+			Interval:    60,
+			Flow:        flowMessage(1200, 1020, 1200, 1500, 1),
+			Expected: []*flow.Message{
+				flowMessage(1200, 1140, 1200, 1500, 1),
+			},
+		}, {
+			Description: "end after reception",
+			Interval:    60,
+			Flow:        flowMessage(1110, 1050, 1130, 600, 6),
+			Expected: []*flow.Message{
+				flowMessage(1079, 1050, 1080, 300, 3),
+				flowMessage(1110, 1080, 1110, 300, 3),
+			},
+		}, {
+			Description: "empty segment",
+			Interval:    60,
+			Flow:        flowMessage(1150, 1000, 1150, 1, 1),
+			Expected: []*flow.Message{
+				flowMessage(1150, 1140, 1150, 1, 1),
+			},
+		}, {
+			Description: "capped duration",
+			Interval:    60,
+			MaxDuration: 120,
+			Flow:        flowMessage(1200, 600, 1200, 1000, 10),
+			Expected: []*flow.Message{
+				flowMessage(1139, 1080, 1140, 500, 5),
+				flowMessage(1200, 1140, 1200, 500, 5),
+			},
+		}, {
+			Description: "large counters",
+			Interval:    60,
+			Flow:        flowMessage(1200, 1080, 1200, math.MaxUint64, 10),
+			Expected: []*flow.Message{
+				flowMessage(1139, 1080, 1140, math.MaxUint64/2, 5),
+				flowMessage(1200, 1140, 1200, math.MaxUint64/2+1, 5),
+			},
+		},
+	}
+	for _, tc := range cases {
+		got := spreadFlow(tc.Flow, tc.Interval, tc.MaxDuration)
+		if diff := helpers.Diff(got, tc.Expected); diff != "" {
+			t.Errorf("spreadFlow(%s) (-got, +want):\n%s", tc.Description, diff)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mitchellh/mapstructure"
@@ -17,6 +18,8 @@ import (
 	"akvorado/common/helpers"
 	"akvorado/inlet/flow/input"
 	"akvorado/inlet/flow/input/file"
+	"akvorado/inlet/flow/input/kafka"
+	"akvorado/inlet/flow/input/tcp"
 	"akvorado/inlet/flow/input/udp"
 )
 
@@ -27,6 +30,22 @@ type Configuration struct {
 	// RateLimit defines a rate limit on the number of flows per
 	// second. The limit is per-exporter.
 	RateLimit rate.Limit `validate:"isdefault|min=100"`
+	// InterfaceCounters enables the export of interface counters
+	// received through sFlow counter samples.
+	InterfaceCounters bool
+	// InterfaceCountersQueueSize defines the number of interface
+	// counters waiting to be exported. When full, new counters are
+	// dropped.
+	InterfaceCountersQueueSize int `validate:"min=1"`
+	// StatePersistFile defines a file to store decoder state
+	// (templates, sampling rates) and survive restarts
+	StatePersistFile string
+	// StatePersistInterval defines how often the decoder state is
+	// saved. It is also saved on shutdown.
+	StatePersistInterval time.Duration `validate:"min=1s"`
+	// StateMaxAge defines how old a persisted state can be to be
+	// loaded on startup. 0 means no limit.
+	StateMaxAge time.Duration `validate:"min=0"`
 }
 
 // DefaultConfiguration represents the default configuration for the flow component
@@ -39,6 +58,11 @@ func DefaultConfiguration() Configuration {
 			Decoder: "sflow",
 			Config:  udp.DefaultConfiguration(),
 		}},
+		InterfaceCounters:          false,
+		InterfaceCountersQueueSize: 1000,
+		StatePersistFile:           "",
+		StatePersistInterval:       time.Minute,
+		StateMaxAge:                time.Hour,
 	}
 }
 
@@ -149,8 +173,12 @@ func (ic InputConfiguration) MarshalYAML() (interface{}, error) {
 		"decoder": ic.Decoder,
 	}
 	configStruct := reflect.ValueOf(ic.Config).Elem()
-	for i, field := range reflect.VisibleFields(configStruct.Type()) {
-		result[strings.ToLower(field.Name)] = configStruct.Field(i).Interface()
+	for _, field := range reflect.VisibleFields(configStruct.Type()) {
+		if field.Anonymous {
+			// Promoted fields are also visible
+			continue
+		}
+		result[strings.ToLower(field.Name)] = configStruct.FieldByIndex(field.Index).Interface()
 	}
 	return result, nil
 }
@@ -165,8 +193,10 @@ func (ic InputConfiguration) MarshalJSON() ([]byte, error) {
 }
 
 var inputs = map[string](func() input.Configuration){
-	"udp":  udp.DefaultConfiguration,
-	"file": file.DefaultConfiguration,
+	"udp":   udp.DefaultConfiguration,
+	"file":  file.DefaultConfiguration,
+	"kafka": kafka.DefaultConfiguration,
+	"tcp":   tcp.DefaultConfiguration,
 }
 
 func init() {
@@ -11,7 +11,9 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"akvorado/common/helpers"
+	"akvorado/inlet/flow/input"
 	"akvorado/inlet/flow/input/file"
+	"akvorado/inlet/flow/input/kafka"
 	"akvorado/inlet/flow/input/udp"
 )
 
@@ -206,6 +208,13 @@ func TestMarshalYAML(t *testing.T) {
 					QueueSize: 1000,
 					Workers:   3,
 				},
+			}, {
+				Decoder: "netflow",
+				Config: func() input.Configuration {
+					config := kafka.DefaultConfiguration().(*kafka.Configuration)
+					config.Topic = "raw-flows"
+					return config
+				}(),
 			},
 		},
 	}
@@ -226,7 +235,31 @@ func TestMarshalYAML(t *testing.T) {
   receivebuffer: 0
   type: udp
   workers: 3
+- brokers:
+  - 127.0.0.1:9092
+  consumergroup: akvorado-inlet
+  decoder: netflow
+  exporterencoding: string
+  exporterheader: exporter
+  queuesize: 100000
+  tls:
+    enable: false
+    verify: true
+    cafile: ""
+    certfile: ""
+    keyfile: ""
+    saslusername: ""
+    saslpassword: ""
+    saslmechanism: none
+  topic: raw-flows
+  type: kafka
+  version: 2.8.1
 ratelimit: 0
+interfacecounters: false
+interfacecountersqueuesize: 0
+statepersistfile: ""
+statepersistinterval: 0s
+statemaxage: 0s
 `
 	if diff := helpers.Diff(strings.Split(string(got), "\n"), strings.Split(expected, "\n")); diff != "" {
 		t.Fatalf("Marshal() (-got, +want):\n%s", diff)
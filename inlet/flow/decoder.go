@@ -4,6 +4,7 @@
 package flow
 
 import (
+	"net"
 	"time"
 
 	"akvorado/inlet/flow/decoder"
@@ -42,6 +43,13 @@ func (wd *wrappedDecoder) Name() string {
 	return wd.orig.Name()
 }
 
+// CloseSession forwards the end of a session to the original decoder.
+func (wd *wrappedDecoder) CloseSession(source net.IP, session string) {
+	if sd, ok := wd.orig.(decoder.SessionDecoder); ok {
+		sd.CloseSession(source, session)
+	}
+}
+
 // wrapDecoder wraps the provided decoders to get statistics from it.
 func (c *Component) wrapDecoder(d decoder.Decoder) decoder.Decoder {
 	return &wrappedDecoder{
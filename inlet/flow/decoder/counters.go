@@ -0,0 +1,33 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package decoder
+
+import "net/netip"
+
+// InterfaceCounters contains the generic counters for an interface,
+// as exported by some protocols (like sFlow counter samples). The
+// counters are cumulative.
+type InterfaceCounters struct {
+	TimeReceived    uint64
+	ExporterAddress netip.Addr
+	ExporterName    string
+	IfIndex         uint32
+	IfName          string
+	IfDescription   string
+	IfSpeed         uint64
+	IfInOctets      uint64
+	IfInErrors      uint32
+	IfInDiscards    uint32
+	IfOutOctets     uint64
+	IfOutErrors     uint32
+	IfOutDiscards   uint32
+}
+
+// CountersDecoder is the interface a decoder should implement when
+// it is able to extract interface counters.
+type CountersDecoder interface {
+	// SetCountersHandler sets the function to call with the
+	// interface counters decoded from a payload.
+	SetCountersHandler(func([]*InterfaceCounters))
+}
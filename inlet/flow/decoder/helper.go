@@ -45,15 +45,35 @@ func ConvertGoflowToFlowMessage(input *goflowmessage.FlowMessage) *FlowMessage {
 		SrcNetMask:       input.SrcNet,
 		DstNetMask:       input.DstNet,
 		NextHopAS:        input.NextHopAS,
+		SrcVlan:          vlanCheck(input.SrcVlan),
+		DstVlan:          vlanCheck(input.DstVlan),
+		SrcMAC:           input.SrcMac,
+		DstMAC:           input.DstMac,
+		MPLS1StLabel:     input.MPLS1Label,
 	}
 	if !net.IP(input.BgpNextHop).IsUnspecified() {
 		result.NextHop = ipCopy(input.BgpNextHop)
 	} else {
 		result.NextHop = ipCopy(input.NextHop)
 	}
+	if result.SrcVlan == 0 {
+		// 802.1Q tag found in the sampled header. It was seen on
+		// the input interface, so it cannot be used for DstVlan
+		// which is only set when explicitly provided.
+		result.SrcVlan = vlanCheck(input.VlanId)
+	}
 	return &result
 }
 
+// vlanCheck returns the provided VLAN ID if it is valid or 0
+// otherwise. sFlow uses 0xffffffff when the VLAN is unknown.
+func vlanCheck(vlan uint32) uint32 {
+	if vlan > 4095 {
+		return 0
+	}
+	return vlan
+}
+
 // Ensure we copy the IP address. This is similar to To16(), except
 // that when we get an IPv6, we return a copy.
 func ipCopy(src net.IP) net.IP {
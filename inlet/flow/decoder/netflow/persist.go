@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package netflow
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sync/atomic"
+	"time"
+
+	"github.com/netsampler/goflow2/decoders/netflow"
+
+	"akvorado/inlet/flow/decoder"
+)
+
+// exporterState is the persisted state for a given exporter.
+type exporterState struct {
+	LastUpdated   int64
+	Templates     map[templateKey]interface{}
+	SamplingRates map[samplingRateKey]uint32
+}
+
+// SaveState returns the templates and the sampling rates for each
+// exporter. Templates and sampling rates scoped to a session are not
+// saved as they are only valid during the session.
+func (nd *Decoder) SaveState() ([]byte, error) {
+	state := map[string]*exporterState{}
+	getState := func(key string) *exporterState {
+		es, ok := state[key]
+		if !ok {
+			es = &exporterState{
+				Templates:     map[templateKey]interface{}{},
+				SamplingRates: map[samplingRateKey]uint32{},
+			}
+			state[key] = es
+		}
+		return es
+	}
+
+	nd.templatesLock.RLock()
+	for key, templates := range nd.templates {
+		if key.Session != "" {
+			continue
+		}
+		es := getState(key.Exporter)
+		templates.savedLock.Lock()
+		for k, v := range templates.saved {
+			es.Templates[k] = v
+		}
+		templates.savedLock.Unlock()
+		if lastUpdated := atomic.LoadInt64(&templates.lastUpdated); lastUpdated > es.LastUpdated {
+			es.LastUpdated = lastUpdated
+		}
+	}
+	nd.templatesLock.RUnlock()
+
+	nd.samplingLock.RLock()
+	for key, sampling := range nd.sampling {
+		if key.Session != "" {
+			continue
+		}
+		es := getState(key.Exporter)
+		sampling.lock.RLock()
+		for k, v := range sampling.rates {
+			es.SamplingRates[k] = v
+		}
+		sampling.lock.RUnlock()
+		if lastUpdated := atomic.LoadInt64(&sampling.lastUpdated); lastUpdated > es.LastUpdated {
+			es.LastUpdated = lastUpdated
+		}
+	}
+	nd.samplingLock.RUnlock()
+
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	if err := encoder.Encode(decoder.StateVersion); err != nil {
+		return nil, err
+	}
+	if err := encoder.Encode(state); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// LoadState restores templates and sampling rates. Exporters not
+// updated for more than the provided duration are ignored. When the
+// duration is 0, all exporters are restored.
+func (nd *Decoder) LoadState(data []byte, maxAge time.Duration) error {
+	buf := bytes.NewBuffer(data)
+	gobDecoder := gob.NewDecoder(buf)
+	var version int
+	if err := gobDecoder.Decode(&version); err != nil {
+		return err
+	}
+	if version != decoder.StateVersion {
+		return decoder.ErrStateVersion
+	}
+	state := map[string]*exporterState{}
+	if err := gobDecoder.Decode(&state); err != nil {
+		return err
+	}
+
+	threshold := time.Now().Add(-maxAge).Unix()
+	nd.templatesLock.Lock()
+	defer nd.templatesLock.Unlock()
+	nd.samplingLock.Lock()
+	defer nd.samplingLock.Unlock()
+	for key, es := range state {
+		if maxAge > 0 && es.LastUpdated < threshold {
+			continue
+		}
+		templates := newTemplateSystem(nd, key)
+		templates.lastUpdated = es.LastUpdated
+		for k, template := range es.Templates {
+			// Do not use templates.AddTemplate() to not alter metrics.
+			templates.templates.AddTemplate(k.Version, k.ObsDomainID, template)
+			templates.saved[k] = template
+		}
+		nd.templates[exporterKey{Exporter: key}] = templates
+
+		sampling := newSamplingRateSystem()
+		sampling.lastUpdated = es.LastUpdated
+		for k, rate := range es.SamplingRates {
+			sampling.rates[k] = rate
+		}
+		nd.sampling[exporterKey{Exporter: key}] = sampling
+	}
+	return nil
+}
+
+func init() {
+	gob.Register(netflow.TemplateRecord{})
+	gob.Register(netflow.IPFIXOptionsTemplateRecord{})
+	gob.Register(netflow.NFv9OptionsTemplateRecord{})
+}
@@ -1,30 +1,38 @@
 // SPDX-FileCopyrightText: 2022 Free Mobile
 // SPDX-License-Identifier: AGPL-3.0-only
 
-// Package netflow handles NetFlow v9 and IPFIX decoding.
+// Package netflow handles NetFlow v5, NetFlow v9 and IPFIX decoding.
 package netflow
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/netsampler/goflow2/decoders/netflow"
+	"github.com/netsampler/goflow2/decoders/netflowlegacy"
 	"github.com/netsampler/goflow2/producer"
 
 	"akvorado/common/reporter"
 	"akvorado/inlet/flow/decoder"
 )
 
-// Decoder contains the state for the Netflow v9 decoder.
+var errSamplingRateNotFound = errors.New("sampling rate not found")
+
+// Decoder contains the state for the Netflow v5/v9 and IPFIX decoder.
 type Decoder struct {
 	r *reporter.Reporter
 
 	// Templates and sampling
 	templatesLock sync.RWMutex
-	templates     map[string]*templateSystem
+	templates     map[exporterKey]*templateSystem
 	samplingLock  sync.RWMutex
-	sampling      map[string]producer.SamplingRateSystem
+	sampling      map[exporterKey]*samplingRateSystem
 
 	metrics struct {
 		errors             *reporter.CounterVec
@@ -40,8 +48,8 @@ type Decoder struct {
 func New(r *reporter.Reporter) decoder.Decoder {
 	nd := &Decoder{
 		r:         r,
-		templates: map[string]*templateSystem{},
-		sampling:  map[string]producer.SamplingRateSystem{},
+		templates: map[exporterKey]*templateSystem{},
+		sampling:  map[exporterKey]*samplingRateSystem{},
 	}
 
 	nd.metrics.errors = nd.r.CounterVec(
@@ -91,10 +99,38 @@ func New(r *reporter.Reporter) decoder.Decoder {
 	return nd
 }
 
+// exporterKey identifies the scope of templates and sampling rates:
+// an exporter and, for stream-oriented transports, a session.
+type exporterKey struct {
+	Exporter string
+	Session  string
+}
+
 type templateSystem struct {
 	nd        *Decoder
 	key       string
 	templates *netflow.BasicTemplateSystem
+
+	// Copy of the received templates, to be able to persist them
+	savedLock   sync.Mutex
+	saved       map[templateKey]interface{}
+	lastUpdated int64
+}
+
+// templateKey identifies a template received from an exporter.
+type templateKey struct {
+	Version     uint16
+	ObsDomainID uint32
+	TemplateID  uint16
+}
+
+func newTemplateSystem(nd *Decoder, key string) *templateSystem {
+	return &templateSystem{
+		nd:        nd,
+		key:       key,
+		templates: netflow.CreateTemplateSystem(),
+		saved:     map[templateKey]interface{}{},
+	}
 }
 
 func (s *templateSystem) AddTemplate(version uint16, obsDomainID uint32, template interface{}) {
@@ -116,6 +152,11 @@ func (s *templateSystem) AddTemplate(version uint16, obsDomainID uint32, templat
 		typeStr = "template"
 	}
 
+	s.savedLock.Lock()
+	s.saved[templateKey{version, obsDomainID, templateID}] = template
+	s.savedLock.Unlock()
+	atomic.StoreInt64(&s.lastUpdated, time.Now().Unix())
+
 	s.nd.metrics.templatesStats.WithLabelValues(
 		s.key,
 		strconv.Itoa(int(version)),
@@ -129,29 +170,66 @@ func (s *templateSystem) GetTemplate(version uint16, obsDomainID uint32, templat
 	return s.templates.GetTemplate(version, obsDomainID, templateID)
 }
 
+// samplingRateSystem stores sampling rates received through options
+// data. It replaces the one from goflow2 to be able to persist them.
+type samplingRateSystem struct {
+	lock        sync.RWMutex
+	rates       map[samplingRateKey]uint32
+	lastUpdated int64
+}
+
+// samplingRateKey identifies a sampling rate received from an exporter.
+type samplingRateKey struct {
+	Version     uint16
+	ObsDomainID uint32
+}
+
+func newSamplingRateSystem() *samplingRateSystem {
+	return &samplingRateSystem{
+		rates: map[samplingRateKey]uint32{},
+	}
+}
+
+func (s *samplingRateSystem) GetSamplingRate(version uint16, obsDomainID uint32) (uint32, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	samplingRate, ok := s.rates[samplingRateKey{version, obsDomainID}]
+	if !ok {
+		return 0, errSamplingRateNotFound
+	}
+	return samplingRate, nil
+}
+
+func (s *samplingRateSystem) AddSamplingRate(version uint16, obsDomainID uint32, samplingRate uint32) {
+	s.lock.Lock()
+	s.rates[samplingRateKey{version, obsDomainID}] = samplingRate
+	s.lock.Unlock()
+	atomic.StoreInt64(&s.lastUpdated, time.Now().Unix())
+}
+
 // Decode decodes a Netflow payload.
 func (nd *Decoder) Decode(in decoder.RawFlow) []*decoder.FlowMessage {
 	key := in.Source.String()
+	if len(in.Payload) >= 2 && binary.BigEndian.Uint16(in.Payload) == 5 {
+		return nd.decodeLegacy(in, key)
+	}
+	scope := exporterKey{key, in.Session}
 	nd.templatesLock.RLock()
-	templates, ok := nd.templates[key]
+	templates, ok := nd.templates[scope]
 	nd.templatesLock.RUnlock()
 	if !ok {
-		templates = &templateSystem{
-			nd:        nd,
-			templates: netflow.CreateTemplateSystem(),
-			key:       key,
-		}
+		templates = newTemplateSystem(nd, key)
 		nd.templatesLock.Lock()
-		nd.templates[key] = templates
+		nd.templates[scope] = templates
 		nd.templatesLock.Unlock()
 	}
 	nd.samplingLock.RLock()
-	sampling, ok := nd.sampling[key]
+	sampling, ok := nd.sampling[scope]
 	nd.samplingLock.RUnlock()
 	if !ok {
-		sampling = producer.CreateSamplingSystem()
+		sampling = newSamplingRateSystem()
 		nd.samplingLock.Lock()
-		nd.sampling[key] = sampling
+		nd.sampling[scope] = sampling
 		nd.samplingLock.Unlock()
 	}
 
@@ -235,6 +313,58 @@ func (nd *Decoder) Decode(in decoder.RawFlow) []*decoder.FlowMessage {
 	return results
 }
 
+// decodeLegacy decodes a Netflow v5 payload. There is no template
+// and the sampling rate is provided in the header.
+func (nd *Decoder) decodeLegacy(in decoder.RawFlow, key string) []*decoder.FlowMessage {
+	ts := uint64(in.TimeReceived.UTC().Unix())
+	buf := bytes.NewBuffer(in.Payload)
+	msgDec, err := netflowlegacy.DecodeMessage(buf)
+	if err != nil {
+		nd.metrics.errors.WithLabelValues(key, "error decoding").Inc()
+		return nil
+	}
+	packet, ok := msgDec.(netflowlegacy.PacketNetFlowV5)
+	if !ok {
+		nd.metrics.stats.WithLabelValues(key, "unknown").
+			Inc()
+		return nil
+	}
+	nd.metrics.stats.WithLabelValues(key, "5").Inc()
+
+	flowMessageSet, _ := producer.ProcessMessageNetFlowLegacy(msgDec)
+	// The two upper bits of the sampling interval are the sampling
+	// mode. An interval of 0 means the flows are not sampled.
+	samplingRate := uint64(packet.SamplingInterval & 0x3fff)
+	if samplingRate == 0 {
+		samplingRate = 1
+	}
+	results := make([]*decoder.FlowMessage, len(flowMessageSet))
+	for idx, fmsg := range flowMessageSet {
+		fmsg.TimeReceived = ts
+		fmsg.SamplerAddress = in.Source
+		fmsg.SamplingRate = samplingRate
+		timeDiff := fmsg.TimeReceived - fmsg.TimeFlowEnd
+		nd.metrics.timeStatsSum.WithLabelValues(key, "5").
+			Observe(float64(timeDiff))
+		results[idx] = decoder.ConvertGoflowToFlowMessage(fmsg)
+		results[idx].NextHop = net.IP(fmsg.NextHop).To16()
+	}
+
+	return results
+}
+
+// CloseSession discards templates and sampling rates received during
+// the provided session.
+func (nd *Decoder) CloseSession(source net.IP, session string) {
+	scope := exporterKey{source.String(), session}
+	nd.templatesLock.Lock()
+	delete(nd.templates, scope)
+	nd.templatesLock.Unlock()
+	nd.samplingLock.Lock()
+	delete(nd.sampling, scope)
+	nd.samplingLock.Unlock()
+}
+
 // Name returns the name of the decoder.
 func (nd *Decoder) Name() string {
 	return "netflow"
@@ -4,9 +4,13 @@
 package netflow
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
 	"net"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"akvorado/common/helpers"
 	"akvorado/common/reporter"
@@ -213,3 +217,314 @@ func TestDecode(t *testing.T) {
 		t.Fatalf("Metrics after data (-got, +want):\n%s", diff)
 	}
 }
+
+func TestDecodeNFv5(t *testing.T) {
+	r := reporter.NewMock(t)
+	nfdecoder := New(r)
+
+	data := helpers.ReadPcapPayload(t, filepath.Join("testdata", "data-nfv5.pcap"))
+	got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: net.ParseIP("127.0.0.1")})
+	if got == nil {
+		t.Fatalf("Decode() error on data")
+	}
+	expectedFlows := []*decoder.FlowMessage{
+		{
+			SequenceNum:     1234,
+			ExporterAddress: net.ParseIP("127.0.0.1").To16(),
+			SamplingRate:    1000,
+			TimeFlowStart:   1669999990,
+			TimeFlowEnd:     1669999999,
+			Bytes:           2500,
+			Packets:         5,
+			SrcAddr:         net.ParseIP("192.0.2.10").To16(),
+			DstAddr:         net.ParseIP("198.51.100.20").To16(),
+			SrcNetMask:      24,
+			DstNetMask:      22,
+			SrcAS:           64500,
+			DstAS:           64501,
+			Etype:           0x800,
+			Proto:           6,
+			SrcPort:         443,
+			DstPort:         51234,
+			InIf:            10,
+			OutIf:           20,
+			TCPFlags:        24,
+			NextHop:         net.ParseIP("203.0.113.1").To16(),
+		}, {
+			SequenceNum:     1234,
+			ExporterAddress: net.ParseIP("127.0.0.1").To16(),
+			SamplingRate:    1000,
+			TimeFlowStart:   1669999995,
+			TimeFlowEnd:     1669999995,
+			Bytes:           60,
+			Packets:         1,
+			SrcAddr:         net.ParseIP("198.51.100.21").To16(),
+			DstAddr:         net.ParseIP("192.0.2.11").To16(),
+			SrcNetMask:      22,
+			DstNetMask:      24,
+			SrcAS:           64501,
+			DstAS:           64500,
+			Etype:           0x800,
+			Proto:           17,
+			SrcPort:         53,
+			DstPort:         33000,
+			InIf:            20,
+			OutIf:           10,
+			NextHop:         net.ParseIP("203.0.113.2").To16(),
+		},
+	}
+	for _, f := range got {
+		f.TimeReceived = 0
+	}
+	if diff := helpers.Diff(got, expectedFlows); diff != "" {
+		t.Fatalf("Decode() (-got, +want):\n%s", diff)
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_flow_decoder_netflow_", "count")
+	expectedMetrics := map[string]string{
+		`count{exporter="127.0.0.1",version="5"}`: "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics after data (-got, +want):\n%s", diff)
+	}
+}
+
+func TestDecodeNFv5Unsampled(t *testing.T) {
+	r := reporter.NewMock(t)
+	nfdecoder := New(r)
+
+	// Clear the sampling interval from the header
+	data := helpers.ReadPcapPayload(t, filepath.Join("testdata", "data-nfv5.pcap"))
+	data[22], data[23] = 0, 0
+	got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: net.ParseIP("127.0.0.1")})
+	if len(got) != 2 {
+		t.Fatalf("Decode() got %d flows, expected 2", len(got))
+	}
+	for _, f := range got {
+		if f.SamplingRate != 1 {
+			t.Fatalf("Decode() got sampling rate %d, expected 1", f.SamplingRate)
+		}
+	}
+}
+
+func TestDecodeL2Fields(t *testing.T) {
+	type field struct {
+		Type  uint16
+		Value []byte
+	}
+	// build returns a NetFlow v9 or IPFIX packet with a template
+	// and a data record using the provided fields.
+	build := func(version uint16, fields []field) []byte {
+		templateSet, dataSet := []byte{}, []byte{}
+		templateSet = binary.BigEndian.AppendUint16(templateSet, 256)
+		templateSet = binary.BigEndian.AppendUint16(templateSet, uint16(len(fields)))
+		for _, f := range fields {
+			templateSet = binary.BigEndian.AppendUint16(templateSet, f.Type)
+			templateSet = binary.BigEndian.AppendUint16(templateSet, uint16(len(f.Value)))
+			dataSet = append(dataSet, f.Value...)
+		}
+		for len(dataSet)%4 != 0 {
+			dataSet = append(dataSet, 0)
+		}
+		templateSetID := uint16(0)
+		if version == 10 {
+			templateSetID = 2
+		}
+		sets := []byte{}
+		sets = binary.BigEndian.AppendUint16(sets, templateSetID)
+		sets = binary.BigEndian.AppendUint16(sets, uint16(4+len(templateSet)))
+		sets = append(sets, templateSet...)
+		sets = binary.BigEndian.AppendUint16(sets, 256)
+		sets = binary.BigEndian.AppendUint16(sets, uint16(4+len(dataSet)))
+		sets = append(sets, dataSet...)
+
+		packet := []byte{}
+		packet = binary.BigEndian.AppendUint16(packet, version)
+		if version == 9 {
+			packet = binary.BigEndian.AppendUint16(packet, 2)          // count
+			packet = binary.BigEndian.AppendUint32(packet, 1000)       // uptime
+			packet = binary.BigEndian.AppendUint32(packet, 1669999999) // unix seconds
+		} else {
+			packet = binary.BigEndian.AppendUint16(packet, uint16(16+len(sets)))
+			packet = binary.BigEndian.AppendUint32(packet, 1669999999) // export time
+		}
+		packet = binary.BigEndian.AppendUint32(packet, 1) // sequence number
+		packet = binary.BigEndian.AppendUint32(packet, 0) // source ID
+		return append(packet, sets...)
+	}
+	commonFields := []field{
+		{1, []byte{0, 0, 0x05, 0xdc}},                    // IN_BYTES
+		{2, []byte{0, 0, 0, 1}},                          // IN_PKTS
+		{8, []byte{192, 0, 2, 1}},                        // IPV4_SRC_ADDR
+		{12, []byte{198, 51, 100, 1}},                    // IPV4_DST_ADDR
+		{56, []byte{0x24, 0x6e, 0x96, 0x90, 0x7a, 0x50}}, // IN_SRC_MAC
+		{80, []byte{0x24, 0x6e, 0x96, 0x04, 0x3c, 0x08}}, // IN_DST_MAC
+		{58, []byte{0, 100}},                             // SRC_VLAN
+		{70, []byte{0x03, 0xe8, 0x21}},                   // MPLS_LABEL_1 (label 16002)
+	}
+
+	type l2Fields struct {
+		SrcVlan      uint32
+		DstVlan      uint32
+		SrcMAC       uint64
+		DstMAC       uint64
+		MPLS1stLabel uint32
+	}
+	cases := []struct {
+		Description string
+		Version     uint16
+		Fields      []field
+		Expected    l2Fields
+	}{
+		{
+			Description: "NetFlow v9",
+			Version:     9,
+			Fields:      append(append([]field{}, commonFields...), field{59, []byte{0, 200}}), // DST_VLAN
+			Expected: l2Fields{
+				SrcVlan:      100,
+				DstVlan:      200,
+				SrcMAC:       0x246e96907a50,
+				DstMAC:       0x246e96043c08,
+				MPLS1stLabel: 16002,
+			},
+		}, {
+			Description: "IPFIX without destination VLAN",
+			Version:     10,
+			Fields:      commonFields,
+			Expected: l2Fields{
+				SrcVlan:      100,
+				DstVlan:      0, // no fallback
+				SrcMAC:       0x246e96907a50,
+				DstMAC:       0x246e96043c08,
+				MPLS1stLabel: 16002,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			nfdecoder := New(reporter.NewMock(t))
+			got := nfdecoder.Decode(decoder.RawFlow{
+				Payload: build(tc.Version, tc.Fields),
+				Source:  net.ParseIP("127.0.0.1"),
+			})
+			if len(got) != 1 {
+				t.Fatalf("Decode() got %d flows, expected 1", len(got))
+			}
+			gotL2 := l2Fields{
+				SrcVlan:      got[0].SrcVlan,
+				DstVlan:      got[0].DstVlan,
+				SrcMAC:       got[0].SrcMAC,
+				DstMAC:       got[0].DstMAC,
+				MPLS1stLabel: got[0].MPLS1StLabel,
+			}
+			if diff := helpers.Diff(gotL2, tc.Expected); diff != "" {
+				t.Fatalf("Decode() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSaveLoadState(t *testing.T) {
+	r := reporter.NewMock(t)
+	nfdecoder := New(r).(*Decoder)
+	for _, f := range []string{
+		"options-template-257.pcap",
+		"options-data-257.pcap",
+		"template-260.pcap",
+	} {
+		payload := helpers.ReadPcapPayload(t, filepath.Join("testdata", f))
+		if got := nfdecoder.Decode(decoder.RawFlow{Payload: payload, Source: net.ParseIP("127.0.0.1")}); got == nil {
+			t.Fatalf("Decode(%q) error", f)
+		}
+	}
+	state, err := nfdecoder.SaveState()
+	if err != nil {
+		t.Fatalf("SaveState() error:\n%+v", err)
+	}
+	data := helpers.ReadPcapPayload(t, filepath.Join("testdata", "data-260.pcap"))
+
+	t.Run("fresh state", func(t *testing.T) {
+		r := reporter.NewMock(t)
+		nfdecoder := New(r).(*Decoder)
+		if err := nfdecoder.LoadState(state, time.Hour); err != nil {
+			t.Fatalf("LoadState() error:\n%+v", err)
+		}
+		got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: net.ParseIP("127.0.0.1")})
+		if len(got) != 4 {
+			t.Fatalf("Decode() got %d flows, expected 4", len(got))
+		}
+		for _, f := range got {
+			if f.SamplingRate != 30000 {
+				t.Fatalf("Decode() got sampling rate %d, expected 30000", f.SamplingRate)
+			}
+		}
+	})
+
+	t.Run("stale state", func(t *testing.T) {
+		// Make the state older
+		decoded := map[string]*exporterState{}
+		dec := gob.NewDecoder(bytes.NewBuffer(state))
+		var version int
+		if err := dec.Decode(&version); err != nil {
+			t.Fatalf("Decode() error:\n%+v", err)
+		}
+		if err := dec.Decode(&decoded); err != nil {
+			t.Fatalf("Decode() error:\n%+v", err)
+		}
+		for _, es := range decoded {
+			es.LastUpdated -= 7200
+		}
+		var buf bytes.Buffer
+		enc := gob.NewEncoder(&buf)
+		enc.Encode(&version)
+		enc.Encode(decoded)
+
+		r := reporter.NewMock(t)
+		nfdecoder := New(r).(*Decoder)
+		if err := nfdecoder.LoadState(buf.Bytes(), time.Hour); err != nil {
+			t.Fatalf("LoadState() error:\n%+v", err)
+		}
+		if got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: net.ParseIP("127.0.0.1")}); got != nil {
+			t.Fatalf("Decode() should have failed with stale templates, got %d flows", len(got))
+		}
+	})
+}
+
+func TestSessionScope(t *testing.T) {
+	r := reporter.NewMock(t)
+	nfdecoder := New(r)
+	source := net.ParseIP("127.0.0.1")
+	template := helpers.ReadPcapPayload(t, filepath.Join("testdata", "template-260.pcap"))
+	data := helpers.ReadPcapPayload(t, filepath.Join("testdata", "data-260.pcap"))
+
+	if got := nfdecoder.Decode(decoder.RawFlow{Payload: template, Source: source, Session: "1"}); got == nil {
+		t.Fatalf("Decode() error on template")
+	}
+	if got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: source, Session: "2"}); got != nil {
+		t.Fatalf("Decode() in another session should have failed")
+	}
+	if got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: source}); got != nil {
+		t.Fatalf("Decode() without session should have failed")
+	}
+	if got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: source, Session: "1"}); len(got) == 0 {
+		t.Fatalf("Decode() in the same session should have succeeded")
+	}
+
+	// Session-scoped templates are not persisted
+	state, err := nfdecoder.(decoder.PersistentDecoder).SaveState()
+	if err != nil {
+		t.Fatalf("SaveState() error:\n%+v", err)
+	}
+	other := New(reporter.NewMock(t))
+	if err := other.(decoder.PersistentDecoder).LoadState(state, 0); err != nil {
+		t.Fatalf("LoadState() error:\n%+v", err)
+	}
+	if got := other.Decode(decoder.RawFlow{Payload: data, Source: source, Session: "1"}); got != nil {
+		t.Fatalf("Decode() after LoadState() should have failed")
+	}
+
+	nfdecoder.(decoder.SessionDecoder).CloseSession(source, "1")
+	if got := nfdecoder.Decode(decoder.RawFlow{Payload: data, Source: source, Session: "1"}); got != nil {
+		t.Fatalf("Decode() after CloseSession() should have failed")
+	}
+}
@@ -6,6 +6,7 @@
 package decoder
 
 import (
+	"errors"
 	"net"
 	"time"
 
@@ -23,11 +24,46 @@ type Decoder interface {
 	Name() string
 }
 
+// ErrStateVersion is triggered when loading a state from an
+// incompatible version.
+var ErrStateVersion = errors.New("decoder state version mismatch")
+
+// StateVersion is the current version of the persisted state. It
+// covers both the state file and the state of each decoder and
+// should be increased when any of them changes.
+const StateVersion = 1
+
+// PersistentDecoder is the interface a decoder should implement when
+// its state should survive restarts.
+type PersistentDecoder interface {
+	// SaveState returns an opaque representation of the state of
+	// the decoder.
+	SaveState() ([]byte, error)
+	// LoadState restores a state previously returned by
+	// SaveState. Entries older than the provided duration are
+	// ignored.
+	LoadState(state []byte, maxAge time.Duration) error
+}
+
+// SessionDecoder is the interface a decoder should implement when
+// it keeps a state scoped to a transport session (like templates
+// received over a TCP connection).
+type SessionDecoder interface {
+	// CloseSession discards the state attached to the provided
+	// session.
+	CloseSession(source net.IP, session string)
+}
+
 // RawFlow is an undecoded flow.
 type RawFlow struct {
 	TimeReceived time.Time
 	Payload      []byte
 	Source       net.IP
+	// Session identifies the transport session the flow was
+	// received from. It is empty for datagram-oriented
+	// transports. Otherwise, the state of the decoder is scoped
+	// to the session.
+	Session string
 }
 
 // NewDecoderFunc is the signature of a function to instantiate a decoder.
@@ -7,6 +7,7 @@ package sflow
 import (
 	"bytes"
 	"net"
+	"net/netip"
 
 	"github.com/netsampler/goflow2/decoders/sflow"
 	"github.com/netsampler/goflow2/producer"
@@ -29,7 +30,8 @@ const (
 
 // Decoder contains the state for the sFlow v5 decoder.
 type Decoder struct {
-	r *reporter.Reporter
+	r               *reporter.Reporter
+	countersHandler func([]*decoder.InterfaceCounters)
 
 	metrics struct {
 		errors                *reporter.CounterVec
@@ -109,6 +111,7 @@ func (nd *Decoder) Decode(in decoder.RawFlow) []*decoder.FlowMessage {
 	version := "5"
 	samples := msgDecConv.Samples
 	nd.metrics.stats.WithLabelValues(key, agent, version).Inc()
+	counters := []*decoder.InterfaceCounters{}
 	for _, s := range samples {
 		switch sConv := s.(type) {
 		case sflow.FlowSample:
@@ -121,6 +124,7 @@ func (nd *Decoder) Decode(in decoder.RawFlow) []*decoder.FlowMessage {
 				Inc()
 			nd.metrics.sampleRecordsStatsSum.WithLabelValues(key, agent, version, "CounterSample").
 				Add(float64(len(sConv.Records)))
+			counters = append(counters, nd.decodeCounters(net.IP(msgDecConv.AgentIP), ts, sConv)...)
 		case sflow.ExpandedFlowSample:
 			nd.metrics.sampleStatsSum.WithLabelValues(key, agent, version, "ExpandedFlowSample").
 				Inc()
@@ -129,6 +133,10 @@ func (nd *Decoder) Decode(in decoder.RawFlow) []*decoder.FlowMessage {
 		}
 	}
 
+	if len(counters) > 0 && nd.countersHandler != nil {
+		nd.countersHandler(counters)
+	}
+
 	flowMessageSet, _ := producer.ProcessMessageSFlow(msgDec)
 	for _, fmsg := range flowMessageSet {
 		fmsg.TimeReceived = ts
@@ -155,6 +163,36 @@ func (nd *Decoder) Decode(in decoder.RawFlow) []*decoder.FlowMessage {
 	return results
 }
 
+// decodeCounters extracts generic interface counters from a counter sample.
+func (nd *Decoder) decodeCounters(agent net.IP, ts uint64, sample sflow.CounterSample) []*decoder.InterfaceCounters {
+	exporterAddress, _ := netip.AddrFromSlice(agent.To16())
+	results := []*decoder.InterfaceCounters{}
+	for _, record := range sample.Records {
+		ifCounters, ok := record.Data.(sflow.IfCounters)
+		if !ok {
+			continue
+		}
+		results = append(results, &decoder.InterfaceCounters{
+			TimeReceived:    ts,
+			ExporterAddress: exporterAddress,
+			IfIndex:         ifCounters.IfIndex,
+			IfSpeed:         ifCounters.IfSpeed,
+			IfInOctets:      ifCounters.IfInOctets,
+			IfInErrors:      ifCounters.IfInErrors,
+			IfInDiscards:    ifCounters.IfInDiscards,
+			IfOutOctets:     ifCounters.IfOutOctets,
+			IfOutErrors:     ifCounters.IfOutErrors,
+			IfOutDiscards:   ifCounters.IfOutDiscards,
+		})
+	}
+	return results
+}
+
+// SetCountersHandler sets the function to call with decoded interface counters.
+func (nd *Decoder) SetCountersHandler(handler func([]*decoder.InterfaceCounters)) {
+	nd.countersHandler = handler
+}
+
 // Name returns the name of the decoder.
 func (nd *Decoder) Name() string {
 	return "sflow"
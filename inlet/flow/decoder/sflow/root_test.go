@@ -4,7 +4,9 @@
 package sflow
 
 import (
+	"encoding/binary"
 	"net"
+	"net/netip"
 	"path/filepath"
 	"testing"
 
@@ -43,6 +45,10 @@ func TestDecode(t *testing.T) {
 			IPv6FlowLabel:   426132,
 			SrcAddr:         net.ParseIP("2a0c:8880:2:0:185:21:130:38").To16(),
 			DstAddr:         net.ParseIP("2a0c:8880:2:0:185:21:130:39").To16(),
+			SrcVlan:         100,
+			DstVlan:         100,
+			SrcMAC:          0x246e96907a50,
+			DstMAC:          0x246e96043c08,
 			ExporterAddress: net.ParseIP("172.16.0.3").To16(),
 		}, {
 			SequenceNum:     812646826,
@@ -67,6 +73,9 @@ func TestDecode(t *testing.T) {
 			DstNetMask:      27,
 			SrcAddr:         net.ParseIP("104.26.8.24").To16(),
 			DstAddr:         net.ParseIP("45.90.161.46").To16(),
+			DstVlan:         100,
+			SrcMAC:          0xc4ca2bae3437,
+			DstMAC:          0xae18b04b268a,
 			ExporterAddress: net.ParseIP("172.16.0.3").To16(),
 			NextHop:         net.ParseIP("45.90.161.46").To16(),
 		}, {
@@ -88,6 +97,10 @@ func TestDecode(t *testing.T) {
 			IPv6FlowLabel:   426132,
 			SrcAddr:         net.ParseIP("2a0c:8880:2:0:185:21:130:38").To16(),
 			DstAddr:         net.ParseIP("2a0c:8880:2:0:185:21:130:39").To16(),
+			SrcVlan:         100,
+			DstVlan:         100,
+			SrcMAC:          0x246e96907a50,
+			DstMAC:          0x246e96043c08,
 			ExporterAddress: net.ParseIP("172.16.0.3").To16(),
 		}, {
 			SequenceNum:     812646826,
@@ -111,6 +124,9 @@ func TestDecode(t *testing.T) {
 			DstNetMask:      17,
 			SrcAddr:         net.ParseIP("45.90.161.148").To16(),
 			DstAddr:         net.ParseIP("191.87.91.27").To16(),
+			SrcVlan:         100,
+			SrcMAC:          0x7e127c7bfaf0,
+			DstMAC:          0xc4ca2bae3437,
 			ExporterAddress: net.ParseIP("172.16.0.3").To16(),
 			NextHop:         net.ParseIP("31.14.69.110").To16(),
 			NextHopAS:       203698,
@@ -133,6 +149,10 @@ func TestDecode(t *testing.T) {
 			IPv6FlowLabel:   426132,
 			SrcAddr:         net.ParseIP("2a0c:8880:2:0:185:21:130:38").To16(),
 			DstAddr:         net.ParseIP("2a0c:8880:2:0:185:21:130:39").To16(),
+			SrcVlan:         100,
+			DstVlan:         100,
+			SrcMAC:          0x246e96907a50,
+			DstMAC:          0x246e96043c08,
 			ExporterAddress: net.ParseIP("172.16.0.3").To16(),
 		},
 	}
@@ -189,6 +209,10 @@ func TestDecodeInterface(t *testing.T) {
 				IPv6FlowLabel:   426132,
 				SrcAddr:         net.ParseIP("2a0c:8880:2:0:185:21:130:38").To16(),
 				DstAddr:         net.ParseIP("2a0c:8880:2:0:185:21:130:39").To16(),
+				SrcVlan:         100,
+				DstVlan:         100,
+				SrcMAC:          0x246e96907a50,
+				DstMAC:          0x246e96043c08,
 				ExporterAddress: net.ParseIP("172.16.0.3").To16(),
 			},
 		}
@@ -229,6 +253,10 @@ func TestDecodeInterface(t *testing.T) {
 				IPv6FlowLabel:    426132,
 				SrcAddr:          net.ParseIP("2a0c:8880:2:0:185:21:130:38").To16(),
 				DstAddr:          net.ParseIP("2a0c:8880:2:0:185:21:130:39").To16(),
+				SrcVlan:          100,
+				DstVlan:          100,
+				SrcMAC:           0x246e96907a50,
+				DstMAC:           0x246e96043c08,
 				ExporterAddress:  net.ParseIP("172.16.0.3").To16(),
 			},
 		}
@@ -268,6 +296,10 @@ func TestDecodeInterface(t *testing.T) {
 				IPv6FlowLabel:   426132,
 				SrcAddr:         net.ParseIP("2a0c:8880:2:0:185:21:130:38").To16(),
 				DstAddr:         net.ParseIP("2a0c:8880:2:0:185:21:130:39").To16(),
+				SrcVlan:         100,
+				DstVlan:         100,
+				SrcMAC:          0x246e96907a50,
+				DstMAC:          0x246e96043c08,
 				ExporterAddress: net.ParseIP("172.16.0.3").To16(),
 			},
 		}
@@ -280,3 +312,91 @@ func TestDecodeInterface(t *testing.T) {
 		}
 	})
 }
+
+func TestDecodeCounters(t *testing.T) {
+	r := reporter.NewMock(t)
+	sdecoder := New(r)
+	got := []*decoder.InterfaceCounters{}
+	sdecoder.(decoder.CountersDecoder).SetCountersHandler(func(counters []*decoder.InterfaceCounters) {
+		got = append(got, counters...)
+	})
+
+	// Build an sFlow datagram with a counter sample containing a
+	// generic interface counters record.
+	u32 := func(b []byte, v uint32) []byte { return binary.BigEndian.AppendUint32(b, v) }
+	u64 := func(b []byte, v uint64) []byte { return binary.BigEndian.AppendUint64(b, v) }
+	record := []byte{}
+	record = u32(record, 434)            // ifIndex
+	record = u32(record, 6)              // ifType
+	record = u64(record, 10_000_000_000) // ifSpeed
+	record = u32(record, 1)              // ifDirection
+	record = u32(record, 3)              // ifStatus
+	record = u64(record, 1_000_000)      // ifInOctets
+	record = u32(record, 1000)           // ifInUcastPkts
+	record = u32(record, 0)              // ifInMulticastPkts
+	record = u32(record, 0)              // ifInBroadcastPkts
+	record = u32(record, 2)              // ifInDiscards
+	record = u32(record, 1)              // ifInErrors
+	record = u32(record, 0)              // ifInUnknownProtos
+	record = u64(record, 2_000_000)      // ifOutOctets
+	record = u32(record, 2000)           // ifOutUcastPkts
+	record = u32(record, 0)              // ifOutMulticastPkts
+	record = u32(record, 0)              // ifOutBroadcastPkts
+	record = u32(record, 4)              // ifOutDiscards
+	record = u32(record, 3)              // ifOutErrors
+	record = u32(record, 0)              // ifPromiscuousMode
+	sample := []byte{}
+	sample = u32(sample, 12)  // sequence number
+	sample = u32(sample, 434) // source ID
+	sample = u32(sample, 1)   // number of records
+	sample = u32(sample, 1)   // generic interface counters
+	sample = u32(sample, uint32(len(record)))
+	sample = append(sample, record...)
+	data := []byte{}
+	data = u32(data, 5)               // version
+	data = u32(data, 1)               // IPv4 agent
+	data = append(data, 192, 0, 2, 1) // agent address
+	data = u32(data, 0)               // sub agent ID
+	data = u32(data, 100)             // sequence number
+	data = u32(data, 3600000)         // uptime
+	data = u32(data, 1)               // number of samples
+	data = u32(data, 2)               // counter sample
+	data = u32(data, uint32(len(sample)))
+	data = append(data, sample...)
+
+	flows := sdecoder.Decode(decoder.RawFlow{Payload: data, Source: net.ParseIP("127.0.0.1")})
+	if flows == nil {
+		t.Fatalf("Decode() error on data")
+	}
+	if len(flows) != 0 {
+		t.Fatalf("Decode() returned %d flows, expected 0", len(flows))
+	}
+	expected := []*decoder.InterfaceCounters{
+		{
+			ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.1"),
+			IfIndex:         434,
+			IfSpeed:         10_000_000_000,
+			IfInOctets:      1_000_000,
+			IfInErrors:      1,
+			IfInDiscards:    2,
+			IfOutOctets:     2_000_000,
+			IfOutErrors:     3,
+			IfOutDiscards:   4,
+		},
+	}
+	for _, c := range got {
+		c.TimeReceived = 0
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("Decode() counters (-got, +want):\n%s", diff)
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_flow_decoder_sflow_", "sample_")
+	expectedMetrics := map[string]string{
+		`sample_records_sum{agent="192.0.2.1",exporter="127.0.0.1",type="CounterSample",version="5"}`: "1",
+		`sample_sum{agent="192.0.2.1",exporter="127.0.0.1",type="CounterSample",version="5"}`:         "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics after data (-got, +want):\n%s", diff)
+	}
+}
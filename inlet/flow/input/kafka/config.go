@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kafka
+
+import (
+	"akvorado/common/kafka"
+	"akvorado/inlet/flow/input"
+)
+
+// Configuration describes Kafka input configuration.
+type Configuration struct {
+	kafka.Configuration `mapstructure:",squash" yaml:"-,inline"`
+	// ConsumerGroup is the name of the consumer group to use.
+	ConsumerGroup string `validate:"required"`
+	// ExporterHeader is the name of the header containing the
+	// exporter address. When empty or when the header is
+	// missing, the key of the message is used instead.
+	ExporterHeader string
+	// ExporterEncoding tells how the exporter address is encoded,
+	// either as a string ("string") or as 4 or 16 raw bytes
+	// ("binary").
+	ExporterEncoding string `validate:"oneof=string binary"`
+	// QueueSize defines the size of the channel used to
+	// communicate incoming flows. 0 can be used to disable
+	// buffering.
+	QueueSize uint
+}
+
+// DefaultConfiguration is the default configuration for this input
+func DefaultConfiguration() input.Configuration {
+	configuration := kafka.DefaultConfiguration()
+	configuration.Topic = ""
+	return &Configuration{
+		Configuration:    configuration,
+		ConsumerGroup:    "akvorado-inlet",
+		ExporterHeader:   "exporter",
+		ExporterEncoding: "string",
+		QueueSize:        100000,
+	}
+}
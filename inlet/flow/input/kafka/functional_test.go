@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kafka
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/kafka"
+	"akvorado/common/reporter"
+	"akvorado/inlet/flow/decoder"
+)
+
+func TestRealKafka(t *testing.T) {
+	client, brokers := kafka.SetupKafkaBroker(t)
+
+	rand.Seed(time.Now().UnixMicro())
+	topicName := fmt.Sprintf("test-topic-%d", rand.Int())
+	configuration := DefaultConfiguration().(*Configuration)
+	configuration.Topic = topicName
+	configuration.Brokers = brokers
+	configuration.Version = kafka.Version(sarama.V2_8_1_0)
+	configuration.ConsumerGroup = fmt.Sprintf("test-group-%d", rand.Int())
+	r := reporter.NewMock(t)
+	in, err := configuration.New(r, daemon.NewMock(t), &decoder.DummyDecoder{})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	in.(*Input).kafkaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+	ch, err := in.Start()
+	if err != nil {
+		t.Fatalf("Start() error:\n%+v", err)
+	}
+	defer func() {
+		if err := in.Stop(); err != nil {
+			t.Fatalf("Stop() error:\n%+v", err)
+		}
+	}()
+
+	// Send two messages
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		t.Fatalf("NewSyncProducerFromClient() error:\n%+v", err)
+	}
+	defer producer.Close()
+	for _, msg := range []*sarama.ProducerMessage{
+		{
+			Topic: topicName,
+			Key:   sarama.StringEncoder("192.0.2.1"),
+			Value: sarama.StringEncoder("hello world!"),
+		}, {
+			Topic: topicName,
+			Headers: []sarama.RecordHeader{
+				{Key: []byte("exporter"), Value: []byte("192.0.2.2")},
+			},
+			Value: sarama.StringEncoder("goodbye world!"),
+		},
+	} {
+		if _, _, err := producer.SendMessage(msg); err != nil {
+			t.Fatalf("SendMessage() error:\n%+v", err)
+		}
+	}
+
+	// Get them back
+	expected := []string{
+		"192.0.2.1: hello world!",
+		"192.0.2.2: goodbye world!",
+	}
+	got := []string{}
+out:
+	for i := 0; i < len(expected); i++ {
+		select {
+		case flows := <-ch:
+			for _, fl := range flows {
+				got = append(got, fmt.Sprintf("%s: %s", net.IP(fl.ExporterAddress).To4(), fl.InIfDescription))
+			}
+		case <-time.After(30 * time.Second):
+			break out
+		}
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("Input data (-got, +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package kafka handles raw flows received from a Kafka topic.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"gopkg.in/tomb.v2"
+
+	"akvorado/common/daemon"
+	"akvorado/common/kafka"
+	"akvorado/common/reporter"
+	"akvorado/inlet/flow/decoder"
+	"akvorado/inlet/flow/input"
+)
+
+// Input represents the state of a Kafka input.
+type Input struct {
+	r      *reporter.Reporter
+	t      tomb.Tomb
+	config *Configuration
+
+	kafkaConfig *sarama.Config
+
+	metrics struct {
+		bytes    *reporter.CounterVec
+		messages *reporter.CounterVec
+		errors   *reporter.CounterVec
+		outDrops *reporter.CounterVec
+	}
+
+	ch      chan []*decoder.FlowMessage // channel to send flows to
+	decoder decoder.Decoder             // decoder to use
+}
+
+// New instantiate a new Kafka consumer from the provided configuration.
+func (configuration *Configuration) New(r *reporter.Reporter, daemon daemon.Component, dec decoder.Decoder) (input.Input, error) {
+	kafkaConfig, err := kafka.NewConfig(configuration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+	kafkaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
+	kafkaConfig.Consumer.Return.Errors = true
+	if err := kafkaConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("cannot validate Kafka configuration: %w", err)
+	}
+
+	input := &Input{
+		r:           r,
+		config:      configuration,
+		kafkaConfig: kafkaConfig,
+		ch:          make(chan []*decoder.FlowMessage, configuration.QueueSize),
+		decoder:     dec,
+	}
+
+	input.metrics.bytes = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "bytes",
+			Help: "Bytes received by the application.",
+		},
+		[]string{"topic", "exporter"},
+	)
+	input.metrics.messages = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "messages",
+			Help: "Messages received by the application.",
+		},
+		[]string{"topic", "exporter"},
+	)
+	input.metrics.errors = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "errors",
+			Help: "Errors while receiving messages by the application.",
+		},
+		[]string{"topic", "error"},
+	)
+	input.metrics.outDrops = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "out_drops",
+			Help: "Dropped messages due to internal queue full.",
+		},
+		[]string{"topic", "exporter"},
+	)
+
+	daemon.Track(&input.t, "inlet/flow/input/kafka")
+	return input, nil
+}
+
+// Start starts consuming the configured topic and producing flows.
+func (in *Input) Start() (<-chan []*decoder.FlowMessage, error) {
+	in.r.Info().Str("topic", in.config.Topic).Msg("starting Kafka input")
+	group, err := sarama.NewConsumerGroup(in.config.Brokers, in.config.ConsumerGroup, in.kafkaConfig)
+	if err != nil {
+		in.r.Err(err).
+			Str("brokers", strings.Join(in.config.Brokers, ",")).
+			Msg("unable to create Kafka consumer group")
+		return nil, fmt.Errorf("unable to create Kafka consumer group: %w", err)
+	}
+
+	// Consume messages
+	ctx := in.t.Context(context.Background())
+	in.t.Go(func() error {
+		for {
+			if err := group.Consume(ctx, []string{in.config.Topic}, in); err != nil {
+				if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+					return nil
+				}
+				in.r.Err(err).Str("topic", in.config.Topic).Msg("error while consuming from Kafka")
+				in.metrics.errors.WithLabelValues(in.config.Topic, "consumer error").Inc()
+			}
+			select {
+			case <-in.t.Dying():
+				return nil
+			case <-time.After(time.Second):
+			}
+		}
+	})
+
+	// Log errors
+	in.t.Go(func() error {
+		errLogger := in.r.Sample(reporter.BurstSampler(time.Minute, 3))
+		for {
+			select {
+			case <-in.t.Dying():
+				return nil
+			case err, ok := <-group.Errors():
+				if !ok {
+					return nil
+				}
+				errLogger.Err(err).Str("topic", in.config.Topic).Msg("Kafka consumer error")
+				in.metrics.errors.WithLabelValues(in.config.Topic, "consumer error").Inc()
+			}
+		}
+	})
+
+	// Watch for termination and close on dying
+	in.t.Go(func() error {
+		<-in.t.Dying()
+		return group.Close()
+	})
+
+	return in.ch, nil
+}
+
+// Stop stops the Kafka consumer.
+func (in *Input) Stop() error {
+	defer func() {
+		close(in.ch)
+		in.r.Info().Str("topic", in.config.Topic).Msg("Kafka input stopped")
+	}()
+	in.t.Kill(nil)
+	return in.t.Wait()
+}
+
+// Setup is run at the beginning of a new session, before ConsumeClaim.
+func (in *Input) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited.
+func (in *Input) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim decodes the messages from a claim and send the
+// resulting flows to the output channel.
+func (in *Input) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	topic := claim.Topic()
+	errLogger := in.r.Sample(reporter.BurstSampler(time.Minute, 1))
+	for {
+		select {
+		case <-session.Context().Done():
+			return nil
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			session.MarkMessage(msg, "")
+			source := in.exporterAddress(msg)
+			if source == nil {
+				errLogger.Error().Str("topic", topic).Msg("unable to get exporter address")
+				in.metrics.errors.WithLabelValues(topic, "missing exporter address").Inc()
+				continue
+			}
+			srcIP := source.String()
+			in.metrics.bytes.WithLabelValues(topic, srcIP).Add(float64(len(msg.Value)))
+			in.metrics.messages.WithLabelValues(topic, srcIP).Inc()
+
+			received := msg.Timestamp
+			if received.IsZero() {
+				received = time.Now()
+			}
+			flows := in.decoder.Decode(decoder.RawFlow{
+				TimeReceived: received,
+				Payload:      msg.Value,
+				Source:       source,
+			})
+			if len(flows) == 0 {
+				continue
+			}
+			select {
+			case <-session.Context().Done():
+				return nil
+			case in.ch <- flows:
+			default:
+				errLogger.Warn().Msgf("dropping flow due to queue full (size %d)",
+					in.config.QueueSize)
+				in.metrics.outDrops.WithLabelValues(topic, srcIP).Inc()
+			}
+		}
+	}
+}
+
+// exporterAddress extracts the exporter address from a message. The
+// configured header is checked first, then the key. Depending on the
+// configuration, the address is either encoded as a string or as 4 or
+// 16 raw bytes.
+func (in *Input) exporterAddress(msg *sarama.ConsumerMessage) net.IP {
+	candidates := [][]byte{}
+	if in.config.ExporterHeader != "" {
+		for _, header := range msg.Headers {
+			if header != nil && string(header.Key) == in.config.ExporterHeader {
+				candidates = append(candidates, header.Value)
+			}
+		}
+	}
+	candidates = append(candidates, msg.Key)
+	for _, candidate := range candidates {
+		switch in.config.ExporterEncoding {
+		case "binary":
+			if len(candidate) == net.IPv4len || len(candidate) == net.IPv6len {
+				return append(net.IP{}, candidate...)
+			}
+		default:
+			if ip := net.ParseIP(string(candidate)); ip != nil {
+				return ip
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kafka
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Shopify/sarama"
+
+	"akvorado/common/helpers"
+)
+
+func TestExporterAddress(t *testing.T) {
+	cases := []struct {
+		Description string
+		Header      string
+		Encoding    string
+		Message     sarama.ConsumerMessage
+		Expected    net.IP
+	}{
+		{
+			Description: "from header",
+			Header:      "exporter",
+			Message: sarama.ConsumerMessage{
+				Headers: []*sarama.RecordHeader{
+					{Key: []byte("something"), Value: []byte("192.0.2.1")},
+					{Key: []byte("exporter"), Value: []byte("192.0.2.2")},
+				},
+				Key: []byte("192.0.2.3"),
+			},
+			Expected: net.ParseIP("192.0.2.2"),
+		}, {
+			Description: "from raw header",
+			Header:      "exporter",
+			Encoding:    "binary",
+			Message: sarama.ConsumerMessage{
+				Headers: []*sarama.RecordHeader{
+					{Key: []byte("exporter"), Value: []byte{192, 0, 2, 2}},
+				},
+			},
+			Expected: net.IP{192, 0, 2, 2},
+		}, {
+			Description: "raw header without binary encoding",
+			Header:      "exporter",
+			Message: sarama.ConsumerMessage{
+				Headers: []*sarama.RecordHeader{
+					{Key: []byte("exporter"), Value: []byte{192, 0, 2, 2}},
+				},
+			},
+			Expected: nil,
+		}, {
+			Description: "4-character key",
+			Header:      "exporter",
+			Message: sarama.ConsumerMessage{
+				Key: []byte("edge"),
+			},
+			Expected: nil,
+		}, {
+			Description: "from key when header is missing",
+			Header:      "exporter",
+			Message: sarama.ConsumerMessage{
+				Key: []byte("2001:db8::1"),
+			},
+			Expected: net.ParseIP("2001:db8::1"),
+		}, {
+			Description: "from key when header is not configured",
+			Message: sarama.ConsumerMessage{
+				Headers: []*sarama.RecordHeader{
+					{Key: []byte("exporter"), Value: []byte("192.0.2.2")},
+				},
+				Key: []byte("192.0.2.3"),
+			},
+			Expected: net.ParseIP("192.0.2.3"),
+		}, {
+			Description: "no address",
+			Header:      "exporter",
+			Message: sarama.ConsumerMessage{
+				Key: []byte("hello"),
+			},
+			Expected: nil,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			configuration := DefaultConfiguration().(*Configuration)
+			configuration.ExporterHeader = tc.Header
+			if tc.Encoding != "" {
+				configuration.ExporterEncoding = tc.Encoding
+			}
+			in := &Input{config: configuration}
+			got := in.exporterAddress(&tc.Message)
+			if diff := helpers.Diff(got, tc.Expected); diff != "" {
+				t.Fatalf("exporterAddress() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
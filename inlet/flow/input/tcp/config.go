@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tcp
+
+import (
+	"time"
+
+	"akvorado/inlet/flow/input"
+)
+
+// Configuration describes TCP input configuration.
+type Configuration struct {
+	// Listen tells which port to listen to.
+	Listen string `validate:"required,listen"`
+	// QueueSize defines the size of the channel used to
+	// communicate incoming flows. 0 can be used to disable
+	// buffering.
+	QueueSize uint
+	// IdleTimeout is the duration after which an idle connection
+	// is closed. 0 disables the timeout.
+	IdleTimeout time.Duration `validate:"min=0"`
+	// TLS defines the TLS configuration of the listener.
+	TLS TLSConfiguration
+}
+
+// TLSConfiguration defines TLS configuration for the TCP input.
+type TLSConfiguration struct {
+	// Enable says if TLS should be used
+	Enable bool `validate:"required_with=CertFile KeyFile ClientCAFile Exporters"`
+	// CertFile tells the location of the server certificate.
+	CertFile string `validate:"required_with=Enable"`
+	// KeyFile tells the location of the server key.
+	KeyFile string `validate:"required_with=Enable"`
+	// ClientCAFile tells the location of the CA certificate to
+	// check client certificates. When set, exporters have to
+	// present a valid certificate.
+	ClientCAFile string
+	// Exporters maps the common name of client certificates to
+	// the address of the exporter. When the common name is not
+	// present, the remote address of the connection is used.
+	Exporters map[string]string `validate:"dive,ip"`
+}
+
+// DefaultConfiguration is the default configuration for this input
+func DefaultConfiguration() input.Configuration {
+	return &Configuration{
+		Listen:      "0.0.0.0:4739",
+		QueueSize:   100000,
+		IdleTimeout: 10 * time.Minute,
+	}
+}
@@ -0,0 +1,316 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package tcp handles IPFIX over TCP or TLS listeners (RFC 7011).
+package tcp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"akvorado/common/daemon"
+	"akvorado/common/reporter"
+	"akvorado/inlet/flow/decoder"
+	"akvorado/inlet/flow/input"
+)
+
+const (
+	// ipfixVersion is the only version accepted on a stream.
+	ipfixVersion = 10
+	// ipfixHeaderLength is the length of an IPFIX message header.
+	ipfixHeaderLength = 16
+)
+
+var errUnexpectedVersion = errors.New("unexpected version")
+
+// Input represents the state of a TCP listener.
+type Input struct {
+	r      *reporter.Reporter
+	t      tomb.Tomb
+	config *Configuration
+
+	metrics struct {
+		bytes       *reporter.CounterVec
+		packets     *reporter.CounterVec
+		errors      *reporter.CounterVec
+		outDrops    *reporter.CounterVec
+		connections *reporter.GaugeVec
+	}
+
+	tlsConfig *tls.Config
+	connsLock sync.Mutex
+	conns     map[net.Conn]struct{}
+
+	address net.Addr                    // listening address, for testing purpose
+	ch      chan []*decoder.FlowMessage // channel to send flows to
+	decoder decoder.Decoder             // decoder to use
+}
+
+// New instantiate a new TCP listener from the provided configuration.
+func (configuration *Configuration) New(r *reporter.Reporter, daemon daemon.Component, dec decoder.Decoder) (input.Input, error) {
+	input := &Input{
+		r:       r,
+		config:  configuration,
+		conns:   map[net.Conn]struct{}{},
+		ch:      make(chan []*decoder.FlowMessage, configuration.QueueSize),
+		decoder: dec,
+	}
+
+	if configuration.TLS.Enable {
+		cert, err := tls.LoadX509KeyPair(configuration.TLS.CertFile, configuration.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load certificate for TCP input: %w", err)
+		}
+		input.tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}
+		if configuration.TLS.ClientCAFile != "" {
+			caCert, err := os.ReadFile(configuration.TLS.ClientCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read client CA certificate for TCP input: %w", err)
+			}
+			caCertPool := x509.NewCertPool()
+			if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
+				return nil, errors.New("cannot parse client CA certificate for TCP input")
+			}
+			input.tlsConfig.ClientCAs = caCertPool
+			input.tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	input.metrics.bytes = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "bytes",
+			Help: "Bytes received by the application.",
+		},
+		[]string{"listener", "exporter"},
+	)
+	input.metrics.packets = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "packets",
+			Help: "Messages received by the application.",
+		},
+		[]string{"listener", "exporter"},
+	)
+	input.metrics.errors = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "errors",
+			Help: "Errors while receiving messages by the application.",
+		},
+		[]string{"listener", "error"},
+	)
+	input.metrics.outDrops = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "out_drops",
+			Help: "Dropped messages due to internal queue full.",
+		},
+		[]string{"listener", "exporter"},
+	)
+	input.metrics.connections = r.GaugeVec(
+		reporter.GaugeOpts{
+			Name: "connections",
+			Help: "Number of established connections.",
+		},
+		[]string{"listener", "exporter"},
+	)
+
+	daemon.Track(&input.t, "inlet/flow/input/tcp")
+	return input, nil
+}
+
+// Start starts listening to the provided TCP socket and producing flows.
+func (in *Input) Start() (<-chan []*decoder.FlowMessage, error) {
+	in.r.Info().Str("listen", in.config.Listen).Msg("starting TCP input")
+	listener, err := net.Listen("tcp", in.config.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen to %v: %w", in.config.Listen, err)
+	}
+	in.address = listener.Addr()
+	if in.tlsConfig != nil {
+		listener = tls.NewListener(listener, in.tlsConfig)
+	}
+	in.r.Info().Str("listen", in.address.String()).Msg("TCP input listening")
+
+	in.t.Go(func() error {
+		errLogger := in.r.Sample(reporter.BurstSampler(time.Minute, 1))
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return nil
+				}
+				errLogger.Err(err).Str("listen", in.config.Listen).Msg("unable to accept connection")
+				in.metrics.errors.WithLabelValues(in.config.Listen, "cannot accept").Inc()
+				continue
+			}
+			in.connsLock.Lock()
+			in.conns[conn] = struct{}{}
+			in.connsLock.Unlock()
+			in.t.Go(func() error {
+				defer func() {
+					in.connsLock.Lock()
+					delete(in.conns, conn)
+					in.connsLock.Unlock()
+					conn.Close()
+				}()
+				in.handleConnection(conn)
+				return nil
+			})
+		}
+	})
+
+	// Watch for termination and close on dying
+	in.t.Go(func() error {
+		<-in.t.Dying()
+		listener.Close()
+		in.connsLock.Lock()
+		for conn := range in.conns {
+			conn.Close()
+		}
+		in.connsLock.Unlock()
+		return nil
+	})
+
+	return in.ch, nil
+}
+
+// handleConnection decodes the IPFIX messages received on the
+// provided connection until it is closed.
+func (in *Input) handleConnection(conn net.Conn) {
+	listen := in.config.Listen
+	session := conn.RemoteAddr().String()
+	l := in.r.With().
+		Str("listen", listen).
+		Str("remote", session).
+		Logger()
+	errLogger := l.Sample(reporter.BurstSampler(time.Minute, 1))
+
+	source, err := in.exporterAddress(conn)
+	if err != nil {
+		errLogger.Err(err).Msg("unable to identify exporter")
+		in.metrics.errors.WithLabelValues(listen, "cannot identify exporter").Inc()
+		return
+	}
+	srcIP := source.String()
+	l.Debug().Str("exporter", srcIP).Msg("new connection")
+	in.metrics.connections.WithLabelValues(listen, srcIP).Inc()
+	defer func() {
+		in.metrics.connections.WithLabelValues(listen, srcIP).Dec()
+		if sd, ok := in.decoder.(decoder.SessionDecoder); ok {
+			sd.CloseSession(source, session)
+		}
+		l.Debug().Str("exporter", srcIP).Msg("connection closed")
+	}()
+
+	reader := bufio.NewReader(conn)
+	for {
+		payload, err := in.readMessage(conn, reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			errLogger.Err(err).Str("exporter", srcIP).Msg("unable to read IPFIX message")
+			in.metrics.errors.WithLabelValues(listen, "cannot read message").Inc()
+			return
+		}
+		in.metrics.bytes.WithLabelValues(listen, srcIP).Add(float64(len(payload)))
+		in.metrics.packets.WithLabelValues(listen, srcIP).Inc()
+		flows := in.decoder.Decode(decoder.RawFlow{
+			TimeReceived: time.Now(),
+			Payload:      payload,
+			Source:       source,
+			Session:      session,
+		})
+		if len(flows) == 0 {
+			continue
+		}
+		select {
+		case <-in.t.Dying():
+			return
+		case in.ch <- flows:
+		default:
+			errLogger.Warn().Msgf("dropping flow due to queue full (size %d)",
+				in.config.QueueSize)
+			in.metrics.outDrops.WithLabelValues(listen, srcIP).Inc()
+		}
+	}
+}
+
+// readMessage reads a complete IPFIX message from the provided
+// reader. Messages are framed using the length from their header.
+func (in *Input) readMessage(conn net.Conn, reader *bufio.Reader) ([]byte, error) {
+	if in.config.IdleTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(in.config.IdleTimeout))
+	}
+	header, err := reader.Peek(4)
+	if err != nil {
+		return nil, err
+	}
+	version := binary.BigEndian.Uint16(header[0:2])
+	length := int(binary.BigEndian.Uint16(header[2:4]))
+	if version != ipfixVersion {
+		return nil, fmt.Errorf("%w: %d", errUnexpectedVersion, version)
+	}
+	if length < ipfixHeaderLength {
+		return nil, fmt.Errorf("message too short: %d", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("truncated message: %w", err)
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// exporterAddress returns the exporter address for the provided
+// connection. With TLS, the common name of the client certificate is
+// mapped to an exporter address when possible. Otherwise, the remote
+// address is used.
+func (in *Input) exporterAddress(conn net.Conn) (net.IP, error) {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if in.config.IdleTimeout > 0 {
+			tlsConn.SetDeadline(time.Now().Add(in.config.IdleTimeout))
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, fmt.Errorf("TLS handshake error: %w", err)
+		}
+		state := tlsConn.ConnectionState()
+		if len(state.PeerCertificates) > 0 {
+			cn := state.PeerCertificates[0].Subject.CommonName
+			if exporter, ok := in.config.TLS.Exporters[cn]; ok {
+				if ip := net.ParseIP(exporter); ip != nil {
+					return ip, nil
+				}
+			}
+		}
+	}
+	tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return nil, fmt.Errorf("unexpected remote address %s", conn.RemoteAddr())
+	}
+	return tcpAddr.IP, nil
+}
+
+// Stop stops the TCP listener
+func (in *Input) Stop() error {
+	l := in.r.With().Str("listen", in.config.Listen).Logger()
+	defer func() {
+		close(in.ch)
+		l.Info().Msg("TCP listener stopped")
+	}()
+	in.t.Kill(nil)
+	return in.t.Wait()
+}
@@ -0,0 +1,218 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/inlet/flow/decoder"
+)
+
+// ipfixMessage builds a fake IPFIX message with the provided content.
+func ipfixMessage(content string) []byte {
+	msg := make([]byte, ipfixHeaderLength+len(content))
+	binary.BigEndian.PutUint16(msg[0:2], ipfixVersion)
+	binary.BigEndian.PutUint16(msg[2:4], uint16(len(msg)))
+	copy(msg[ipfixHeaderLength:], content)
+	return msg
+}
+
+// receiveFlows gets the content of the provided number of flows.
+func receiveFlows(t *testing.T, ch <-chan []*decoder.FlowMessage, count int) []string {
+	t.Helper()
+	got := []string{}
+	for len(got) < count {
+		select {
+		case flows := <-ch:
+			for _, fl := range flows {
+				got = append(got, fl.InIfDescription[ipfixHeaderLength:])
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no decoded flows received")
+		}
+	}
+	return got
+}
+
+func TestTCPInput(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration().(*Configuration)
+	configuration.Listen = "127.0.0.1:0"
+	in, err := configuration.New(r, daemon.NewMock(t), &decoder.DummyDecoder{})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	ch, err := in.Start()
+	if err != nil {
+		t.Fatalf("Start() error:\n%+v", err)
+	}
+	defer func() {
+		if err := in.Stop(); err != nil {
+			t.Fatalf("Stop() error:\n%+v", err)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", in.(*Input).address.String())
+	if err != nil {
+		t.Fatalf("Dial() error:\n%+v", err)
+	}
+	defer conn.Close()
+
+	// Two messages in one write, then one message in two writes
+	payload := append(ipfixMessage("hello world!"), ipfixMessage("bye bye")...)
+	if _, err := conn.Write(payload); err != nil {
+		t.Fatalf("Write() error:\n%+v", err)
+	}
+	payload = ipfixMessage("hello again!")
+	if _, err := conn.Write(payload[:10]); err != nil {
+		t.Fatalf("Write() error:\n%+v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, err := conn.Write(payload[10:]); err != nil {
+		t.Fatalf("Write() error:\n%+v", err)
+	}
+
+	got := receiveFlows(t, ch, 3)
+	expected := []string{"hello world!", "bye bye", "hello again!"}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("Input data (-got, +want):\n%s", diff)
+	}
+
+	// Send an invalid message
+	if _, err := conn.Write([]byte{0, 9, 0, 20}); err != nil {
+		t.Fatalf("Write() error:\n%+v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_flow_input_tcp_")
+	expectedMetrics := map[string]string{
+		`bytes{exporter="127.0.0.1",listener="127.0.0.1:0"}`:         "79",
+		`packets{exporter="127.0.0.1",listener="127.0.0.1:0"}`:       "3",
+		`connections{exporter="127.0.0.1",listener="127.0.0.1:0"}`:   "0",
+		`errors{error="cannot read message",listener="127.0.0.1:0"}`: "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Input metrics (-got, +want):\n%s", diff)
+	}
+}
+
+// generateCertificate generates a certificate signed by the provided
+// parent (or self-signed) and writes it with its key to the provided
+// directory.
+func generateCertificate(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error:\n%+v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent = template
+		parentKey = key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error:\n%+v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error:\n%+v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error:\n%+v", err)
+	}
+	os.WriteFile(filepath.Join(dir, name+".pem"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(filepath.Join(dir, name+".key"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return cert, key
+}
+
+func TestTLSInput(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := generateCertificate(t, dir, "ca", nil, nil)
+	generateCertificate(t, dir, "server", ca, caKey)
+	generateCertificate(t, dir, "router1", ca, caKey)
+
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration().(*Configuration)
+	configuration.Listen = "127.0.0.1:0"
+	configuration.TLS = TLSConfiguration{
+		Enable:       true,
+		CertFile:     filepath.Join(dir, "server.pem"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+		Exporters: map[string]string{
+			"router1": "192.0.2.1",
+		},
+	}
+	in, err := configuration.New(r, daemon.NewMock(t), &decoder.DummyDecoder{})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	ch, err := in.Start()
+	if err != nil {
+		t.Fatalf("Start() error:\n%+v", err)
+	}
+	defer func() {
+		if err := in.Stop(); err != nil {
+			t.Fatalf("Stop() error:\n%+v", err)
+		}
+	}()
+
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "router1.pem"), filepath.Join(dir, "router1.key"))
+	if err != nil {
+		t.Fatalf("LoadX509KeyPair() error:\n%+v", err)
+	}
+	caPool := x509.NewCertPool()
+	caPool.AddCert(ca)
+	conn, err := tls.Dial("tcp", in.(*Input).address.String(), &tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		RootCAs:      caPool,
+	})
+	if err != nil {
+		t.Fatalf("Dial() error:\n%+v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write(ipfixMessage("hello world!")); err != nil {
+		t.Fatalf("Write() error:\n%+v", err)
+	}
+
+	select {
+	case flows := <-ch:
+		got := net.IP(flows[0].ExporterAddress).String()
+		if got != "192.0.2.1" {
+			t.Fatalf("ExporterAddress (-got, +want):\n-%s\n+%s", got, "192.0.2.1")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no decoded flows received")
+	}
+}
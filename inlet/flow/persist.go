@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package flow
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"akvorado/inlet/flow/decoder"
+)
+
+// saveState stores the state of the decoders to the provided location.
+func (c *Component) saveState(stateFile string) error {
+	state := map[string][]byte{}
+	for name, dec := range c.decoders {
+		pdec, ok := dec.(decoder.PersistentDecoder)
+		if !ok {
+			continue
+		}
+		decState, err := pdec.SaveState()
+		if err != nil {
+			return fmt.Errorf("unable to save state for decoder %q: %w", name, err)
+		}
+		state[name] = decState
+	}
+
+	tmpFile, err := ioutil.TempFile(
+		filepath.Dir(stateFile),
+		fmt.Sprintf("%s-*", filepath.Base(stateFile)))
+	if err != nil {
+		return fmt.Errorf("unable to create state file %q: %w", stateFile, err)
+	}
+	defer func() {
+		tmpFile.Close()           // ignore errors
+		os.Remove(tmpFile.Name()) // ignore errors
+	}()
+
+	// Write state
+	encoder := gob.NewEncoder(tmpFile)
+	if err := encoder.Encode(decoder.StateVersion); err != nil {
+		return fmt.Errorf("unable to encode state: %w", err)
+	}
+	if err := encoder.Encode(state); err != nil {
+		return fmt.Errorf("unable to encode state: %w", err)
+	}
+
+	// Move state to new location
+	if err := os.Rename(tmpFile.Name(), stateFile); err != nil {
+		return fmt.Errorf("unable to write state file %q: %w", stateFile, err)
+	}
+	return nil
+}
+
+// loadState loads the state of the decoders from the provided location.
+func (c *Component) loadState(stateFile string) error {
+	f, err := os.Open(stateFile)
+	if err != nil {
+		return fmt.Errorf("unable to load state %q: %w", stateFile, err)
+	}
+	defer f.Close()
+	gobDecoder := gob.NewDecoder(f)
+	var version int
+	if err := gobDecoder.Decode(&version); err != nil {
+		return fmt.Errorf("unable to decode state: %w", err)
+	}
+	if version != decoder.StateVersion {
+		return decoder.ErrStateVersion
+	}
+	state := map[string][]byte{}
+	if err := gobDecoder.Decode(&state); err != nil {
+		return fmt.Errorf("unable to decode state: %w", err)
+	}
+
+	for name, decState := range state {
+		dec, ok := c.decoders[name]
+		if !ok {
+			continue
+		}
+		pdec, ok := dec.(decoder.PersistentDecoder)
+		if !ok {
+			continue
+		}
+		if err := pdec.LoadState(decState, c.config.StateMaxAge); err != nil {
+			return fmt.Errorf("unable to load state for decoder %q: %w", name, err)
+		}
+	}
+	return nil
+}
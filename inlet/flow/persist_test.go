@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package flow
+
+import (
+	"encoding/gob"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/inlet/flow/decoder"
+)
+
+func TestSaveLoadStateFile(t *testing.T) {
+	base := filepath.Join("decoder", "netflow", "testdata")
+	source := net.ParseIP("127.0.0.1")
+	stateFile := filepath.Join(t.TempDir(), "flow.state")
+	config := DefaultConfiguration()
+	config.Inputs = nil
+
+	// Missing state file
+	c := NewMock(t, reporter.NewMock(t), config)
+	if err := c.loadState(stateFile); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("loadState() error:\n%+v", err)
+	}
+
+	// Feed templates and save the state
+	for _, f := range []string{
+		"options-template-257.pcap",
+		"options-data-257.pcap",
+		"template-260.pcap",
+	} {
+		payload := helpers.ReadPcapPayload(t, filepath.Join(base, f))
+		c.decoders["netflow"].Decode(decoder.RawFlow{Payload: payload, Source: source})
+	}
+	if err := c.saveState(stateFile); err != nil {
+		t.Fatalf("saveState() error:\n%+v", err)
+	}
+
+	// Restore the state in a new component
+	data := helpers.ReadPcapPayload(t, filepath.Join(base, "data-260.pcap"))
+	c = NewMock(t, reporter.NewMock(t), config)
+	if got := c.decoders["netflow"].Decode(decoder.RawFlow{Payload: data, Source: source}); got != nil {
+		t.Fatalf("Decode() without state should have failed")
+	}
+	if err := c.loadState(stateFile); err != nil {
+		t.Fatalf("loadState() error:\n%+v", err)
+	}
+	got := c.decoders["netflow"].Decode(decoder.RawFlow{Payload: data, Source: source})
+	if len(got) != 4 {
+		t.Fatalf("Decode() got %d flows, expected 4", len(got))
+	}
+	for _, f := range got {
+		if f.SamplingRate != 30000 {
+			t.Fatalf("Decode() got sampling rate %d, expected 30000", f.SamplingRate)
+		}
+	}
+
+	// Incompatible version
+	f, err := os.Create(stateFile)
+	if err != nil {
+		t.Fatalf("Create() error:\n%+v", err)
+	}
+	if err := gob.NewEncoder(f).Encode(decoder.StateVersion + 1); err != nil {
+		t.Fatalf("Encode() error:\n%+v", err)
+	}
+	f.Close()
+	if err := c.loadState(stateFile); !errors.Is(err, decoder.ErrStateVersion) {
+		t.Fatalf("loadState() error:\n%+v", err)
+	}
+}
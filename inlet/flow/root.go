@@ -9,6 +9,8 @@ import (
 	"errors"
 	"fmt"
 	"net/netip"
+	"os"
+	"time"
 
 	"gopkg.in/tomb.v2"
 
@@ -30,16 +32,22 @@ type Component struct {
 		decoderStats  *reporter.CounterVec
 		decoderErrors *reporter.CounterVec
 		decoderTime   *reporter.SummaryVec
+
+		countersDropped *reporter.CounterVec
 	}
 
 	// Channel for sending flows out of the package.
 	outgoingFlows chan *Message
+	// Channel for sending interface counters out of the package
+	// (nil when disabled).
+	outgoingCounters chan *decoder.InterfaceCounters
 
 	// Per-exporter rate-limiters
 	limiters map[netip.Addr]*limiter
 
-	// Inputs
-	inputs []input.Input
+	// Inputs and decoders
+	inputs   []input.Input
+	decoders map[string]decoder.Decoder
 }
 
 // Dependencies are the dependencies of the flow component.
@@ -61,6 +69,11 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		outgoingFlows: make(chan *Message),
 		limiters:      make(map[netip.Addr]*limiter),
 		inputs:        make([]input.Input, len(configuration.Inputs)),
+		decoders:      make(map[string]decoder.Decoder),
+	}
+	if configuration.InterfaceCounters {
+		c.outgoingCounters = make(chan *decoder.InterfaceCounters,
+			configuration.InterfaceCountersQueueSize)
 	}
 
 	// Initialize decoders (at most once each)
@@ -77,7 +90,11 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 			return nil, fmt.Errorf("unknown decoder %q", input.Decoder)
 		}
 		dec = decoderfunc(r)
+		if cdec, ok := dec.(decoder.CountersDecoder); ok && c.outgoingCounters != nil {
+			cdec.SetCountersHandler(c.sendCounters)
+		}
 		alreadyInitialized[input.Decoder] = dec
+		c.decoders[input.Decoder] = dec
 		decs[idx] = c.wrapDecoder(dec)
 	}
 
@@ -113,6 +130,13 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		},
 		[]string{"name"},
 	)
+	c.metrics.countersDropped = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "counters_dropped_total",
+			Help: "Interface counters dropped because the queue was full.",
+		},
+		[]string{"exporter"},
+	)
 
 	c.d.Daemon.Track(&c.t, "inlet/flow")
 	c.initHTTP()
@@ -124,8 +148,52 @@ func (c *Component) Flows() <-chan *Message {
 	return c.outgoingFlows
 }
 
+// Counters returns a channel to receive interface counters. It
+// returns nil when the export of interface counters is disabled.
+func (c *Component) Counters() <-chan *decoder.InterfaceCounters {
+	return c.outgoingCounters
+}
+
+// sendCounters sends the provided interface counters out of the
+// package. It does not block: when the queue is full, counters are
+// dropped to not slow down flow decoding.
+func (c *Component) sendCounters(counters []*decoder.InterfaceCounters) {
+	for _, counter := range counters {
+		select {
+		case c.outgoingCounters <- counter:
+		default:
+			c.metrics.countersDropped.
+				WithLabelValues(counter.ExporterAddress.Unmap().String()).
+				Inc()
+		}
+	}
+}
+
 // Start starts the flow component.
 func (c *Component) Start() error {
+	// Load decoder state and save it periodically
+	if c.config.StatePersistFile != "" {
+		if err := c.loadState(c.config.StatePersistFile); errors.Is(err, os.ErrNotExist) {
+			c.r.Info().Msg("no decoder state to load")
+		} else if err != nil {
+			c.r.Err(err).Msg("cannot load decoder state, ignoring")
+		}
+		c.t.Go(func() error {
+			ticker := time.NewTicker(c.config.StatePersistInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-c.t.Dying():
+					return nil
+				case <-ticker.C:
+					if err := c.saveState(c.config.StatePersistFile); err != nil {
+						c.r.Err(err).Msg("cannot save decoder state")
+					}
+				}
+			}
+		})
+	}
+
 	for _, input := range c.inputs {
 		ch, err := input.Start()
 		stopper := input.Stop
@@ -159,6 +227,14 @@ func (c *Component) Start() error {
 func (c *Component) Stop() error {
 	defer func() {
 		close(c.outgoingFlows)
+		if c.outgoingCounters != nil {
+			close(c.outgoingCounters)
+		}
+		if c.config.StatePersistFile != "" {
+			if err := c.saveState(c.config.StatePersistFile); err != nil {
+				c.r.Err(err).Msg("cannot save decoder state")
+			}
+		}
 		c.r.Info().Msg("flow component stopped")
 	}()
 	c.r.Info().Msg("stopping flow component")
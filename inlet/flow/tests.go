@@ -12,6 +12,7 @@ import (
 	"akvorado/common/helpers"
 	"akvorado/common/http"
 	"akvorado/common/reporter"
+	"akvorado/inlet/flow/decoder"
 	"akvorado/inlet/flow/input/udp"
 )
 
@@ -45,3 +46,8 @@ func NewMock(t *testing.T, r *reporter.Reporter, config Configuration) *Componen
 func (c *Component) Inject(t *testing.T, fmsg *Message) {
 	c.outgoingFlows <- fmsg
 }
+
+// InjectCounters inject the provided interface counters, as if they were received.
+func (c *Component) InjectCounters(t *testing.T, counters *decoder.InterfaceCounters) {
+	c.outgoingCounters <- counters
+}
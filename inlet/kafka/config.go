@@ -27,6 +27,9 @@ type Configuration struct {
 	CompressionCodec CompressionCodec
 	// QueueSize defines the size of the channel used to send to Kafka.
 	QueueSize int `validate:"min=0"`
+	// Outputs is a list of additional outputs. Flows matching the
+	// rule of an output are also sent to it.
+	Outputs []OutputConfiguration `validate:"dive"`
 }
 
 // DefaultConfiguration represents the default configuration for the Kafka exporter.
@@ -37,8 +37,8 @@ func TestRealKafka(t *testing.T) {
 	}
 	helpers.StartStop(t, c)
 
-	c.Send("127.0.0.1", []byte("hello world!"))
-	c.Send("127.0.0.1", []byte("goodbye world!"))
+	c.Send("127.0.0.1", []byte("hello world!"), nil)
+	c.Send("127.0.0.1", []byte("goodbye world!"), nil)
 
 	time.Sleep(10 * time.Millisecond)
 	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_", "sent_")
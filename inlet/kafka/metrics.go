@@ -19,6 +19,12 @@ type metrics struct {
 	bytesSent    *reporter.CounterVec
 	errors       *reporter.CounterVec
 
+	outputMessagesSent *reporter.CounterVec
+	outputErrors       *reporter.CounterVec
+
+	countersMessagesSent *reporter.CounterVec
+	countersBytesSent    *reporter.CounterVec
+
 	kafkaIncomingByteRate  *reporter.MetricDesc
 	kafkaOutgoingByteRate  *reporter.MetricDesc
 	kafkaRequestRate       *reporter.MetricDesc
@@ -57,6 +63,34 @@ func (c *Component) initMetrics() {
 		},
 		[]string{"error"},
 	)
+	c.metrics.outputMessagesSent = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "output_messages_total",
+			Help: "Number of messages sent to a given output.",
+		},
+		[]string{"output"},
+	)
+	c.metrics.outputErrors = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "output_errors_total",
+			Help: "Number of errors when evaluating the rule of a given output.",
+		},
+		[]string{"output"},
+	)
+	c.metrics.countersMessagesSent = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "counters_sent_messages_total",
+			Help: "Number of interface counters messages sent from a given exporter.",
+		},
+		[]string{"exporter"},
+	)
+	c.metrics.countersBytesSent = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "counters_sent_bytes_total",
+			Help: "Number of interface counters bytes sent from a given exporter.",
+		},
+		[]string{"exporter"},
+	)
 
 	c.metrics.kafkaIncomingByteRate = c.r.MetricDesc(
 		"brokers_incoming_byte_rate",
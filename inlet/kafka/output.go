@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kafka
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+
+	"akvorado/inlet/flow"
+)
+
+// OutputConfiguration describes an additional output for flows.
+type OutputConfiguration struct {
+	// Topic is the topic to write matching flows to. Like for the
+	// main topic, the schema version is appended to it.
+	Topic string `validate:"required"`
+	// Brokers is the list of brokers to connect to. When empty, the
+	// main brokers are used.
+	Brokers []string `validate:"dive,listen"`
+	// Match is the rule selecting the flows to send to this output.
+	Match OutputRule
+	// Exclusive tells to not send matching flows to the main topic.
+	Exclusive bool
+}
+
+// OutputRule defines a rule to select the flows sent to an output.
+type OutputRule struct {
+	program *vm.Program
+}
+
+// outputExporterInfo contains the information we want to expose about
+// an exporter.
+type outputExporterInfo struct {
+	IP     string
+	Name   string
+	Group  string
+	Role   string
+	Site   string
+	Region string
+	Tenant string
+}
+
+// outputInterfaceInfo contains the information we want to expose
+// about an interface.
+type outputInterfaceInfo struct {
+	Name         string
+	Description  string
+	Speed        uint32
+	Connectivity string
+	Provider     string
+	Boundary     string
+}
+
+// outputRuleEnvironment defines the environment used by output rules
+type outputRuleEnvironment struct {
+	Exporter outputExporterInfo
+	InIf     outputInterfaceInfo
+	OutIf    outputInterfaceInfo
+}
+
+// newOutputRuleEnvironment builds the environment for output rules
+// from a flow.
+func newOutputRuleEnvironment(fl *flow.Message) outputRuleEnvironment {
+	return outputRuleEnvironment{
+		Exporter: outputExporterInfo{
+			IP:     net.IP(fl.ExporterAddress).String(),
+			Name:   fl.ExporterName,
+			Group:  fl.ExporterGroup,
+			Role:   fl.ExporterRole,
+			Site:   fl.ExporterSite,
+			Region: fl.ExporterRegion,
+			Tenant: fl.ExporterTenant,
+		},
+		InIf: outputInterfaceInfo{
+			Name:         fl.InIfName,
+			Description:  fl.InIfDescription,
+			Speed:        fl.InIfSpeed,
+			Connectivity: fl.InIfConnectivity,
+			Provider:     fl.InIfProvider,
+			Boundary:     strings.ToLower(fl.InIfBoundary.String()),
+		},
+		OutIf: outputInterfaceInfo{
+			Name:         fl.OutIfName,
+			Description:  fl.OutIfDescription,
+			Speed:        fl.OutIfSpeed,
+			Connectivity: fl.OutIfConnectivity,
+			Provider:     fl.OutIfProvider,
+			Boundary:     strings.ToLower(fl.OutIfBoundary.String()),
+		},
+	}
+}
+
+// exec executes the output rule with the provided environment.
+func (or *OutputRule) exec(env outputRuleEnvironment) (bool, error) {
+	result, err := expr.Run(or.program, env)
+	if err != nil {
+		return false, fmt.Errorf("unable to execute output rule %q: %w", or, err)
+	}
+	return result.(bool), nil
+}
+
+// UnmarshalText compiles an output rule.
+func (or *OutputRule) UnmarshalText(text []byte) error {
+	program, err := expr.Compile(string(text),
+		expr.Env(outputRuleEnvironment{}),
+		expr.AsBool())
+	if err != nil {
+		return fmt.Errorf("cannot compile output rule %q: %w", string(text), err)
+	}
+	or.program = program
+	return nil
+}
+
+// String turns an output rule into a string
+func (or OutputRule) String() string {
+	return or.program.Source.Content()
+}
+
+// MarshalText turns an output rule into a string
+func (or OutputRule) MarshalText() ([]byte, error) {
+	return []byte(or.String()), nil
+}
+
+// output is an additional output for flows.
+type output struct {
+	config   OutputConfiguration
+	topic    string
+	producer sarama.AsyncProducer
+}
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kafka
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/inlet/flow"
+	"akvorado/inlet/flow/decoder"
+)
+
+func TestOutputRule(t *testing.T) {
+	fl := &flow.Message{
+		ExporterAddress: net.ParseIP("192.0.2.142"),
+		ExporterName:    "edge1.example.com",
+		ExporterTenant:  "alfred",
+		InIfName:        "Gi0/0/0",
+		InIfProvider:    "telia",
+		InIfBoundary:    decoder.FlowMessage_EXTERNAL,
+		OutIfSpeed:      1000,
+	}
+	cases := []struct {
+		Rule          string
+		Expected      bool
+		ExpectedError bool
+	}{
+		{`true`, true, false},
+		{`Exporter.Tenant == "alfred"`, true, false},
+		{`Exporter.Tenant == "batman"`, false, false},
+		{`Exporter.IP startsWith "192.0.2."`, true, false},
+		{`InIf.Boundary == "external" && InIf.Provider == "telia"`, true, false},
+		{`OutIf.Boundary == "external"`, false, false},
+		{`OutIf.Speed >= 1000`, true, false},
+		{`Exporter.Name matches "^core"`, false, false},
+		{`Exporter.Unknown == "alfred"`, false, true},
+		{`Exporter.Tenant`, false, true},
+	}
+	env := newOutputRuleEnvironment(fl)
+	for _, tc := range cases {
+		var rule OutputRule
+		err := rule.UnmarshalText([]byte(tc.Rule))
+		if err != nil && !tc.ExpectedError {
+			t.Errorf("UnmarshalText(%q) error:\n%+v", tc.Rule, err)
+			continue
+		}
+		if err == nil && tc.ExpectedError {
+			t.Errorf("UnmarshalText(%q) did not error", tc.Rule)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if rule.String() != tc.Rule {
+			t.Errorf("String() == %q but expected %q", rule.String(), tc.Rule)
+		}
+		got, err := rule.exec(env)
+		if err != nil {
+			t.Errorf("exec(%q) error:\n%+v", tc.Rule, err)
+			continue
+		}
+		if got != tc.Expected {
+			t.Errorf("exec(%q) == %v but expected %v", tc.Rule, got, tc.Expected)
+		}
+	}
+}
+
+func TestKafkaOutputs(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.Outputs = []OutputConfiguration{
+		{Topic: "flows-alfred", Exclusive: true},
+		{Topic: "flows-external"},
+	}
+	if err := configuration.Outputs[0].Match.UnmarshalText([]byte(`Exporter.Tenant == "alfred"`)); err != nil {
+		t.Fatalf("UnmarshalText() error:\n%+v", err)
+	}
+	if err := configuration.Outputs[1].Match.UnmarshalText([]byte(`InIf.Boundary == "external"`)); err != nil {
+		t.Fatalf("UnmarshalText() error:\n%+v", err)
+	}
+	c, mockProducer := NewMock(t, r, configuration)
+
+	cases := []struct {
+		Flow           *flow.Message
+		ExpectedTopics []string
+	}{
+		{nil, []string{"flows"}},
+		{&flow.Message{ExporterTenant: "batman"}, []string{"flows"}},
+		{&flow.Message{ExporterTenant: "alfred"}, []string{"flows-alfred"}},
+		{
+			&flow.Message{InIfBoundary: decoder.FlowMessage_EXTERNAL},
+			[]string{"flows-external", "flows"},
+		}, {
+			&flow.Message{ExporterTenant: "alfred", InIfBoundary: decoder.FlowMessage_EXTERNAL},
+			[]string{"flows-alfred", "flows-external"},
+		},
+	}
+	for _, tc := range cases {
+		topics := make(chan string, len(tc.ExpectedTopics))
+		for range tc.ExpectedTopics {
+			mockProducer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(got *sarama.ProducerMessage) error {
+				topics <- got.Topic
+				return nil
+			})
+		}
+		c.Send("127.0.0.1", []byte("hello world!"), tc.Flow)
+		for _, expected := range tc.ExpectedTopics {
+			expected = fmt.Sprintf("%s-v%d", expected, flow.CurrentSchemaVersion)
+			select {
+			case got := <-topics:
+				if got != expected {
+					t.Errorf("Send(%v) sent to %q but expected %q", tc.Flow, got, expected)
+				}
+			case <-time.After(1 * time.Second):
+				t.Fatalf("Send(%v) did not send to %q", tc.Flow, expected)
+			}
+		}
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_output_")
+	expectedMetrics := map[string]string{
+		`messages_total{output="flows-alfred"}`:   "2",
+		`messages_total{output="flows-external"}`: "2",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestKafkaOutputWithoutRule(t *testing.T) {
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration()
+	configuration.Outputs = []OutputConfiguration{{Topic: "flows-alfred"}}
+	if _, err := New(r, configuration, Dependencies{}); err == nil {
+		t.Fatal("New() did not error")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/Shopify/sarama"
+	gometrics "github.com/rcrowley/go-metrics"
 	"gopkg.in/tomb.v2"
 
 	"akvorado/common/daemon"
@@ -28,9 +29,11 @@ type Component struct {
 	config Configuration
 
 	kafkaTopic          string
+	kafkaCountersTopic  string
 	kafkaConfig         *sarama.Config
 	kafkaProducer       sarama.AsyncProducer
-	createKafkaProducer func() (sarama.AsyncProducer, error)
+	createKafkaProducer func([]string, *sarama.Config) (sarama.AsyncProducer, error)
+	outputs             []output
 	metrics             metrics
 }
 
@@ -64,13 +67,21 @@ func New(reporter *reporter.Reporter, configuration Configuration, dependencies
 		d:      &dependencies,
 		config: configuration,
 
-		kafkaConfig: kafkaConfig,
-		kafkaTopic:  fmt.Sprintf("%s-v%d", configuration.Topic, flow.CurrentSchemaVersion),
+		kafkaConfig:        kafkaConfig,
+		kafkaTopic:         fmt.Sprintf("%s-v%d", configuration.Topic, flow.CurrentSchemaVersion),
+		kafkaCountersTopic: fmt.Sprintf("%s-counters", configuration.Topic),
 	}
-	c.initMetrics()
-	c.createKafkaProducer = func() (sarama.AsyncProducer, error) {
-		return sarama.NewAsyncProducer(c.config.Brokers, c.kafkaConfig)
+	for idx, outputConfiguration := range configuration.Outputs {
+		if outputConfiguration.Match.program == nil {
+			return nil, fmt.Errorf("missing match rule for output %d", idx)
+		}
+		c.outputs = append(c.outputs, output{
+			config: outputConfiguration,
+			topic:  fmt.Sprintf("%s-v%d", outputConfiguration.Topic, flow.CurrentSchemaVersion),
+		})
 	}
+	c.initMetrics()
+	c.createKafkaProducer = sarama.NewAsyncProducer
 	c.d.Daemon.Track(&c.t, "inlet/kafka")
 	return &c, nil
 }
@@ -81,19 +92,45 @@ func (c *Component) Start() error {
 	kafka.GlobalKafkaLogger.Register(c.r)
 
 	// Create producer
-	kafkaProducer, err := c.createKafkaProducer()
+	kafkaProducer, err := c.startProducer(c.config.Brokers, c.kafkaConfig)
+	if err != nil {
+		return err
+	}
+	c.kafkaProducer = kafkaProducer
+
+	// Create producers for outputs using their own brokers. Each of
+	// them gets its own metric registry as broker IDs may collide
+	// between clusters.
+	for idx := range c.outputs {
+		if len(c.outputs[idx].config.Brokers) == 0 {
+			c.outputs[idx].producer = kafkaProducer
+			continue
+		}
+		kafkaConfig := *c.kafkaConfig
+		kafkaConfig.MetricRegistry = gometrics.NewRegistry()
+		producer, err := c.startProducer(c.outputs[idx].config.Brokers, &kafkaConfig)
+		if err != nil {
+			return err
+		}
+		c.outputs[idx].producer = producer
+	}
+	return nil
+}
+
+// startProducer creates a new producer and starts the goroutine
+// logging its errors.
+func (c *Component) startProducer(brokers []string, kafkaConfig *sarama.Config) (sarama.AsyncProducer, error) {
+	kafkaProducer, err := c.createKafkaProducer(brokers, kafkaConfig)
 	if err != nil {
 		c.r.Err(err).
-			Str("brokers", strings.Join(c.config.Brokers, ",")).
+			Str("brokers", strings.Join(brokers, ",")).
 			Msg("unable to create async producer")
-		return fmt.Errorf("unable to create Kafka async producer: %w", err)
+		return nil, fmt.Errorf("unable to create Kafka async producer: %w", err)
 	}
-	c.kafkaProducer = kafkaProducer
 
-	// Main loop
 	c.t.Go(func() error {
 		defer kafkaProducer.Close()
-		defer c.kafkaConfig.MetricRegistry.UnregisterAll()
+		defer kafkaConfig.MetricRegistry.UnregisterAll()
 		errLogger := c.r.Sample(reporter.BurstSampler(10*time.Second, 3))
 		for {
 			select {
@@ -110,7 +147,7 @@ func (c *Component) Start() error {
 			}
 		}
 	})
-	return nil
+	return kafkaProducer, nil
 }
 
 // Stop stops the Kafka component
@@ -124,15 +161,54 @@ func (c *Component) Stop() error {
 	return c.t.Wait()
 }
 
-// Send a message to Kafka.
-func (c *Component) Send(exporter string, payload []byte) {
+// Send a flow to Kafka. The flow is sent to the main topic and to the
+// outputs whose rule matches it. The flow may be nil, in this case,
+// the payload is only sent to the main topic.
+func (c *Component) Send(exporter string, payload []byte, fl *flow.Message) {
+	sendToMain := true
+	if fl != nil && len(c.outputs) > 0 {
+		env := newOutputRuleEnvironment(fl)
+		for _, output := range c.outputs {
+			ok, err := output.config.Match.exec(env)
+			if err != nil {
+				c.metrics.outputErrors.WithLabelValues(output.config.Topic).Inc()
+				continue
+			}
+			if !ok {
+				continue
+			}
+			c.metrics.outputMessagesSent.WithLabelValues(output.config.Topic).Inc()
+			c.send(output.producer, output.topic, exporter, payload)
+			if output.config.Exclusive {
+				sendToMain = false
+			}
+		}
+	}
+	if sendToMain {
+		c.send(c.kafkaProducer, c.kafkaTopic, exporter, payload)
+	}
+}
+
+// send sends a payload to the provided topic using a random key.
+func (c *Component) send(producer sarama.AsyncProducer, topic string, exporter string, payload []byte) {
 	c.metrics.bytesSent.WithLabelValues(exporter).Add(float64(len(payload)))
 	c.metrics.messagesSent.WithLabelValues(exporter).Inc()
 	key := make([]byte, 4)
 	binary.BigEndian.PutUint32(key, rand.Uint32())
-	c.kafkaProducer.Input() <- &sarama.ProducerMessage{
-		Topic: c.kafkaTopic,
+	producer.Input() <- &sarama.ProducerMessage{
+		Topic: topic,
 		Key:   sarama.ByteEncoder(key),
 		Value: sarama.ByteEncoder(payload),
 	}
 }
+
+// SendCounters sends interface counters to Kafka.
+func (c *Component) SendCounters(exporter string, payload []byte) {
+	c.metrics.countersBytesSent.WithLabelValues(exporter).Add(float64(len(payload)))
+	c.metrics.countersMessagesSent.WithLabelValues(exporter).Inc()
+	c.kafkaProducer.Input() <- &sarama.ProducerMessage{
+		Topic: c.kafkaCountersTopic,
+		Key:   sarama.StringEncoder(exporter),
+		Value: sarama.ByteEncoder(payload),
+	}
+}
@@ -37,7 +37,7 @@ func TestKafka(t *testing.T) {
 		}
 		return nil
 	})
-	c.Send("127.0.0.1", []byte("hello world!"))
+	c.Send("127.0.0.1", []byte("hello world!"), nil)
 	select {
 	case <-received:
 	case <-time.After(1 * time.Second):
@@ -46,7 +46,7 @@ func TestKafka(t *testing.T) {
 
 	// Another but with a fail
 	mockProducer.ExpectInputAndFail(errors.New("noooo"))
-	c.Send("127.0.0.1", []byte("goodbye world!"))
+	c.Send("127.0.0.1", []byte("goodbye world!"), nil)
 
 	time.Sleep(10 * time.Millisecond)
 	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_")
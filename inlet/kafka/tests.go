@@ -25,11 +25,15 @@ func NewMock(t *testing.T, reporter *reporter.Reporter, configuration Configurat
 		t.Fatalf("New() error:\n%+v", err)
 	}
 
-	// Use a mocked Kafka producer
+	// Use a mocked Kafka producer. Only the producer for the main
+	// brokers is returned.
 	var mockProducer *mocks.AsyncProducer
-	c.createKafkaProducer = func() (sarama.AsyncProducer, error) {
-		mockProducer = mocks.NewAsyncProducer(t, c.kafkaConfig)
-		return mockProducer, nil
+	c.createKafkaProducer = func(_ []string, kafkaConfig *sarama.Config) (sarama.AsyncProducer, error) {
+		producer := mocks.NewAsyncProducer(t, kafkaConfig)
+		if mockProducer == nil {
+			mockProducer = producer
+		}
+		return producer, nil
 	}
 
 	helpers.StartStop(t, c)
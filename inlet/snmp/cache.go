@@ -27,7 +27,7 @@ var (
 	// ErrCacheVersion is triggered when loading a cache from an incompatible version
 	ErrCacheVersion = errors.New("SNMP cache version mismatch")
 	// cacheCurrentVersionNumber is the current version of the on-disk cache format
-	cacheCurrentVersionNumber = 9
+	cacheCurrentVersionNumber = 11
 )
 
 // snmpCache represents the SNMP cache.
@@ -49,15 +49,26 @@ type snmpCache struct {
 // cachedExporter represents information about a exporter. It includes
 // the mapping from ifIndex to interfaces.
 type cachedExporter struct {
-	Name       string
+	Exporter
 	Interfaces map[uint]*cachedInterface
 }
 
+// Exporter contains the information about an exporter.
+type Exporter struct {
+	Name        string
+	Description string
+	ObjectID    string
+	Location    string
+}
+
 // Interface contains the information about an interface.
 type Interface struct {
 	Name        string
 	Description string
 	Speed       uint
+	Type        uint
+	AdminStatus uint
+	VRF         string
 }
 
 // cachedInterface contains the information about a cached interface.
@@ -113,33 +124,33 @@ func newSNMPCache(r *reporter.Reporter, clock clock.Clock) *snmpCache {
 }
 
 // Lookup will perform a lookup of the cache. It returns the exporter
-// name as well as the requested interface.
-func (sc *snmpCache) Lookup(ip netip.Addr, ifIndex uint) (string, Interface, error) {
+// information as well as the requested interface.
+func (sc *snmpCache) Lookup(ip netip.Addr, ifIndex uint) (Exporter, Interface, error) {
 	return sc.lookup(ip, ifIndex, true)
 }
 
-func (sc *snmpCache) lookup(ip netip.Addr, ifIndex uint, touchAccess bool) (string, Interface, error) {
+func (sc *snmpCache) lookup(ip netip.Addr, ifIndex uint, touchAccess bool) (Exporter, Interface, error) {
 	sc.cacheLock.RLock()
 	defer sc.cacheLock.RUnlock()
 	exporter, ok := sc.cache[ip]
 	if !ok {
 		sc.metrics.cacheMiss.Inc()
-		return "", Interface{}, ErrCacheMiss
+		return Exporter{}, Interface{}, ErrCacheMiss
 	}
 	iface, ok := exporter.Interfaces[ifIndex]
 	if !ok {
 		sc.metrics.cacheMiss.Inc()
-		return "", Interface{}, ErrCacheMiss
+		return Exporter{}, Interface{}, ErrCacheMiss
 	}
 	sc.metrics.cacheHit.Inc()
 	if touchAccess {
 		atomic.StoreInt64(&iface.LastAccessed, sc.clock.Now().Unix())
 	}
-	return exporter.Name, iface.Interface, nil
+	return exporter.Exporter, iface.Interface, nil
 }
 
 // Put a new entry in the cache.
-func (sc *snmpCache) Put(ip netip.Addr, exporterName string, ifIndex uint, iface Interface) {
+func (sc *snmpCache) Put(ip netip.Addr, exporterInfo Exporter, ifIndex uint, iface Interface) {
 	sc.cacheLock.Lock()
 	defer sc.cacheLock.Unlock()
 
@@ -154,7 +165,7 @@ func (sc *snmpCache) Put(ip netip.Addr, exporterName string, ifIndex uint, iface
 		exporter = &cachedExporter{Interfaces: make(map[uint]*cachedInterface)}
 		sc.cache[ip] = exporter
 	}
-	exporter.Name = exporterName
+	exporter.Exporter = exporterInfo
 	exporter.Interfaces[ifIndex] = &ciface
 }
 
@@ -31,17 +31,17 @@ func setupTestCache(t *testing.T) (*reporter.Reporter, *clock.Mock, *snmpCache)
 }
 
 type answer struct {
-	ExporterName string
-	Interface    Interface
-	Err          error
+	Exporter  Exporter
+	Interface Interface
+	Err       error
 }
 
 func expectCacheLookup(t *testing.T, sc *snmpCache, exporterIP string, ifIndex uint, expected answer) {
 	t.Helper()
 	ip := netip.MustParseAddr(exporterIP)
 	ip = netip.AddrFrom16(ip.As16())
-	gotExporterName, gotInterface, err := sc.lookup(ip, ifIndex, false)
-	got := answer{gotExporterName, gotInterface, err}
+	gotExporter, gotInterface, err := sc.lookup(ip, ifIndex, false)
+	got := answer{gotExporter, gotInterface, err}
 	if diff := helpers.Diff(got, expected); diff != "" {
 		t.Errorf("Lookup() (-got, +want):\n%s", diff)
 	}
@@ -66,10 +66,10 @@ func TestGetEmpty(t *testing.T) {
 
 func TestSimpleLookup(t *testing.T) {
 	r, _, sc := setupTestCache(t)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 676, Interface{Name: "Gi0/0/0/1", Description: "Transit", Speed: 1000})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 676, Interface{Name: "Gi0/0/0/1", Description: "Transit", Speed: 1000})
 	expectCacheLookup(t, sc, "127.0.0.1", 676, answer{
-		ExporterName: "localhost",
-		Interface:    Interface{Name: "Gi0/0/0/1", Description: "Transit", Speed: 1000}})
+		Exporter:  Exporter{Name: "localhost"},
+		Interface: Interface{Name: "Gi0/0/0/1", Description: "Transit", Speed: 1000}})
 	expectCacheLookup(t, sc, "127.0.0.1", 787, answer{Err: ErrCacheMiss})
 	expectCacheLookup(t, sc, "127.0.0.2", 676, answer{Err: ErrCacheMiss})
 
@@ -88,46 +88,46 @@ func TestSimpleLookup(t *testing.T) {
 
 func TestExpire(t *testing.T) {
 	r, clock, sc := setupTestCache(t)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
 	clock.Add(10 * time.Minute)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost2", 678, Interface{Name: "Gi0/0/0/2", Description: "Peering"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost2"}, 678, Interface{Name: "Gi0/0/0/2", Description: "Peering"})
 	clock.Add(10 * time.Minute)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.2"), "localhost3", 678, Interface{Name: "Gi0/0/0/1", Description: "IX"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.2"), Exporter{Name: "localhost3"}, 678, Interface{Name: "Gi0/0/0/1", Description: "IX"})
 	clock.Add(10 * time.Minute)
 	sc.Expire(time.Hour)
 	expectCacheLookup(t, sc, "127.0.0.1", 676, answer{
-		ExporterName: "localhost2",
-		Interface:    Interface{Name: "Gi0/0/0/1", Description: "Transit"}})
+		Exporter:  Exporter{Name: "localhost2"},
+		Interface: Interface{Name: "Gi0/0/0/1", Description: "Transit"}})
 	expectCacheLookup(t, sc, "127.0.0.1", 678, answer{
-		ExporterName: "localhost2",
-		Interface:    Interface{Name: "Gi0/0/0/2", Description: "Peering"}})
+		Exporter:  Exporter{Name: "localhost2"},
+		Interface: Interface{Name: "Gi0/0/0/2", Description: "Peering"}})
 	expectCacheLookup(t, sc, "127.0.0.2", 678, answer{
-		ExporterName: "localhost3",
-		Interface:    Interface{Name: "Gi0/0/0/1", Description: "IX"}})
+		Exporter:  Exporter{Name: "localhost3"},
+		Interface: Interface{Name: "Gi0/0/0/1", Description: "IX"}})
 	sc.Expire(29 * time.Minute)
 	expectCacheLookup(t, sc, "127.0.0.1", 676, answer{Err: ErrCacheMiss})
 	expectCacheLookup(t, sc, "127.0.0.1", 678, answer{
-		ExporterName: "localhost2",
-		Interface:    Interface{Name: "Gi0/0/0/2", Description: "Peering"}})
+		Exporter:  Exporter{Name: "localhost2"},
+		Interface: Interface{Name: "Gi0/0/0/2", Description: "Peering"}})
 	expectCacheLookup(t, sc, "127.0.0.2", 678, answer{
-		ExporterName: "localhost3",
-		Interface:    Interface{Name: "Gi0/0/0/1", Description: "IX"}})
+		Exporter:  Exporter{Name: "localhost3"},
+		Interface: Interface{Name: "Gi0/0/0/1", Description: "IX"}})
 	sc.Expire(19 * time.Minute)
 	expectCacheLookup(t, sc, "127.0.0.1", 676, answer{Err: ErrCacheMiss})
 	expectCacheLookup(t, sc, "127.0.0.1", 678, answer{Err: ErrCacheMiss})
 	expectCacheLookup(t, sc, "127.0.0.2", 678, answer{
-		ExporterName: "localhost3",
-		Interface:    Interface{Name: "Gi0/0/0/1", Description: "IX"}})
+		Exporter:  Exporter{Name: "localhost3"},
+		Interface: Interface{Name: "Gi0/0/0/1", Description: "IX"}})
 	sc.Expire(9 * time.Minute)
 	expectCacheLookup(t, sc, "127.0.0.1", 676, answer{Err: ErrCacheMiss})
 	expectCacheLookup(t, sc, "127.0.0.1", 678, answer{Err: ErrCacheMiss})
 	expectCacheLookup(t, sc, "127.0.0.2", 678, answer{Err: ErrCacheMiss})
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
 	clock.Add(10 * time.Minute)
 	sc.Expire(19 * time.Minute)
 	expectCacheLookup(t, sc, "127.0.0.1", 676, answer{
-		ExporterName: "localhost",
-		Interface:    Interface{Name: "Gi0/0/0/1", Description: "Transit"}})
+		Exporter:  Exporter{Name: "localhost"},
+		Interface: Interface{Name: "Gi0/0/0/1", Description: "Transit"}})
 
 	gotMetrics := r.GetMetrics("akvorado_inlet_snmp_cache_")
 	expectedMetrics := map[string]string{
@@ -144,11 +144,11 @@ func TestExpire(t *testing.T) {
 
 func TestExpireRefresh(t *testing.T) {
 	_, clock, sc := setupTestCache(t)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
 	clock.Add(10 * time.Minute)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 678, Interface{Name: "Gi0/0/0/2", Description: "Peering"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 678, Interface{Name: "Gi0/0/0/2", Description: "Peering"})
 	clock.Add(10 * time.Minute)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.2"), "localhost2", 678, Interface{Name: "Gi0/0/0/1", Description: "IX"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.2"), Exporter{Name: "localhost2"}, 678, Interface{Name: "Gi0/0/0/1", Description: "IX"})
 	clock.Add(10 * time.Minute)
 
 	// Refresh first entry
@@ -157,21 +157,21 @@ func TestExpireRefresh(t *testing.T) {
 
 	sc.Expire(29 * time.Minute)
 	expectCacheLookup(t, sc, "127.0.0.1", 676, answer{
-		ExporterName: "localhost",
-		Interface:    Interface{Name: "Gi0/0/0/1", Description: "Transit"}})
+		Exporter:  Exporter{Name: "localhost"},
+		Interface: Interface{Name: "Gi0/0/0/1", Description: "Transit"}})
 	expectCacheLookup(t, sc, "127.0.0.1", 678, answer{Err: ErrCacheMiss})
 	expectCacheLookup(t, sc, "127.0.0.2", 678, answer{
-		ExporterName: "localhost2",
-		Interface:    Interface{Name: "Gi0/0/0/1", Description: "IX"}})
+		Exporter:  Exporter{Name: "localhost2"},
+		Interface: Interface{Name: "Gi0/0/0/1", Description: "IX"}})
 }
 
 func TestWouldExpire(t *testing.T) {
 	_, clock, sc := setupTestCache(t)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
 	clock.Add(10 * time.Minute)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 678, Interface{Name: "Gi0/0/0/2", Description: "Peering"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 678, Interface{Name: "Gi0/0/0/2", Description: "Peering"})
 	clock.Add(10 * time.Minute)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.2"), "localhost2", 678, Interface{Name: "Gi0/0/0/1", Description: "IX"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.2"), Exporter{Name: "localhost2"}, 678, Interface{Name: "Gi0/0/0/1", Description: "IX"})
 	clock.Add(10 * time.Minute)
 	// Refresh
 	sc.Lookup(netip.MustParseAddr("::ffff:127.0.0.1"), 676)
@@ -217,14 +217,14 @@ func TestWouldExpire(t *testing.T) {
 
 func TestNeedUpdates(t *testing.T) {
 	_, clock, sc := setupTestCache(t)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
 	clock.Add(10 * time.Minute)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 678, Interface{Name: "Gi0/0/0/2", Description: "Peering"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 678, Interface{Name: "Gi0/0/0/2", Description: "Peering"})
 	clock.Add(10 * time.Minute)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.2"), "localhost2", 678, Interface{Name: "Gi0/0/0/1", Description: "IX"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.2"), Exporter{Name: "localhost2"}, 678, Interface{Name: "Gi0/0/0/1", Description: "IX"})
 	clock.Add(10 * time.Minute)
 	// Refresh
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost1", 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost1"}, 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
 	clock.Add(10 * time.Minute)
 
 	cases := []struct {
@@ -275,11 +275,11 @@ func TestLoadNotExist(t *testing.T) {
 
 func TestSaveLoad(t *testing.T) {
 	_, clock, sc := setupTestCache(t)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
 	clock.Add(10 * time.Minute)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 678, Interface{Name: "Gi0/0/0/2", Description: "Peering"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 678, Interface{Name: "Gi0/0/0/2", Description: "Peering"})
 	clock.Add(10 * time.Minute)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.2"), "localhost2", 678, Interface{Name: "Gi0/0/0/1", Description: "IX", Speed: 1000})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.2"), Exporter{Name: "localhost2"}, 678, Interface{Name: "Gi0/0/0/1", Description: "IX", Speed: 1000})
 
 	target := filepath.Join(t.TempDir(), "cache")
 	if err := sc.Save(target); err != nil {
@@ -295,16 +295,16 @@ func TestSaveLoad(t *testing.T) {
 	sc.Expire(29 * time.Minute)
 	expectCacheLookup(t, sc, "127.0.0.1", 676, answer{Err: ErrCacheMiss})
 	expectCacheLookup(t, sc, "127.0.0.1", 678, answer{
-		ExporterName: "localhost",
-		Interface:    Interface{Name: "Gi0/0/0/2", Description: "Peering"}})
+		Exporter:  Exporter{Name: "localhost"},
+		Interface: Interface{Name: "Gi0/0/0/2", Description: "Peering"}})
 	expectCacheLookup(t, sc, "127.0.0.2", 678, answer{
-		ExporterName: "localhost2",
-		Interface:    Interface{Name: "Gi0/0/0/1", Description: "IX", Speed: 1000}})
+		Exporter:  Exporter{Name: "localhost2"},
+		Interface: Interface{Name: "Gi0/0/0/1", Description: "IX", Speed: 1000}})
 }
 
 func TestLoadMismatchVersion(t *testing.T) {
 	_, _, sc := setupTestCache(t)
-	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), "localhost", 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
+	sc.Put(netip.MustParseAddr("::ffff:127.0.0.1"), Exporter{Name: "localhost"}, 676, Interface{Name: "Gi0/0/0/1", Description: "Transit"})
 	target := filepath.Join(t.TempDir(), "cache")
 
 	cacheCurrentVersionNumber++
@@ -347,7 +347,7 @@ func TestConcurrentOperations(t *testing.T) {
 				ip := rand.Intn(10)
 				iface := rand.Intn(100)
 				sc.Put(netip.MustParseAddr(fmt.Sprintf("::ffff:127.0.0.%d", ip)),
-					fmt.Sprintf("localhost%d", ip),
+					Exporter{Name: fmt.Sprintf("localhost%d", ip)},
 					uint(iface), Interface{Name: "Gi0/0/0/1", Description: "Transit"})
 				select {
 				case <-done:
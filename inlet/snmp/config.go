@@ -32,6 +32,12 @@ type Configuration struct {
 	PollerTimeout time.Duration `validate:"min=100ms"`
 	// PollerCoalesce tells how many requests can be contained inside a single SNMP PDU
 	PollerCoalesce int `validate:"min=0"`
+	// PollerVRF tells if the poller should walk the MPLS L3VPN MIB to
+	// get the VRF of each interface
+	PollerVRF bool
+	// PollerVRFRefresh tells how often the VRF of the interfaces of
+	// an exporter should be refreshed
+	PollerVRFRefresh time.Duration `validate:"min=1m"`
 	// Workers define the number of workers used to poll SNMP
 	Workers int `validate:"min=1"`
 
@@ -65,6 +71,7 @@ func DefaultConfiguration() Configuration {
 		PollerRetries:      1,
 		PollerTimeout:      time.Second,
 		PollerCoalesce:     10,
+		PollerVRFRefresh:   time.Hour,
 		Workers:            1,
 
 		Communities: helpers.MustNewSubnetMap(map[string]string{
@@ -8,6 +8,8 @@ import (
 	"errors"
 	"fmt"
 	"net/netip"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -31,7 +33,10 @@ type realPoller struct {
 	pendingRequests     map[string]struct{}
 	pendingRequestsLock sync.Mutex
 	errLogger           reporter.Logger
-	put                 func(exporterIP netip.Addr, exporterName string, ifIndex uint, iface Interface)
+	put                 func(exporterIP netip.Addr, exporter Exporter, ifIndex uint, iface Interface)
+
+	vrfCache     map[string]*vrfCacheEntry
+	vrfCacheLock sync.Mutex
 
 	metrics struct {
 		pendingRequests reporter.GaugeFunc
@@ -39,18 +44,34 @@ type realPoller struct {
 		failures        *reporter.CounterVec
 		retries         *reporter.CounterVec
 		times           *reporter.SummaryVec
+		vrfWalks        *reporter.CounterVec
 	}
 }
 
+// vrfCacheEntry contains the VRF of each interface of an exporter.
+// The lock is held while refreshing the entry.
+type vrfCacheEntry struct {
+	lock    sync.Mutex
+	updated time.Time
+	vrfs    map[uint]string
+}
+
 type pollerConfig struct {
 	Retries            int
 	Timeout            time.Duration
 	Communities        *helpers.SubnetMap[string]
 	SecurityParameters *helpers.SubnetMap[SecurityParameters]
+	VRF                bool
+	VRFRefreshInterval time.Duration
 }
 
+// mplsL3VpnIfConfRowStatus is the OID of the column of
+// mplsL3VpnIfConfTable used to find the VRF of an interface. This
+// table is indexed by the VRF name and the ifIndex.
+const mplsL3VpnIfConfRowStatus = "1.3.6.1.2.1.10.166.11.1.2.1.1.5"
+
 // newPoller creates a new SNMP poller.
-func newPoller(r *reporter.Reporter, config pollerConfig, clock clock.Clock, put func(netip.Addr, string, uint, Interface)) *realPoller {
+func newPoller(r *reporter.Reporter, config pollerConfig, clock clock.Clock, put func(netip.Addr, Exporter, uint, Interface)) *realPoller {
 	p := &realPoller{
 		r:               r,
 		config:          config,
@@ -58,6 +79,7 @@ func newPoller(r *reporter.Reporter, config pollerConfig, clock clock.Clock, put
 		pendingRequests: make(map[string]struct{}),
 		errLogger:       r.Sample(reporter.BurstSampler(10*time.Second, 3)),
 		put:             put,
+		vrfCache:        make(map[string]*vrfCacheEntry),
 	}
 	p.metrics.pendingRequests = r.GaugeFunc(
 		reporter.GaugeOpts{
@@ -89,6 +111,11 @@ func newPoller(r *reporter.Reporter, config pollerConfig, clock clock.Clock, put
 			Help:       "Time to successfully poll for values.",
 			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
 		}, []string{"exporter"})
+	p.metrics.vrfWalks = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "poller_vrf_walks",
+			Help: "Number of walks of the VRF table.",
+		}, []string{"exporter"})
 	return p
 }
 
@@ -169,12 +196,19 @@ func (p *realPoller) Poll(ctx context.Context, exporter, agent netip.Addr, port
 		p.errLogger.Err(err).Str("exporter", exporterStr).Msg("unable to connect")
 	}
 	start := p.clock.Now()
-	requests := []string{"1.3.6.1.2.1.1.5.0"}
+	requests := []string{
+		"1.3.6.1.2.1.1.5.0", // sysName
+		"1.3.6.1.2.1.1.1.0", // sysDescr
+		"1.3.6.1.2.1.1.2.0", // sysObjectID
+		"1.3.6.1.2.1.1.6.0", // sysLocation
+	}
 	for _, ifIndex := range ifIndexes {
 		moreRequests := []string{
 			fmt.Sprintf("1.3.6.1.2.1.2.2.1.2.%d", ifIndex),     // ifDescr
 			fmt.Sprintf("1.3.6.1.2.1.31.1.1.1.18.%d", ifIndex), // ifAlias
 			fmt.Sprintf("1.3.6.1.2.1.31.1.1.1.15.%d", ifIndex), // ifSpeed
+			fmt.Sprintf("1.3.6.1.2.1.2.2.1.3.%d", ifIndex),     // ifType
+			fmt.Sprintf("1.3.6.1.2.1.2.2.1.7.%d", ifIndex),     // ifAdminStatus
 		}
 		requests = append(requests, moreRequests...)
 	}
@@ -203,6 +237,8 @@ func (p *realPoller) Poll(ctx context.Context, exporter, agent netip.Addr, port
 		switch result.Variables[idx].Type {
 		case gosnmp.OctetString:
 			*target = string(result.Variables[idx].Value.([]byte))
+		case gosnmp.ObjectIdentifier:
+			*target = strings.TrimPrefix(result.Variables[idx].Value.(string), ".")
 		case gosnmp.NoSuchInstance, gosnmp.NoSuchObject:
 			if mandatory {
 				p.metrics.failures.WithLabelValues(exporterStr, fmt.Sprintf("%s missing", what)).Inc()
@@ -218,6 +254,8 @@ func (p *realPoller) Poll(ctx context.Context, exporter, agent netip.Addr, port
 		switch result.Variables[idx].Type {
 		case gosnmp.Gauge32:
 			*target = result.Variables[idx].Value.(uint)
+		case gosnmp.Integer:
+			*target = uint(result.Variables[idx].Value.(int))
 		case gosnmp.NoSuchInstance, gosnmp.NoSuchObject:
 			if mandatory {
 				p.metrics.failures.WithLabelValues(exporterStr, fmt.Sprintf("%s missing", what)).Inc()
@@ -230,16 +268,35 @@ func (p *realPoller) Poll(ctx context.Context, exporter, agent netip.Addr, port
 		return true
 	}
 	var (
-		sysNameVal string
-		ifDescrVal = "unknown"
-		ifAliasVal string
-		ifSpeedVal uint
+		sysNameVal       string
+		sysDescrVal      string
+		sysObjectIDVal   string
+		sysLocationVal   string
+		ifDescrVal       = "unknown"
+		ifAliasVal       string
+		ifSpeedVal       uint
+		ifTypeVal        uint
+		ifAdminStatusVal uint
 	)
 	if !processStr(0, "sysname", &sysNameVal, true) {
 		return errors.New("unable to get sysName")
 	}
-	for idx := 1; idx < len(requests)-2; idx += 3 {
-		ifIndex := ifIndexes[(idx-1)/3]
+	// Other system information is optional
+	processStr(1, "sysdescr", &sysDescrVal, false)
+	processStr(2, "sysobjectid", &sysObjectIDVal, false)
+	processStr(3, "syslocation", &sysLocationVal, false)
+	exporterInfo := Exporter{
+		Name:        sysNameVal,
+		Description: sysDescrVal,
+		ObjectID:    sysObjectIDVal,
+		Location:    sysLocationVal,
+	}
+	var vrfs map[uint]string
+	if p.config.VRF {
+		vrfs = p.getVRFs(g, exporterStr)
+	}
+	for idx := 4; idx < len(requests)-4; idx += 5 {
+		ifIndex := ifIndexes[(idx-4)/5]
 		ok := true
 		if !processStr(idx, "ifdescr", &ifDescrVal, ifIndex > 0) {
 			ok = false
@@ -250,13 +307,20 @@ func (p *realPoller) Poll(ctx context.Context, exporter, agent netip.Addr, port
 		if !processUint(idx+2, "ifspeed", &ifSpeedVal, ifIndex > 0) {
 			ok = false
 		}
+		// ifType and ifAdminStatus are optional
+		ifTypeVal, ifAdminStatusVal = 0, 0
+		processUint(idx+3, "iftype", &ifTypeVal, false)
+		processUint(idx+4, "ifadminstatus", &ifAdminStatusVal, false)
 		if !ok {
 			continue
 		}
-		p.put(exporter, sysNameVal, ifIndex, Interface{
+		p.put(exporter, exporterInfo, ifIndex, Interface{
 			Name:        ifDescrVal,
 			Description: ifAliasVal,
 			Speed:       ifSpeedVal,
+			Type:        ifTypeVal,
+			AdminStatus: ifAdminStatusVal,
+			VRF:         vrfs[ifIndex],
 		})
 		p.metrics.successes.WithLabelValues(exporterStr).Inc()
 	}
@@ -265,6 +329,82 @@ func (p *realPoller) Poll(ctx context.Context, exporter, agent netip.Addr, port
 	return nil
 }
 
+// getVRFs returns the VRF of each interface of an exporter. The
+// mapping is cached and refreshed once per VRF refresh interval.
+func (p *realPoller) getVRFs(g *gosnmp.GoSNMP, exporterStr string) map[uint]string {
+	p.vrfCacheLock.Lock()
+	entry, ok := p.vrfCache[exporterStr]
+	if !ok {
+		entry = &vrfCacheEntry{}
+		p.vrfCache[exporterStr] = entry
+	}
+	p.vrfCacheLock.Unlock()
+
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	if entry.vrfs != nil && p.clock.Now().Sub(entry.updated) < p.config.VRFRefreshInterval {
+		return entry.vrfs
+	}
+	vrfs, err := p.pollVRFs(g, exporterStr)
+	if err != nil && entry.vrfs != nil {
+		// Keep the previous mapping
+		vrfs = entry.vrfs
+	}
+	entry.vrfs = vrfs
+	entry.updated = p.clock.Now()
+	return vrfs
+}
+
+// pollVRFs walks the MPLS L3VPN MIB to get the VRF of all the
+// interfaces of an exporter. Errors are not fatal as many exporters
+// do not support this MIB: an empty mapping is returned.
+func (p *realPoller) pollVRFs(g *gosnmp.GoSNMP, exporterStr string) (map[uint]string, error) {
+	result := map[uint]string{}
+	p.metrics.vrfWalks.WithLabelValues(exporterStr).Inc()
+	pdus, err := g.WalkAll(mplsL3VpnIfConfRowStatus)
+	if err != nil {
+		p.metrics.failures.WithLabelValues(exporterStr, "vrf walk").Inc()
+		p.errLogger.Err(err).Str("exporter", exporterStr).Msg("unable to walk VRF table")
+		return result, err
+	}
+	for _, pdu := range pdus {
+		vrf, ifIndex, ok := parseVRFIndex(strings.TrimPrefix(
+			strings.TrimPrefix(pdu.Name, "."),
+			mplsL3VpnIfConfRowStatus+"."))
+		if ok {
+			result[ifIndex] = vrf
+		}
+	}
+	return result, nil
+}
+
+// parseVRFIndex parses the index of an entry of mplsL3VpnIfConfTable.
+// The VRF name is encoded with its length first, followed by each
+// character. The ifIndex comes last.
+func parseVRFIndex(index string) (string, uint, bool) {
+	parts := strings.Split(index, ".")
+	if len(parts) < 2 {
+		return "", 0, false
+	}
+	length, err := strconv.ParseUint(parts[0], 10, 8)
+	if err != nil || len(parts) != int(length)+2 {
+		return "", 0, false
+	}
+	name := make([]byte, length)
+	for i := range name {
+		c, err := strconv.ParseUint(parts[i+1], 10, 8)
+		if err != nil {
+			return "", 0, false
+		}
+		name[i] = byte(c)
+	}
+	ifIndex, err := strconv.ParseUint(parts[len(parts)-1], 10, 32)
+	if err != nil {
+		return "", 0, false
+	}
+	return string(name), uint(ifIndex), true
+}
+
 type goSNMPLogger struct {
 	r *reporter.Reporter
 }
@@ -29,8 +29,10 @@ func TestPoller(t *testing.T) {
 		{
 			Description: "SNMPv2",
 			Config: pollerConfig{
-				Retries: 2,
-				Timeout: 100 * time.Millisecond,
+				Retries:            2,
+				Timeout:            100 * time.Millisecond,
+				VRF:                true,
+				VRFRefreshInterval: time.Hour,
 				Communities: helpers.MustNewSubnetMap(map[string]string{
 					"::/0": "private",
 				}),
@@ -38,8 +40,10 @@ func TestPoller(t *testing.T) {
 		}, {
 			Description: "SNMPv3",
 			Config: pollerConfig{
-				Retries: 2,
-				Timeout: 100 * time.Millisecond,
+				Retries:            2,
+				Timeout:            100 * time.Millisecond,
+				VRF:                true,
+				VRFRefreshInterval: time.Hour,
 				Communities: helpers.MustNewSubnetMap(map[string]string{
 					"::/0": "public",
 				}),
@@ -58,8 +62,10 @@ func TestPoller(t *testing.T) {
 			Description: "SNMPv3 no priv",
 			Skip:        "GoSNMPServer is broken with this configuration",
 			Config: pollerConfig{
-				Retries: 2,
-				Timeout: 100 * time.Millisecond,
+				Retries:            2,
+				Timeout:            100 * time.Millisecond,
+				VRF:                true,
+				VRFRefreshInterval: time.Hour,
 				Communities: helpers.MustNewSubnetMap(map[string]string{
 					"::/0": "public",
 				}),
@@ -84,10 +90,11 @@ func TestPoller(t *testing.T) {
 			r := reporter.NewMock(t)
 			clock := clock.NewMock()
 			config := tc.Config
-			p := newPoller(r, config, clock, func(exporterIP netip.Addr, exporterName string, ifIndex uint, iface Interface) {
-				got = append(got, fmt.Sprintf("%s %s %d %s %s %d",
-					exporterIP.Unmap().String(), exporterName,
-					ifIndex, iface.Name, iface.Description, iface.Speed))
+			p := newPoller(r, config, clock, func(exporterIP netip.Addr, exporter Exporter, ifIndex uint, iface Interface) {
+				got = append(got, fmt.Sprintf("%s %s %s %s %d %s %s %d %d %d %s",
+					exporterIP.Unmap().String(), exporter.Name, exporter.ObjectID, exporter.Location,
+					ifIndex, iface.Name, iface.Description, iface.Speed,
+					iface.Type, iface.AdminStatus, iface.VRF))
 			})
 
 			// Start a new SNMP server
@@ -119,6 +126,18 @@ func TestPoller(t *testing.T) {
 								OnGet: func() (interface{}, error) {
 									return "exporter62", nil
 								},
+							}, {
+								OID:  "1.3.6.1.2.1.1.2.0",
+								Type: gosnmp.ObjectIdentifier,
+								OnGet: func() (interface{}, error) {
+									return "1.3.6.1.4.1.9.1.1745", nil
+								},
+							}, {
+								OID:  "1.3.6.1.2.1.1.6.0",
+								Type: gosnmp.OctetString,
+								OnGet: func() (interface{}, error) {
+									return "paris", nil
+								},
 							}, {
 								OID:  "1.3.6.1.2.1.2.2.1.2.641",
 								Type: gosnmp.OctetString,
@@ -169,6 +188,38 @@ func TestPoller(t *testing.T) {
 								},
 							},
 							// ifAlias.643 missing
+							{
+								OID:  "1.3.6.1.2.1.2.2.1.3.641",
+								Type: gosnmp.Integer,
+								OnGet: func() (interface{}, error) {
+									return 161, nil
+								},
+							}, {
+								OID:  "1.3.6.1.2.1.2.2.1.3.642",
+								Type: gosnmp.Integer,
+								OnGet: func() (interface{}, error) {
+									return 6, nil
+								},
+							}, {
+								OID:  "1.3.6.1.2.1.2.2.1.7.641",
+								Type: gosnmp.Integer,
+								OnGet: func() (interface{}, error) {
+									return 1, nil
+								},
+							}, {
+								OID:  "1.3.6.1.2.1.2.2.1.7.642",
+								Type: gosnmp.Integer,
+								OnGet: func() (interface{}, error) {
+									return 2, nil
+								},
+							}, {
+								// VRF "customer" for ifIndex 642
+								OID:  "1.3.6.1.2.1.10.166.11.1.2.1.1.5.8.99.117.115.116.111.109.101.114.642",
+								Type: gosnmp.Integer,
+								OnGet: func() (interface{}, error) {
+									return 1, nil
+								},
+							},
 						},
 					},
 				},
@@ -198,20 +249,33 @@ func TestPoller(t *testing.T) {
 			p.Poll(context.Background(), lo, lo, uint16(port), []uint{0})
 			time.Sleep(50 * time.Millisecond)
 			if diff := helpers.Diff(got, []string{
-				`127.0.0.1 exporter62 641 Gi0/0/0/0 Transit 10000`,
-				`127.0.0.1 exporter62 642 Gi0/0/0/1 Peering 20000`,
-				`127.0.0.1 exporter62 0 unknown  0`,
+				`127.0.0.1 exporter62 1.3.6.1.4.1.9.1.1745 paris 641 Gi0/0/0/0 Transit 10000 161 1 `,
+				`127.0.0.1 exporter62 1.3.6.1.4.1.9.1.1745 paris 642 Gi0/0/0/1 Peering 20000 6 2 customer`,
+				`127.0.0.1 exporter62 1.3.6.1.4.1.9.1.1745 paris 0 unknown  0 0 0 `,
 			}); diff != "" {
 				t.Fatalf("Poll() (-got, +want):\n%s", diff)
 			}
 
-			gotMetrics := r.GetMetrics("akvorado_inlet_snmp_poller_", "failure_", "pending_", "success_")
+			gotMetrics := r.GetMetrics("akvorado_inlet_snmp_poller_", "failure_", "pending_", "success_", "vrf_")
 			expectedMetrics := map[string]string{
 				`failure_requests{error="ifalias missing",exporter="127.0.0.1"}`: "2", // 643+644
 				`failure_requests{error="ifdescr missing",exporter="127.0.0.1"}`: "1", // 644
 				`failure_requests{error="ifspeed missing",exporter="127.0.0.1"}`: "1", // 644
 				`pending_requests`:                       "0",
 				`success_requests{exporter="127.0.0.1"}`: "3", // 641+642+0
+				`vrf_walks{exporter="127.0.0.1"}`:        "1",
+			}
+			if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+				t.Fatalf("Metrics (-got, +want):\n%s", diff)
+			}
+
+			// VRF mapping should be refreshed after the refresh interval
+			clock.Add(2 * time.Hour)
+			p.Poll(context.Background(), lo, lo, uint16(port), []uint{642})
+			time.Sleep(50 * time.Millisecond)
+			gotMetrics = r.GetMetrics("akvorado_inlet_snmp_poller_", "vrf_")
+			expectedMetrics = map[string]string{
+				`vrf_walks{exporter="127.0.0.1"}`: "2",
 			}
 			if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
 				t.Fatalf("Metrics (-got, +want):\n%s", diff)
@@ -219,3 +283,30 @@ func TestPoller(t *testing.T) {
 		})
 	}
 }
+
+func TestParseVRFIndex(t *testing.T) {
+	cases := []struct {
+		Index           string
+		ExpectedVRF     string
+		ExpectedIfIndex uint
+		ExpectedOK      bool
+	}{
+		{"8.99.117.115.116.111.109.101.114.642", "customer", 642, true},
+		{"0.12", "", 12, true},
+		{"3.65.66.67", "", 0, false},
+		{"3.65.66.67.10.11", "", 0, false},
+		{"2.65.300.10", "", 0, false},
+		{"12", "", 0, false},
+	}
+	for _, tc := range cases {
+		vrf, ifIndex, ok := parseVRFIndex(tc.Index)
+		if ok != tc.ExpectedOK {
+			t.Errorf("parseVRFIndex(%q) ok == %v but expected %v", tc.Index, ok, tc.ExpectedOK)
+			continue
+		}
+		if vrf != tc.ExpectedVRF || ifIndex != tc.ExpectedIfIndex {
+			t.Errorf("parseVRFIndex(%q) == %q, %d but expected %q, %d",
+				tc.Index, vrf, ifIndex, tc.ExpectedVRF, tc.ExpectedIfIndex)
+		}
+	}
+}
@@ -92,6 +92,8 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 			Timeout:            configuration.PollerTimeout,
 			Communities:        configuration.Communities,
 			SecurityParameters: configuration.SecurityParameters,
+			VRF:                configuration.PollerVRF,
+			VRFRefreshInterval: configuration.PollerVRFRefresh,
 		}, dependencies.Clock, sc.Put),
 	}
 	c.d.Daemon.Track(&c.t, "inlet/snmp")
@@ -234,8 +236,8 @@ type lookupRequest struct {
 // Lookup for interface information for the provided exporter and ifIndex.
 // If the information is not in the cache, it will be polled, but
 // won't be returned immediately.
-func (c *Component) Lookup(exporterIP netip.Addr, ifIndex uint) (string, Interface, error) {
-	exporterName, iface, err := c.sc.Lookup(exporterIP, ifIndex)
+func (c *Component) Lookup(exporterIP netip.Addr, ifIndex uint) (Exporter, Interface, error) {
+	exporter, iface, err := c.sc.Lookup(exporterIP, ifIndex)
 	if errors.Is(err, ErrCacheMiss) {
 		req := lookupRequest{
 			ExporterIP: exporterIP,
@@ -247,7 +249,7 @@ func (c *Component) Lookup(exporterIP netip.Addr, ifIndex uint) (string, Interfa
 			c.metrics.pollerBusyCount.WithLabelValues(exporterIP.Unmap().String()).Inc()
 		}
 	}
-	return exporterName, iface, err
+	return exporter, iface, err
 }
 
 // Dispatch an incoming request to workers. May handle more than the
@@ -22,8 +22,8 @@ import (
 func expectSNMPLookup(t *testing.T, c *Component, exporter string, ifIndex uint, expected answer) {
 	t.Helper()
 	ip := netip.AddrFrom16(netip.MustParseAddr(exporter).As16())
-	gotExporterName, gotInterface, err := c.Lookup(ip, ifIndex)
-	got := answer{gotExporterName, gotInterface, err}
+	gotExporter, gotInterface, err := c.Lookup(ip, ifIndex)
+	got := answer{gotExporter, gotInterface, err}
 	if diff := helpers.Diff(got, expected); diff != "" {
 		t.Fatalf("Lookup() (-got, +want):\n%s", diff)
 	}
@@ -36,8 +36,8 @@ func TestLookup(t *testing.T) {
 	expectSNMPLookup(t, c, "127.0.0.1", 765, answer{Err: ErrCacheMiss})
 	time.Sleep(30 * time.Millisecond)
 	expectSNMPLookup(t, c, "127.0.0.1", 765, answer{
-		ExporterName: "127_0_0_1",
-		Interface:    Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
+		Exporter:  Exporter{Name: "127_0_0_1"},
+		Interface: Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
 	})
 }
 
@@ -55,8 +55,8 @@ func TestSNMPCommunities(t *testing.T) {
 	expectSNMPLookup(t, c, "127.0.0.1", 765, answer{Err: ErrCacheMiss})
 	time.Sleep(30 * time.Millisecond)
 	expectSNMPLookup(t, c, "127.0.0.1", 765, answer{
-		ExporterName: "127_0_0_1",
-		Interface:    Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
+		Exporter:  Exporter{Name: "127_0_0_1"},
+		Interface: Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
 	})
 
 	// Use "private", should not work
@@ -81,8 +81,8 @@ func TestComponentSaveLoad(t *testing.T) {
 		expectSNMPLookup(t, c, "127.0.0.1", 765, answer{Err: ErrCacheMiss})
 		time.Sleep(30 * time.Millisecond)
 		expectSNMPLookup(t, c, "127.0.0.1", 765, answer{
-			ExporterName: "127_0_0_1",
-			Interface:    Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
+			Exporter:  Exporter{Name: "127_0_0_1"},
+			Interface: Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
 		})
 	})
 
@@ -90,8 +90,8 @@ func TestComponentSaveLoad(t *testing.T) {
 		r := reporter.NewMock(t)
 		c := NewMock(t, r, configuration, Dependencies{Daemon: daemon.NewMock(t)})
 		expectSNMPLookup(t, c, "127.0.0.1", 765, answer{
-			ExporterName: "127_0_0_1",
-			Interface:    Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
+			Exporter:  Exporter{Name: "127_0_0_1"},
+			Interface: Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
 		})
 	})
 }
@@ -106,8 +106,8 @@ func TestAutoRefresh(t *testing.T) {
 	expectSNMPLookup(t, c, "127.0.0.1", 765, answer{Err: ErrCacheMiss})
 	time.Sleep(30 * time.Millisecond)
 	expectSNMPLookup(t, c, "127.0.0.1", 765, answer{
-		ExporterName: "127_0_0_1",
-		Interface:    Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
+		Exporter:  Exporter{Name: "127_0_0_1"},
+		Interface: Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
 	})
 
 	// Keep it in the cache!
@@ -122,8 +122,8 @@ func TestAutoRefresh(t *testing.T) {
 	mockClock.Add(2 * time.Minute)
 	time.Sleep(30 * time.Millisecond)
 	expectSNMPLookup(t, c, "127.0.0.1", 765, answer{
-		ExporterName: "127_0_0_1",
-		Interface:    Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
+		Exporter:  Exporter{Name: "127_0_0_1"},
+		Interface: Interface{Name: "Gi0/0/765", Description: "Interface 765", Speed: 1000},
 	})
 
 	gotMetrics := r.GetMetrics("akvorado_inlet_snmp_cache_")
@@ -19,11 +19,11 @@ import (
 // mockPoller will use static data.
 type mockPoller struct {
 	config Configuration
-	put    func(netip.Addr, string, uint, Interface)
+	put    func(netip.Addr, Exporter, uint, Interface)
 }
 
 // newMockPoller creates a fake SNMP poller.
-func newMockPoller(configuration Configuration, put func(netip.Addr, string, uint, Interface)) *mockPoller {
+func newMockPoller(configuration Configuration, put func(netip.Addr, Exporter, uint, Interface)) *mockPoller {
 	return &mockPoller{
 		config: configuration,
 		put:    put,
@@ -34,7 +34,7 @@ func newMockPoller(configuration Configuration, put func(netip.Addr, string, uin
 func (p *mockPoller) Poll(ctx context.Context, exporter, agent netip.Addr, port uint16, ifIndexes []uint) error {
 	for _, ifIndex := range ifIndexes {
 		if p.config.Communities.LookupOrDefault(exporter, "public") == "public" {
-			p.put(exporter, strings.ReplaceAll(exporter.Unmap().String(), ".", "_"), ifIndex, Interface{
+			p.put(exporter, Exporter{Name: strings.ReplaceAll(exporter.Unmap().String(), ".", "_")}, ifIndex, Interface{
 				Name:        fmt.Sprintf("Gi0/0/%d", ifIndex),
 				Description: fmt.Sprintf("Interface %d", ifIndex),
 				Speed:       1000,
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alerting
+
+import "time"
+
+// Configuration describes the configuration for the alerting component.
+type Configuration struct {
+	// Interval is how often rules are evaluated
+	Interval time.Duration `validate:"min=10s"`
+	// Webhooks is the list of URLs to notify when alerts fire or
+	// are resolved
+	Webhooks []string `validate:"dive,url"`
+	// Timeout is the timeout for webhook notifications
+	Timeout time.Duration `validate:"min=1s"`
+	// Rules is the list of alerting rules
+	Rules []RuleConfiguration `validate:"dive"`
+}
+
+// RuleConfiguration describes an alerting rule.
+type RuleConfiguration struct {
+	// Name is the name of the rule
+	Name string `validate:"required"`
+	// Filter selects the flows to evaluate, using the same
+	// language as the console
+	Filter string
+	// Dimensions is the list of columns to group flows with,
+	// using the same names as the console
+	Dimensions []string `validate:"dive,alphanum"`
+	// Units is the unit used to compute rates (pps, l3bps or
+	// l2bps, default to l3bps)
+	Units string `validate:"isdefault|oneof=pps l3bps l2bps"`
+	// Window is the duration over which rates are computed
+	// (default to 5 minutes)
+	Window time.Duration `validate:"isdefault|min=1m"`
+	// Limit is the number of top rows to evaluate and report
+	// (default to 10)
+	Limit int `validate:"isdefault|min=1,max=50"`
+	// Threshold is the rate above which a row fires an alert
+	Threshold float64 `validate:"required_without=Baseline,min=0"`
+	// Baseline is the duration before the window to compute the
+	// usual rate of each row
+	Baseline time.Duration `validate:"isdefault|min=1h"`
+	// Deviation is the ratio between the rate and the baseline
+	// rate for a row to fire an alert
+	Deviation float64 `validate:"required_with=Baseline,isdefault|gt=1"`
+}
+
+// DefaultConfiguration represents the default configuration for the alerting component.
+func DefaultConfiguration() Configuration {
+	return Configuration{
+		Interval: time.Minute,
+		Timeout:  10 * time.Second,
+	}
+}
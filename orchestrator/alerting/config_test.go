@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"akvorado/common/helpers"
+)
+
+func TestDefaultConfiguration(t *testing.T) {
+	if err := helpers.Validate.Struct(DefaultConfiguration()); err != nil {
+		t.Fatalf("validate.Struct() error:\n%+v", err)
+	}
+}
+
+func TestRuleConfigurationValidation(t *testing.T) {
+	cases := []struct {
+		Description string
+		Rule        RuleConfiguration
+		Error       bool
+	}{
+		{
+			Description: "threshold",
+			Rule: RuleConfiguration{
+				Name:      "ddos",
+				Threshold: 1e9,
+			},
+		}, {
+			Description: "baseline",
+			Rule: RuleConfiguration{
+				Name:      "ddos",
+				Baseline:  24 * time.Hour,
+				Deviation: 3,
+			},
+		}, {
+			Description: "no threshold nor baseline",
+			Rule: RuleConfiguration{
+				Name: "ddos",
+			},
+			Error: true,
+		}, {
+			Description: "baseline without deviation",
+			Rule: RuleConfiguration{
+				Name:     "ddos",
+				Baseline: 24 * time.Hour,
+			},
+			Error: true,
+		}, {
+			Description: "deviation too small",
+			Rule: RuleConfiguration{
+				Name:      "ddos",
+				Baseline:  24 * time.Hour,
+				Deviation: 0.5,
+			},
+			Error: true,
+		}, {
+			Description: "invalid dimension",
+			Rule: RuleConfiguration{
+				Name:       "ddos",
+				Threshold:  1e9,
+				Dimensions: []string{"DstAddr); DROP TABLE flows"},
+			},
+			Error: true,
+		}, {
+			Description: "invalid units",
+			Rule: RuleConfiguration{
+				Name:      "ddos",
+				Threshold: 1e9,
+				Units:     "bps",
+			},
+			Error: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			err := helpers.Validate.Struct(tc.Rule)
+			if err != nil && !tc.Error {
+				t.Fatalf("validate.Struct() error:\n%+v", err)
+			} else if err == nil && tc.Error {
+				t.Fatal("validate.Struct() did not error")
+			}
+		})
+	}
+}
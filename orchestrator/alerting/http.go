@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alerting
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// alertsHandlerFunc returns the list of firing alerts.
+func (c *Component) alertsHandlerFunc(gc *gin.Context) {
+	alerts := []Alert{}
+	c.alertsLock.RLock()
+	for _, ruleAlerts := range c.alerts {
+		for _, alert := range ruleAlerts {
+			alerts = append(alerts, *alert)
+		}
+	}
+	c.alertsLock.RUnlock()
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Rule != alerts[j].Rule {
+			return alerts[i].Rule < alerts[j].Rule
+		}
+		return alerts[i].Rate > alerts[j].Rate
+	})
+	gc.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
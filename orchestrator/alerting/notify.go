@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// notification is the payload sent to webhooks. It contains the alerts
+// whose state changed and the top rows from the evaluation.
+type notification struct {
+	Rule   string  `json:"rule"`
+	Alerts []Alert `json:"alerts"`
+	Rows   []row   `json:"rows"`
+}
+
+// notify sends the provided notification to all webhooks.
+func (c *Component) notify(ctx context.Context, n notification) {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		c.r.Err(err).Msg("cannot encode notification")
+		return
+	}
+	for _, webhook := range c.config.Webhooks {
+		c.metrics.notifications.WithLabelValues(webhook).Inc()
+		if err := c.sendWebhook(ctx, webhook, payload); err != nil {
+			c.r.Err(err).Str("webhook", webhook).Msg("cannot notify webhook")
+			c.metrics.notificationErrors.WithLabelValues(webhook).Inc()
+		}
+	}
+}
+
+// sendWebhook posts a payload to the provided URL.
+func (c *Component) sendWebhook(ctx context.Context, url string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
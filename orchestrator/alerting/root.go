@@ -0,0 +1,264 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package alerting evaluates alerting rules against flows stored in
+// ClickHouse and notifies webhooks when alerts fire or are resolved.
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"gopkg.in/tomb.v2"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/daemon"
+	"akvorado/common/http"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+)
+
+// Component represents the alerting component.
+type Component struct {
+	r      *reporter.Reporter
+	d      *Dependencies
+	t      tomb.Tomb
+	config Configuration
+	rules  []rule
+	tables *schema.Tables
+
+	alertsLock sync.RWMutex
+	alerts     map[string]map[string]*Alert // rule name → dimensions → alert
+
+	metrics struct {
+		evaluations        *reporter.CounterVec
+		evaluationErrors   *reporter.CounterVec
+		activeAlerts       *reporter.GaugeVec
+		notifications      *reporter.CounterVec
+		notificationErrors *reporter.CounterVec
+	}
+}
+
+// Dependencies define the dependencies of the alerting component.
+type Dependencies struct {
+	Daemon     daemon.Component
+	HTTP       *http.Component
+	ClickHouse *clickhousedb.Component
+	Clock      clock.Clock
+}
+
+// AlertState is the state of an alert.
+type AlertState string
+
+const (
+	// AlertFiring is the state of an alert whose rule is matching.
+	AlertFiring AlertState = "firing"
+	// AlertResolved is the state of an alert whose rule is not
+	// matching anymore.
+	AlertResolved AlertState = "resolved"
+)
+
+// Alert is an alert for a rule and a set of dimensions.
+type Alert struct {
+	Rule       string     `json:"rule"`
+	Dimensions []string   `json:"dimensions"`
+	State      AlertState `json:"state"`
+	Rate       float64    `json:"rate"`
+	Baseline   float64    `json:"baseline,omitempty"`
+	StartsAt   time.Time  `json:"starts-at"`
+	EndsAt     *time.Time `json:"ends-at,omitempty"`
+}
+
+// New creates a new alerting component.
+func New(r *reporter.Reporter, configuration Configuration, dependencies Dependencies) (*Component, error) {
+	if dependencies.Clock == nil {
+		dependencies.Clock = clock.New()
+	}
+	c := Component{
+		r:      r,
+		d:      &dependencies,
+		config: configuration,
+		alerts: map[string]map[string]*Alert{},
+		tables: schema.NewTables(r, dependencies.ClickHouse),
+	}
+	names := map[string]struct{}{}
+	for _, config := range configuration.Rules {
+		if _, ok := names[config.Name]; ok {
+			return nil, fmt.Errorf("duplicate rule %q", config.Name)
+		}
+		names[config.Name] = struct{}{}
+		rule, err := compileRule(config)
+		if err != nil {
+			return nil, err
+		}
+		c.rules = append(c.rules, rule)
+		c.alerts[rule.Name] = map[string]*Alert{}
+	}
+	c.d.Daemon.Track(&c.t, "orchestrator/alerting")
+
+	c.metrics.evaluations = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "evaluations_total",
+			Help: "Number of rule evaluations.",
+		}, []string{"rule"})
+	c.metrics.evaluationErrors = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "evaluation_errors_total",
+			Help: "Number of rule evaluations that failed.",
+		}, []string{"rule"})
+	c.metrics.activeAlerts = c.r.GaugeVec(
+		reporter.GaugeOpts{
+			Name: "active_alerts",
+			Help: "Number of firing alerts.",
+		}, []string{"rule"})
+	c.metrics.notifications = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "notifications_total",
+			Help: "Number of webhook notifications sent.",
+		}, []string{"webhook"})
+	c.metrics.notificationErrors = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "notification_errors_total",
+			Help: "Number of webhook notifications that failed.",
+		}, []string{"webhook"})
+
+	c.d.HTTP.GinRouter.GET("/api/v0/orchestrator/alerting/alerts", c.alertsHandlerFunc)
+
+	return &c, nil
+}
+
+// Start starts the alerting component.
+func (c *Component) Start() error {
+	c.r.Info().Msg("starting alerting component")
+	if len(c.rules) == 0 {
+		return nil
+	}
+	c.t.Go(func() error {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.tables.Refresh(c.t.Context(nil)); err != nil {
+					c.r.Err(err).Msg("cannot refresh flows tables")
+					continue
+				}
+				// Once successful, do that less often
+				ticker.Reset(10 * time.Minute)
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	c.t.Go(func() error {
+		ticker := c.d.Clock.Ticker(c.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.t.Dying():
+				return nil
+			case <-ticker.C:
+				c.evaluateRules()
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops the alerting component.
+func (c *Component) Stop() error {
+	defer c.r.Info().Msg("alerting component stopped")
+	c.r.Info().Msg("stopping alerting component")
+	c.t.Kill(nil)
+	return c.t.Wait()
+}
+
+// evaluateRules evaluates all the rules.
+func (c *Component) evaluateRules() {
+	for _, rule := range c.rules {
+		ctx, cancel := context.WithTimeout(c.t.Context(nil), c.config.Interval)
+		err := c.evaluateRule(ctx, rule)
+		cancel()
+		if err != nil {
+			c.r.Err(err).Str("rule", rule.Name).Msg("cannot evaluate rule")
+			c.metrics.evaluationErrors.WithLabelValues(rule.Name).Inc()
+		}
+	}
+}
+
+// evaluateRule evaluates a rule, updates the state of the associated
+// alerts and sends notifications when some alerts changed state.
+func (c *Component) evaluateRule(ctx context.Context, r rule) error {
+	c.metrics.evaluations.WithLabelValues(r.Name).Inc()
+	now := c.d.Clock.Now()
+	// Use the same tables as the console. Consolidated tables
+	// only have data at the start of each interval.
+	table, resolution := c.tables.Best(r.period(now), r.Window, r.mainTableRequired)
+	rows := []row{}
+	if err := c.d.ClickHouse.Select(ctx, &rows, r.toSQL(now.Truncate(resolution), table)); err != nil {
+		return fmt.Errorf("cannot query ClickHouse: %w", err)
+	}
+	changed := c.updateAlerts(r, rows, now)
+	if len(changed) > 0 {
+		c.notify(ctx, notification{
+			Rule:   r.Name,
+			Alerts: changed,
+			Rows:   rows,
+		})
+	}
+	return nil
+}
+
+// updateAlerts updates the state of the alerts for the provided rule
+// using the rows returned by the last evaluation. It returns the
+// alerts whose state changed. Alerts still firing are only updated.
+func (c *Component) updateAlerts(r rule, rows []row, now time.Time) []Alert {
+	c.alertsLock.Lock()
+	defer c.alertsLock.Unlock()
+	alerts := c.alerts[r.Name]
+	changed := []Alert{}
+	firing := map[string]struct{}{}
+	for _, row := range rows {
+		if !r.fires(row) {
+			continue
+		}
+		key := strings.Join(row.Dimensions, "\x00")
+		firing[key] = struct{}{}
+		if alert, ok := alerts[key]; ok {
+			alert.Rate = row.Rate
+			alert.Baseline = row.Baseline
+			continue
+		}
+		alert := &Alert{
+			Rule:       r.Name,
+			Dimensions: row.Dimensions,
+			State:      AlertFiring,
+			Rate:       row.Rate,
+			Baseline:   row.Baseline,
+			StartsAt:   now,
+		}
+		alerts[key] = alert
+		changed = append(changed, *alert)
+	}
+	resolvedKeys := []string{}
+	for key := range alerts {
+		if _, ok := firing[key]; !ok {
+			resolvedKeys = append(resolvedKeys, key)
+		}
+	}
+	sort.Strings(resolvedKeys)
+	for _, key := range resolvedKeys {
+		resolved := *alerts[key]
+		resolved.State = AlertResolved
+		resolved.EndsAt = &now
+		delete(alerts, key)
+		changed = append(changed, resolved)
+	}
+	c.metrics.activeAlerts.WithLabelValues(r.Name).Set(float64(len(alerts)))
+	return changed
+}
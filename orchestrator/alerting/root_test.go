@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alerting
+
+import (
+	"encoding/json"
+	netHTTP "net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/http"
+	"akvorado/common/reporter"
+)
+
+func TestAlerting(t *testing.T) {
+	r := reporter.NewMock(t)
+	h := http.NewMock(t, r)
+	ch, mockConn := clickhousedb.NewMock(t, r)
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC))
+
+	var notificationsLock sync.Mutex
+	notifications := []notification{}
+	webhook := httptest.NewServer(netHTTP.HandlerFunc(func(w netHTTP.ResponseWriter, req *netHTTP.Request) {
+		var n notification
+		if err := json.NewDecoder(req.Body).Decode(&n); err != nil {
+			t.Errorf("Decode() error:\n%+v", err)
+		}
+		notificationsLock.Lock()
+		notifications = append(notifications, n)
+		notificationsLock.Unlock()
+	}))
+	defer webhook.Close()
+
+	config := DefaultConfiguration()
+	// Rules are evaluated explicitly below: the mock clock should
+	// never trigger the evaluation ticker.
+	config.Interval = 24 * time.Hour
+	config.Webhooks = []string{webhook.URL}
+	config.Rules = []RuleConfiguration{{
+		Name:       "ddos",
+		Dimensions: []string{"DstAddr"},
+		Units:      "pps",
+		Threshold:  1000,
+	}}
+	c, err := New(r, config, Dependencies{
+		Daemon:     daemon.NewMock(t),
+		HTTP:       h,
+		ClickHouse: ch,
+		Clock:      mockClock,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, c)
+
+	gomock.InOrder(
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Any()).
+			SetArg(1, []row{
+				{Dimensions: []string{"192.0.2.1"}, Rate: 5000},
+				{Dimensions: []string{"192.0.2.2"}, Rate: 500},
+			}).
+			Return(nil),
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Any()).
+			SetArg(1, []row{
+				{Dimensions: []string{"192.0.2.1"}, Rate: 6000},
+				{Dimensions: []string{"192.0.2.2"}, Rate: 500},
+			}).
+			Return(nil),
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Any()).
+			SetArg(1, []row{
+				{Dimensions: []string{"192.0.2.2"}, Rate: 500},
+			}).
+			Return(nil),
+	)
+
+	// First evaluation: the alert fires
+	c.evaluateRules()
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			URL: "/api/v0/orchestrator/alerting/alerts",
+			JSONOutput: gin.H{
+				"alerts": []gin.H{{
+					"rule":       "ddos",
+					"dimensions": []string{"192.0.2.1"},
+					"state":      "firing",
+					"rate":       5000,
+					"starts-at":  "2022-11-20T10:00:00Z",
+				}},
+			},
+		},
+	})
+
+	// Second evaluation: the alert is still firing, no notification
+	mockClock.Add(time.Minute)
+	c.evaluateRules()
+
+	// Third evaluation: the alert is resolved
+	mockClock.Add(time.Minute)
+	c.evaluateRules()
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			URL:        "/api/v0/orchestrator/alerting/alerts",
+			JSONOutput: gin.H{"alerts": []gin.H{}},
+		},
+	})
+
+	resolved := time.Date(2022, 11, 20, 10, 2, 0, 0, time.UTC)
+	expected := []notification{
+		{
+			Rule: "ddos",
+			Alerts: []Alert{{
+				Rule:       "ddos",
+				Dimensions: []string{"192.0.2.1"},
+				State:      AlertFiring,
+				Rate:       5000,
+				StartsAt:   time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC),
+			}},
+			Rows: []row{
+				{Dimensions: []string{"192.0.2.1"}, Rate: 5000},
+				{Dimensions: []string{"192.0.2.2"}, Rate: 500},
+			},
+		}, {
+			Rule: "ddos",
+			Alerts: []Alert{{
+				Rule:       "ddos",
+				Dimensions: []string{"192.0.2.1"},
+				State:      AlertResolved,
+				Rate:       6000,
+				StartsAt:   time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC),
+				EndsAt:     &resolved,
+			}},
+			Rows: []row{
+				{Dimensions: []string{"192.0.2.2"}, Rate: 500},
+			},
+		},
+	}
+	notificationsLock.Lock()
+	defer notificationsLock.Unlock()
+	if diff := helpers.Diff(notifications, expected); diff != "" {
+		t.Fatalf("notifications (-got, +want):\n%s", diff)
+	}
+
+	gotMetrics := r.GetMetrics("akvorado_orchestrator_alerting_")
+	expectedMetrics := map[string]string{
+		`active_alerts{rule="ddos"}`:                         "0",
+		`evaluations_total{rule="ddos"}`:                     "3",
+		`notifications_total{webhook="` + webhook.URL + `"}`: "2",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestDuplicateRules(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.Rules = []RuleConfiguration{
+		{Name: "ddos", Threshold: 1000},
+		{Name: "ddos", Threshold: 2000},
+	}
+	if _, err := New(r, config, Dependencies{
+		Daemon: daemon.NewMock(t),
+		HTTP:   http.NewMock(t, r),
+	}); err == nil {
+		t.Fatal("New() did not error")
+	}
+}
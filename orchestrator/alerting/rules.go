@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alerting
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"akvorado/common/schema"
+	"akvorado/console/filter"
+)
+
+// rule is a compiled alerting rule.
+type rule struct {
+	RuleConfiguration
+	filter            string
+	dimensions        []string
+	mainTableRequired bool
+}
+
+// row is a row returned when evaluating a rule.
+type row struct {
+	Dimensions []string `ch:"dimensions" json:"dimensions"`
+	Rate       float64  `ch:"rate" json:"rate"`
+	Baseline   float64  `ch:"baseline" json:"baseline,omitempty"`
+}
+
+// compileRule parses the filter and the dimensions of a rule and sets
+// default values.
+func compileRule(config RuleConfiguration) (rule, error) {
+	r := rule{RuleConfiguration: config}
+	if r.Units == "" {
+		r.Units = "l3bps"
+	}
+	if r.Window == 0 {
+		r.Window = 5 * time.Minute
+	}
+	if r.Limit == 0 {
+		r.Limit = 10
+	}
+	if strings.TrimSpace(config.Filter) != "" {
+		meta := &filter.Meta{}
+		got, err := filter.Parse("", []byte(config.Filter), filter.GlobalStore("meta", meta))
+		if err != nil {
+			return rule{}, fmt.Errorf("invalid filter for rule %q: %s",
+				config.Name, filter.HumanError(err))
+		}
+		r.filter = got.(string)
+		r.mainTableRequired = meta.MainTableRequired
+	}
+	for _, dimension := range config.Dimensions {
+		got, err := schema.ColumnToSQL(dimension)
+		if err != nil {
+			return rule{}, fmt.Errorf("invalid dimensions for rule %q: %w", config.Name, err)
+		}
+		r.dimensions = append(r.dimensions, got)
+		r.mainTableRequired = r.mainTableRequired || schema.ColumnRequiresMainTable(dimension)
+	}
+	return r, nil
+}
+
+// period returns the start of the data needed to evaluate the rule at
+// the provided time.
+func (r rule) period(now time.Time) time.Time {
+	return now.Add(-r.Window - r.Baseline)
+}
+
+// toSQL builds the SQL query to evaluate the rule at the provided time
+// using the provided flows table.
+func (r rule) toSQL(now time.Time, table string) string {
+	var units string
+	switch r.Units {
+	case "pps":
+		units = `Packets*SamplingRate`
+	case "l3bps":
+		units = `Bytes*SamplingRate*8`
+	case "l2bps":
+		units = `(Bytes+18*Packets)*SamplingRate*8`
+	}
+
+	end := now.UTC().Truncate(time.Second)
+	start := end.Add(-r.Window)
+	baseline := `toFloat64(0)`
+	first := r.period(end)
+	if r.Baseline > 0 {
+		baseline = fmt.Sprintf(`sumIf(%s, TimeReceived < %s)/%d`,
+			units, toDateTime(start), uint64(r.Baseline.Seconds()))
+	}
+	where := fmt.Sprintf(`TimeReceived BETWEEN %s AND %s`, toDateTime(first), toDateTime(end))
+	if r.filter != "" {
+		where = fmt.Sprintf(`%s AND (%s)`, where, r.filter)
+	}
+	selectDimensions := `emptyArrayString()`
+	if len(r.dimensions) > 0 {
+		selectDimensions = fmt.Sprintf(`[%s]`, strings.Join(r.dimensions, ", "))
+	}
+	return strings.TrimSpace(fmt.Sprintf(`
+SELECT
+ %s AS dimensions,
+ sumIf(%s, TimeReceived >= %s)/%d AS rate,
+ %s AS baseline
+FROM %s
+WHERE %s
+GROUP BY dimensions
+ORDER BY rate DESC
+LIMIT %d`,
+		selectDimensions,
+		units, toDateTime(start), uint64(r.Window.Seconds()),
+		baseline, table, where, r.Limit))
+}
+
+// fires tells if the provided row should fire an alert.
+func (r rule) fires(result row) bool {
+	if result.Rate <= r.Threshold {
+		return false
+	}
+	if r.Baseline > 0 && result.Rate <= result.Baseline*r.Deviation {
+		return false
+	}
+	return true
+}
+
+func toDateTime(t time.Time) string {
+	return fmt.Sprintf(`toDateTime('%s', 'UTC')`, t.UTC().Format("2006-01-02 15:04:05"))
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2022 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"akvorado/common/helpers"
+)
+
+func TestCompileRule(t *testing.T) {
+	if _, err := compileRule(RuleConfiguration{
+		Name:   "ddos",
+		Filter: "InIfBoundary = ",
+	}); err == nil {
+		t.Fatal("compileRule() did not error")
+	}
+	if _, err := compileRule(RuleConfiguration{
+		Name:       "ddos",
+		Dimensions: []string{"DstAddress"},
+	}); err == nil {
+		t.Fatal("compileRule() did not error")
+	}
+
+	cases := []struct {
+		Rule              RuleConfiguration
+		MainTableRequired bool
+	}{
+		{RuleConfiguration{Name: "exporters", Dimensions: []string{"ExporterName"}}, false},
+		{RuleConfiguration{Name: "ddos", Dimensions: []string{"DstAddr"}}, true},
+		{RuleConfiguration{Name: "dns", Filter: "SrcPort = 53"}, true},
+	}
+	for _, tc := range cases {
+		rule, err := compileRule(tc.Rule)
+		if err != nil {
+			t.Fatalf("compileRule(%q) error:\n%+v", tc.Rule.Name, err)
+		}
+		if rule.mainTableRequired != tc.MainTableRequired {
+			t.Errorf("compileRule(%q) mainTableRequired == %v but expected %v",
+				tc.Rule.Name, rule.mainTableRequired, tc.MainTableRequired)
+		}
+	}
+}
+
+func TestRuleToSQL(t *testing.T) {
+	now := time.Date(2022, 11, 20, 10, 15, 30, 500, time.UTC)
+	cases := []struct {
+		Description string
+		Rule        RuleConfiguration
+		Table       string
+		Expected    string
+	}{
+		{
+			Description: "no dimensions",
+			Rule: RuleConfiguration{
+				Name:      "traffic",
+				Threshold: 1e9,
+			},
+			Table: "flows",
+			Expected: `
+SELECT
+ emptyArrayString() AS dimensions,
+ sumIf(Bytes*SamplingRate*8, TimeReceived >= toDateTime('2022-11-20 10:10:30', 'UTC'))/300 AS rate,
+ toFloat64(0) AS baseline
+FROM flows
+WHERE TimeReceived BETWEEN toDateTime('2022-11-20 10:10:30', 'UTC') AND toDateTime('2022-11-20 10:15:30', 'UTC')
+GROUP BY dimensions
+ORDER BY rate DESC
+LIMIT 10`,
+		}, {
+			Description: "threshold",
+			Rule: RuleConfiguration{
+				Name:       "ddos",
+				Filter:     "InIfBoundary = external AND Proto = 17",
+				Dimensions: []string{"DstAddr", "ExporterName"},
+				Units:      "pps",
+				Window:     time.Minute,
+				Limit:      5,
+				Threshold:  1e6,
+			},
+			Table: "flows",
+			Expected: `
+SELECT
+ [replaceRegexpOne(IPv6NumToString(DstAddr), '^::ffff:', ''), ExporterName] AS dimensions,
+ sumIf(Packets*SamplingRate, TimeReceived >= toDateTime('2022-11-20 10:14:30', 'UTC'))/60 AS rate,
+ toFloat64(0) AS baseline
+FROM flows
+WHERE TimeReceived BETWEEN toDateTime('2022-11-20 10:14:30', 'UTC') AND toDateTime('2022-11-20 10:15:30', 'UTC') AND (InIfBoundary = 'external' AND Proto = 17)
+GROUP BY dimensions
+ORDER BY rate DESC
+LIMIT 5`,
+		}, {
+			Description: "baseline",
+			Rule: RuleConfiguration{
+				Name:       "surge",
+				Dimensions: []string{"SrcAS"},
+				Units:      "l2bps",
+				Baseline:   time.Hour,
+				Deviation:  2,
+			},
+			Table: "flows_1m0s",
+			Expected: `
+SELECT
+ [concat(toString(SrcAS), ': ', dictGetOrDefault('asns', 'name', SrcAS, '???'))] AS dimensions,
+ sumIf((Bytes+18*Packets)*SamplingRate*8, TimeReceived >= toDateTime('2022-11-20 10:10:30', 'UTC'))/300 AS rate,
+ sumIf((Bytes+18*Packets)*SamplingRate*8, TimeReceived < toDateTime('2022-11-20 10:10:30', 'UTC'))/3600 AS baseline
+FROM flows_1m0s
+WHERE TimeReceived BETWEEN toDateTime('2022-11-20 09:10:30', 'UTC') AND toDateTime('2022-11-20 10:15:30', 'UTC')
+GROUP BY dimensions
+ORDER BY rate DESC
+LIMIT 10`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			rule, err := compileRule(tc.Rule)
+			if err != nil {
+				t.Fatalf("compileRule() error:\n%+v", err)
+			}
+			if diff := helpers.Diff(rule.toSQL(now, tc.Table), tc.Expected[1:]); diff != "" {
+				t.Errorf("toSQL() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRuleFires(t *testing.T) {
+	threshold, _ := compileRule(RuleConfiguration{Name: "threshold", Threshold: 100})
+	baseline, _ := compileRule(RuleConfiguration{Name: "baseline", Threshold: 10, Baseline: time.Hour, Deviation: 2})
+	cases := []struct {
+		Rule     rule
+		Row      row
+		Expected bool
+	}{
+		{threshold, row{Rate: 50}, false},
+		{threshold, row{Rate: 150}, true},
+		{baseline, row{Rate: 5, Baseline: 1}, false},
+		{baseline, row{Rate: 50, Baseline: 40}, false},
+		{baseline, row{Rate: 50, Baseline: 20}, true},
+	}
+	for _, tc := range cases {
+		if got := tc.Rule.fires(tc.Row); got != tc.Expected {
+			t.Errorf("%s.fires(%+v) == %v but expected %v", tc.Rule.Name, tc.Row, got, tc.Expected)
+		}
+	}
+}
@@ -25,6 +25,9 @@ type Configuration struct {
 	// Resolutions describe the various resolutions to use to
 	// store data and the associated TTLs.
 	Resolutions []ResolutionConfiguration `validate:"dive"`
+	// InterfaceCountersTTL is how long to keep interface
+	// counters. A value of 0 means to never expire.
+	InterfaceCountersTTL time.Duration `validate:"isdefault|min=1h"`
 	// MaxPartitions define the number of partitions to have for a
 	// consolidated flow tables when full.
 	MaxPartitions int `validate:"isdefault|min=1"`
@@ -78,6 +81,7 @@ func DefaultConfiguration() Configuration {
 			{5 * time.Minute, 3 * 30 * 24 * time.Hour}, // 90 days
 			{time.Hour, 12 * 30 * 24 * time.Hour},      // 1 year
 		},
+		InterfaceCountersTTL:  3 * 30 * 24 * time.Hour, // 90 days
 		MaxPartitions:         50,
 		NetworkSourcesTimeout: 10 * time.Second,
 	}
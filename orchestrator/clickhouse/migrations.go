@@ -101,6 +101,15 @@ func (c *Component) migrateDatabase() error {
 			}, migrationStepWithDescription{
 				"add SrcNetPrefix/DstNetPrefix aliases to flows table",
 				c.migrationStepAddSrcNetPrefixDstNetPrefixColumn,
+			}, migrationStepWithDescription{
+				"add SrcVlan/DstVlan/SrcMAC/DstMAC/MPLS1stLabel columns to flows table",
+				c.migrationStepAddVlanMACMPLSColumns,
+			}, migrationStepWithDescription{
+				"add IPTos/IPTTL/TCPFlags/IcmpType/IcmpCode columns to flows table",
+				c.migrationStepAddIPHeaderColumns,
+			}, migrationStepWithDescription{
+				"add DSCP alias to flows table",
+				c.migrationStepAddDSCPColumn,
 			})
 		}
 		steps = append(steps, []migrationStepWithDescription{
@@ -110,14 +119,23 @@ func (c *Component) migrateDatabase() error {
 			}, {
 				fmt.Sprintf("configure TTL for flows table with resolution %s", resolution.Interval),
 				c.migrationsStepSetTTLFlowsTable(resolution),
+			}, {
+				fmt.Sprintf("create distributed flows table with resolution %s", resolution.Interval),
+				c.migrationStepCreateDistributedTable(flowsTable(resolution)),
 			},
 		}...)
 	}
 	steps = append(steps, []migrationStepWithDescription{
 		{"create exporters view", c.migrationStepCreateExportersView},
+		{"create distributed exporters table", c.migrationStepCreateDistributedTable("exporters")},
 		{"create raw flows table", c.migrationStepCreateRawFlowsTable},
 		{"create raw flows consumer view", c.migrationStepCreateRawFlowsConsumerView},
 		{"create raw flows errors view", c.migrationStepCreateRawFlowsErrorsView},
+		{"create interface counters table", c.migrationStepCreateInterfaceCountersTable},
+		{"configure TTL for interface counters table", c.migrationStepSetTTLInterfaceCountersTable},
+		{"create distributed interface counters table", c.migrationStepCreateDistributedTable("interface_counters")},
+		{"create raw interface counters table", c.migrationStepCreateRawInterfaceCountersTable},
+		{"create raw interface counters consumer view", c.migrationStepCreateRawInterfaceCountersConsumerView},
 	}...)
 
 	count := 0
@@ -170,7 +188,7 @@ func (c *Component) migrateDatabase() error {
 	c.metrics.migrationsVersion.Set(float64(total))
 
 	// Reload dictionaries
-	if err := c.d.ClickHouse.Exec(ctx, "SYSTEM RELOAD DICTIONARIES"); err != nil {
+	if err := c.d.ClickHouse.Exec(ctx, fmt.Sprintf("SYSTEM RELOAD DICTIONARIES%s", c.onCluster())); err != nil {
 		c.r.Err(err).Msg("unable to reload dictionaries after migration")
 	}
 
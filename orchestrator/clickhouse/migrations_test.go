@@ -13,20 +13,28 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/golang/mock/gomock"
+
 	"akvorado/common/clickhousedb"
+	"akvorado/common/clickhousedb/mocks"
 	"akvorado/common/daemon"
 	"akvorado/common/helpers"
 	"akvorado/common/http"
 	"akvorado/common/kafka"
 	"akvorado/common/reporter"
+	"akvorado/inlet/flow"
 )
 
 var ignoredTables = []string{
 	"flows_1_raw",
 	"flows_1_raw_consumer",
+	"flows_4_raw",
+	"flows_4_raw_consumer",
+	"flows_4_raw_errors",
 }
 
 func dropAllTables(t *testing.T, ch *clickhousedb.Component) {
@@ -216,11 +224,14 @@ WHERE database=currentDatabase() AND table NOT LIKE '.%'`)
 				"flows_1h0m0s_consumer",
 				"flows_1m0s",
 				"flows_1m0s_consumer",
-				"flows_4_raw",
-				"flows_4_raw_consumer",
-				"flows_4_raw_errors",
+				"flows_5_raw",
+				"flows_5_raw_consumer",
+				"flows_5_raw_errors",
 				"flows_5m0s",
 				"flows_5m0s_consumer",
+				"interface_counters",
+				"interface_counters_raw",
+				"interface_counters_raw_consumer",
 				"networks",
 				"protocols",
 			}
@@ -293,3 +304,155 @@ GROUP BY table`)
 		})
 	}
 }
+
+func TestMigrationCluster(t *testing.T) {
+	r := reporter.NewMock(t)
+	chComponent, mockConn := clickhousedb.NewMock(t, r)
+	ctrl := gomock.NewController(t)
+
+	// Number of threads
+	threadsRow := mocks.NewMockRow(ctrl)
+	mockConn.EXPECT().
+		QueryRow(gomock.Any(), `SELECT getSetting('max_threads')`).
+		Return(threadsRow)
+	threadsRow.EXPECT().Err().Return(nil)
+	threadsRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...interface{}) error {
+		*dest[0].(*uint8) = 8
+		return nil
+	})
+
+	// Sorting keys
+	sortingKeyRow := mocks.NewMockRow(ctrl)
+	mockConn.EXPECT().
+		QueryRow(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(sortingKeyRow).
+		AnyTimes()
+	sortingKeyRow.EXPECT().Err().Return(nil).AnyTimes()
+	sortingKeyRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...interface{}) error {
+		*dest[0].(*string) = "TimeReceived, ExporterAddress"
+		return nil
+	}).AnyTimes()
+
+	// Check queries never return anything: all steps are executed
+	emptyRows := mocks.NewMockRows(ctrl)
+	mockConn.EXPECT().
+		Query(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(emptyRows, nil).
+		AnyTimes()
+	emptyRows.EXPECT().Next().Return(false).AnyTimes()
+	emptyRows.EXPECT().Close().Return(nil).AnyTimes()
+
+	// Record executed queries
+	var queriesLock sync.Mutex
+	queries := []string{}
+	mockConn.EXPECT().
+		Exec(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, query string, _ ...interface{}) error {
+			queriesLock.Lock()
+			defer queriesLock.Unlock()
+			queries = append(queries, strings.Join(strings.Fields(query), " "))
+			return nil
+		}).
+		AnyTimes()
+
+	configuration := DefaultConfiguration()
+	configuration.Cluster = "akvorado"
+	configuration.OrchestratorURL = "http://something"
+	configuration.Kafka.Configuration = kafka.DefaultConfiguration()
+	ch, err := New(r, configuration, Dependencies{
+		Daemon:     daemon.NewMock(t),
+		HTTP:       http.NewMock(t, r),
+		ClickHouse: chComponent,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	if err := ch.Start(); err != nil {
+		t.Fatalf("Start() error:\n%+v", err)
+	}
+	select {
+	case <-ch.migrationsDone:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Migrations not done")
+	}
+	if err := ch.Stop(); err != nil {
+		t.Fatalf("Stop() error:\n%+v", err)
+	}
+
+	queriesLock.Lock()
+	defer queriesLock.Unlock()
+	for _, query := range queries {
+		if !strings.Contains(query, " ON CLUSTER `akvorado`") {
+			t.Errorf("Exec(%q) not executed on cluster", query)
+		}
+	}
+	expectedQueries := []string{
+		"CREATE OR REPLACE DICTIONARY protocols ON CLUSTER `akvorado` ( proto UInt8 INJECTIVE,",
+		"CREATE TABLE flows_local ON CLUSTER `akvorado` ( TimeReceived DateTime CODEC(DoubleDelta, LZ4),",
+		"ALTER TABLE flows_local ON CLUSTER `akvorado` MODIFY TTL TimeReceived + toIntervalSecond(1296000)",
+		"CREATE TABLE flows ON CLUSTER `akvorado` AS flows_local ENGINE = Distributed(`akvorado`, currentDatabase(), flows_local, rand())",
+		"CREATE TABLE flows_1m0s_local ON CLUSTER `akvorado` ( TimeReceived DateTime CODEC(DoubleDelta, LZ4),",
+		"ALTER TABLE flows_1m0s_local ON CLUSTER `akvorado` ADD COLUMN SrcNetName LowCardinality(String) AFTER DstAS, ADD COLUMN DstNetName LowCardinality(String) AFTER SrcNetName, MODIFY ORDER BY (TimeReceived, ExporterAddress, SrcNetName, DstNetName)",
+		"CREATE MATERIALIZED VIEW flows_1m0s_consumer ON CLUSTER `akvorado` TO flows_1m0s_local AS SELECT * EXCEPT",
+		"CREATE TABLE flows_1m0s ON CLUSTER `akvorado` AS flows_1m0s_local ENGINE = Distributed(`akvorado`, currentDatabase(), flows_1m0s_local, rand())",
+		"CREATE MATERIALIZED VIEW exporters_local ON CLUSTER `akvorado` ENGINE = ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/{database}/exporters_local', '{replica}', TimeReceived) ORDER BY (ExporterAddress, IfName)",
+		"CREATE TABLE exporters ON CLUSTER `akvorado` AS exporters_local ENGINE = Distributed(`akvorado`, currentDatabase(), exporters_local, rand())",
+		fmt.Sprintf("CREATE MATERIALIZED VIEW flows_%d_raw_consumer ON CLUSTER `akvorado` TO flows_local AS WITH", flow.CurrentSchemaVersion),
+		"CREATE TABLE interface_counters ON CLUSTER `akvorado` AS interface_counters_local ENGINE = Distributed(`akvorado`, currentDatabase(), interface_counters_local, rand())",
+		"CREATE MATERIALIZED VIEW interface_counters_raw_consumer ON CLUSTER `akvorado` TO interface_counters_local AS SELECT",
+		"SYSTEM RELOAD DICTIONARIES ON CLUSTER `akvorado`",
+	}
+outer:
+	for _, expected := range expectedQueries {
+		for _, query := range queries {
+			if strings.HasPrefix(query, expected) {
+				continue outer
+			}
+		}
+		t.Errorf("Exec(%q) not executed", expected)
+	}
+	for _, expected := range []string{
+		"ENGINE = ReplicatedMergeTree('/clickhouse/tables/{shard}/{database}/flows_local', '{replica}') PARTITION BY",
+		"ENGINE = ReplicatedSummingMergeTree('/clickhouse/tables/{shard}/{database}/flows_1m0s_local', '{replica}', (Bytes, Packets)) PARTITION BY",
+		"ENGINE = ReplicatedMergeTree('/clickhouse/tables/{shard}/{database}/interface_counters_local', '{replica}') PARTITION BY",
+	} {
+		found := false
+		for _, query := range queries {
+			if strings.Contains(query, expected) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("No query with %q executed", expected)
+		}
+	}
+}
+
+func TestMigrationClusterExistingTable(t *testing.T) {
+	r := reporter.NewMock(t)
+	ctrl := gomock.NewController(t)
+	mockConn := mocks.NewMockConn(ctrl)
+	configuration := DefaultConfiguration()
+	configuration.Cluster = "akvorado"
+	c := Component{r: r, config: configuration}
+
+	engineRows := mocks.NewMockRows(ctrl)
+	mockConn.EXPECT().
+		Query(gomock.Any(),
+			`SELECT engine FROM system.tables WHERE name = $1 AND database = currentDatabase()`,
+			"flows").
+		Return(engineRows, nil)
+	engineRows.EXPECT().Next().Return(true)
+	engineRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...interface{}) error {
+		*dest[0].(*string) = "MergeTree"
+		return nil
+	})
+	engineRows.EXPECT().Close().Return(nil)
+
+	// No DROP TABLE should be executed
+	step := c.migrationStepCreateDistributedTable("flows")(context.Background(), r.Logger.Logger, mockConn)
+	if err := step.Do(); err == nil {
+		t.Fatal("Do() did not error")
+	}
+}
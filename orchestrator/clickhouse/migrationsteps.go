@@ -63,10 +63,20 @@ const (
  OutIfProvider LowCardinality(String),
  InIfBoundary Enum8('undefined' = 0, 'external' = 1, 'internal' = 2),
  OutIfBoundary Enum8('undefined' = 0, 'external' = 1, 'internal' = 2),
+ SrcVlan UInt16,
+ DstVlan UInt16,
+ SrcMAC UInt64,
+ DstMAC UInt64,
+ MPLS1stLabel UInt32,
  EType UInt32,
  Proto UInt32,
  SrcPort UInt32,
  DstPort UInt32,
+ IPTos UInt8,
+ IPTTL UInt8,
+ TCPFlags UInt16,
+ IcmpType UInt8,
+ IcmpCode UInt8,
  Bytes UInt64,
  Packets UInt64,
  ForwardingStatus UInt32
@@ -139,13 +149,13 @@ func appendToSortingKey(ctx context.Context, conn clickhouse.Conn, table string,
 }
 
 // addColumnsAndUpdateSortingKey combines addColumnsAfter and appendToSortingKey
-func addColumnsAndUpdateSortingKey(ctx context.Context, conn clickhouse.Conn, table string, after string, columns ...string) (string, error) {
+func (c *Component) addColumnsAndUpdateSortingKey(ctx context.Context, conn clickhouse.Conn, table string, after string, columns ...string) (string, error) {
 	modifications := []string{addColumnsAfter(after, columns...)}
 	columnNames := []string{}
 	for _, column := range columns {
 		columnNames = append(columnNames, columnSpecToName(column))
 	}
-	if table != "flows" {
+	if table != c.localTable("flows") {
 		sortingKey, err := appendToSortingKey(ctx, conn, table, columnNames...)
 		if err != nil {
 			return "", err
@@ -155,6 +165,57 @@ func addColumnsAndUpdateSortingKey(ctx context.Context, conn clickhouse.Conn, ta
 	return strings.Join(modifications, ", "), nil
 }
 
+// flowsTable returns the name of the flows table for the provided
+// resolution.
+func flowsTable(resolution ResolutionConfiguration) string {
+	if resolution.Interval == 0 {
+		return "flows"
+	}
+	return fmt.Sprintf("flows_%s", resolution.Interval)
+}
+
+// localTable returns the name of the table storing the data. When
+// using a cluster, this is a replicated table and a distributed table
+// with the provided name is created on top of it.
+func (c *Component) localTable(table string) string {
+	if c.config.Cluster == "" {
+		return table
+	}
+	return fmt.Sprintf("%s_local", table)
+}
+
+// onCluster returns the ON CLUSTER clause to use in DDL queries.
+func (c *Component) onCluster() string {
+	if c.config.Cluster == "" {
+		return ""
+	}
+	return fmt.Sprintf(" ON CLUSTER %s", c.quotedCluster())
+}
+
+// quotedCluster returns the name of the cluster quoted as an
+// identifier.
+func (c *Component) quotedCluster() string {
+	return fmt.Sprintf("`%s`",
+		strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(c.config.Cluster))
+}
+
+// mergeTreeEngine returns the engine for a table using the provided
+// MergeTree variant (empty, "Summing", "Replacing"). When using a
+// cluster, the replicated engine is returned.
+func (c *Component) mergeTreeEngine(table string, variant string, args ...string) string {
+	if c.config.Cluster != "" {
+		args = append([]string{
+			fmt.Sprintf("'/clickhouse/tables/{shard}/{database}/%s'", table),
+			"'{replica}'",
+		}, args...)
+		variant = fmt.Sprintf("Replicated%s", variant)
+	}
+	if len(args) == 0 {
+		return fmt.Sprintf("%sMergeTree", variant)
+	}
+	return fmt.Sprintf("%sMergeTree(%s)", variant, strings.Join(args, ", "))
+}
+
 var nullMigrationStep = migrationStep{
 	CheckQuery: `SELECT 1`,
 	Args:       []interface{}{},
@@ -166,17 +227,20 @@ func (c *Component) migrationsStepCreateFlowsTable(resolution ResolutionConfigur
 		if resolution.Interval == 0 {
 			// Unconsolidated flows table
 			partitionInterval := uint64((resolution.TTL / time.Duration(c.config.MaxPartitions)).Seconds())
+			tableName := c.localTable("flows")
 			return migrationStep{
 				CheckQuery: `SELECT 1 FROM system.tables WHERE name = $1 AND database = currentDatabase()`,
-				Args:       []interface{}{"flows"},
+				Args:       []interface{}{tableName},
 				Do: func() error {
 					return conn.Exec(ctx, fmt.Sprintf(`
-CREATE TABLE flows (
+CREATE TABLE %s%s (
 %s
 )
-ENGINE = MergeTree
+ENGINE = %s
 PARTITION BY toYYYYMMDDhhmmss(toStartOfInterval(TimeReceived, INTERVAL %d second))
-ORDER BY (TimeReceived, ExporterAddress, InIfName, OutIfName)`, flowsSchema, partitionInterval))
+ORDER BY (TimeReceived, ExporterAddress, InIfName, OutIfName)`,
+						tableName, c.onCluster(), flowsSchema,
+						c.mergeTreeEngine(tableName, ""), partitionInterval))
 				},
 			}
 		}
@@ -187,14 +251,14 @@ ORDER BY (TimeReceived, ExporterAddress, InIfName, OutIfName)`, flowsSchema, par
 		// that are summed. The order is the one we are most
 		// likely to use when filtering. SrcAddr and DstAddr
 		// are removed.
-		tableName := fmt.Sprintf("flows_%s", resolution.Interval)
-		viewName := fmt.Sprintf("%s_consumer", tableName)
+		viewName := fmt.Sprintf("%s_consumer", flowsTable(resolution))
+		tableName := c.localTable(flowsTable(resolution))
 		return migrationStep{
 			CheckQuery: `SELECT 1 FROM system.tables WHERE name = $1 AND database = currentDatabase()`,
 			Args:       []interface{}{tableName},
 			Do: func() error {
 				l.Debug().Msgf("drop flows consumer table for interval %s", resolution.Interval)
-				err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s SYNC`, viewName))
+				err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s%s SYNC`, viewName, c.onCluster()))
 				if err != nil {
 					return fmt.Errorf("cannot drop flows consumer table for interval %s: %w",
 						resolution.Interval, err)
@@ -210,10 +274,10 @@ ORDER BY (TimeReceived, ExporterAddress, InIfName, OutIfName)`, flowsSchema, par
 				// That's not the case for SrcNetName and others (they depend on the
 				// SrcAddr which we don't have anymore).
 				return conn.Exec(ctx, fmt.Sprintf(`
-CREATE TABLE %s (
+CREATE TABLE %s%s (
 %s
 )
-ENGINE = SummingMergeTree((Bytes, Packets))
+ENGINE = %s
 PARTITION BY toYYYYMMDDhhmmss(toStartOfInterval(TimeReceived, INTERVAL %d second))
 PRIMARY KEY (TimeReceived,
           ExporterAddress,
@@ -234,12 +298,15 @@ ORDER BY (TimeReceived,
           SrcNetTenant, DstNetTenant,
           SrcCountry, DstCountry,
           Dst1stAS, Dst2ndAS, Dst3rdAS)`,
-					tableName,
+					tableName, c.onCluster(),
 					partialSchema(
 						"SrcAddr", "DstAddr",
 						"SrcNetMask", "DstNetMask",
 						"SrcPort", "DstPort",
-						"DstASPath", "DstCommunities", "DstLargeCommunities"),
+						"DstASPath", "DstCommunities", "DstLargeCommunities",
+						"SrcVlan", "DstVlan", "SrcMAC", "DstMAC", "MPLS1stLabel",
+						"IPTos", "IPTTL", "TCPFlags", "IcmpType", "IcmpCode"),
+					c.mergeTreeEngine(tableName, "Summing", "(Bytes, Packets)"),
 					partitionInterval))
 			},
 		}
@@ -248,12 +315,7 @@ ORDER BY (TimeReceived,
 
 func (c *Component) migrationStepAddPacketSizeBucketColumn(resolution ResolutionConfiguration) migrationStepFunc {
 	return func(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
-		var tableName string
-		if resolution.Interval == 0 {
-			tableName = "flows"
-		} else {
-			tableName = fmt.Sprintf("flows_%s", resolution.Interval)
-		}
+		tableName := c.localTable(flowsTable(resolution))
 		return migrationStep{
 			CheckQuery: `
 SELECT 1 FROM system.columns
@@ -270,8 +332,8 @@ WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 					last = boundary
 				}
 				conditions = append(conditions, fmt.Sprintf("'%d-Inf'", last))
-				return conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s %s",
-					tableName, addColumnsAfter("Packets",
+				return conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s%s %s",
+					tableName, c.onCluster(), addColumnsAfter("Packets",
 						"PacketSize UInt64 ALIAS intDiv(Bytes, Packets)",
 						fmt.Sprintf("PacketSizeBucket LowCardinality(String) ALIAS multiIf(%s)",
 							strings.Join(conditions, ", ")))))
@@ -282,19 +344,14 @@ WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 
 func (c *Component) migrationStepAddSrcNetNameDstNetNameColumns(resolution ResolutionConfiguration) migrationStepFunc {
 	return func(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
-		var tableName string
-		if resolution.Interval == 0 {
-			tableName = "flows"
-		} else {
-			tableName = fmt.Sprintf("flows_%s", resolution.Interval)
-		}
+		tableName := c.localTable(flowsTable(resolution))
 		return migrationStep{
 			CheckQuery: `
 SELECT 1 FROM system.columns
 WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 			Args: []interface{}{tableName, "DstNetName"},
 			Do: func() error {
-				modifications, err := addColumnsAndUpdateSortingKey(ctx, conn, tableName,
+				modifications, err := c.addColumnsAndUpdateSortingKey(ctx, conn, tableName,
 					"DstAS",
 					`SrcNetName LowCardinality(String)`,
 					`DstNetName LowCardinality(String)`,
@@ -302,8 +359,8 @@ WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 				if err != nil {
 					return err
 				}
-				return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s %s`,
-					tableName, modifications))
+				return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s%s %s`,
+					tableName, c.onCluster(), modifications))
 			},
 		}
 	}
@@ -311,19 +368,14 @@ WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 
 func (c *Component) migrationStepAddSrcNetNameDstNetOthersColumns(resolution ResolutionConfiguration) migrationStepFunc {
 	return func(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
-		var tableName string
-		if resolution.Interval == 0 {
-			tableName = "flows"
-		} else {
-			tableName = fmt.Sprintf("flows_%s", resolution.Interval)
-		}
+		tableName := c.localTable(flowsTable(resolution))
 		return migrationStep{
 			CheckQuery: `
 SELECT 1 FROM system.columns
 WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 			Args: []interface{}{tableName, "DstNetRole"},
 			Do: func() error {
-				modifications, err := addColumnsAndUpdateSortingKey(ctx, conn, tableName,
+				modifications, err := c.addColumnsAndUpdateSortingKey(ctx, conn, tableName,
 					"DstNetName",
 					`SrcNetRole LowCardinality(String)`,
 					`DstNetRole LowCardinality(String)`,
@@ -337,8 +389,8 @@ WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 				if err != nil {
 					return err
 				}
-				return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s %s`,
-					tableName, modifications))
+				return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s%s %s`,
+					tableName, c.onCluster(), modifications))
 			},
 		}
 	}
@@ -346,20 +398,15 @@ WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 
 func (c *Component) migrationStepAddExporterColumns(resolution ResolutionConfiguration) migrationStepFunc {
 	return func(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
-		var tableName string
-		if resolution.Interval == 0 {
-			tableName = "flows"
-		} else {
-			tableName = fmt.Sprintf("flows_%s", resolution.Interval)
-		}
+		tableName := c.localTable(flowsTable(resolution))
 		return migrationStep{
 			CheckQuery: `
 SELECT 1 FROM system.columns
 WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 			Args: []interface{}{tableName, "ExporterTenant"},
 			Do: func() error {
-				return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s %s`,
-					tableName, addColumnsAfter("ExporterGroup",
+				return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s%s %s`,
+					tableName, c.onCluster(), addColumnsAfter("ExporterGroup",
 						`ExporterRole LowCardinality(String)`,
 						`ExporterSite LowCardinality(String)`,
 						`ExporterRegion LowCardinality(String)`,
@@ -372,11 +419,10 @@ WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 
 func (c *Component) migrationStepFixOrderByCountry(resolution ResolutionConfiguration) migrationStepFunc {
 	return func(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
-		var tableName string
 		if resolution.Interval == 0 {
 			return nullMigrationStep
 		}
-		tableName = fmt.Sprintf("flows_%s", resolution.Interval)
+		tableName := c.localTable(flowsTable(resolution))
 		return migrationStep{
 			CheckQuery: `
 SELECT 1 FROM system.tables
@@ -387,14 +433,14 @@ AND has(splitByRegexp(',\\s*', sorting_key), $2)`,
 				// Drop the columns
 				l.Debug().Msg("drop SrcCountry/DstCountry columns")
 				err := conn.Exec(ctx,
-					fmt.Sprintf(`ALTER TABLE %s DROP COLUMN SrcCountry, DROP COLUMN DstCountry`,
-						tableName))
+					fmt.Sprintf(`ALTER TABLE %s%s DROP COLUMN SrcCountry, DROP COLUMN DstCountry`,
+						tableName, c.onCluster()))
 				if err != nil {
 					return fmt.Errorf("cannot drop SrcCountry/DstCountry columns: %w", err)
 				}
 				// Add them back
 				l.Debug().Msg("add back SrcCountry/DstCountry columns")
-				modifications, err := addColumnsAndUpdateSortingKey(ctx, conn, tableName,
+				modifications, err := c.addColumnsAndUpdateSortingKey(ctx, conn, tableName,
 					"DstNetTenant",
 					`SrcCountry FixedString(2)`,
 					`DstCountry FixedString(2)`,
@@ -402,8 +448,8 @@ AND has(splitByRegexp(',\\s*', sorting_key), $2)`,
 				if err != nil {
 					return err
 				}
-				return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s %s`,
-					tableName, modifications))
+				return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s%s %s`,
+					tableName, c.onCluster(), modifications))
 			},
 		}
 	}
@@ -411,12 +457,7 @@ AND has(splitByRegexp(',\\s*', sorting_key), $2)`,
 
 func (c *Component) migrationStepAddDstASPathColumns(resolution ResolutionConfiguration) migrationStepFunc {
 	return func(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
-		var tableName string
-		if resolution.Interval == 0 {
-			tableName = "flows"
-		} else {
-			tableName = fmt.Sprintf("flows_%s", resolution.Interval)
-		}
+		tableName := c.localTable(flowsTable(resolution))
 		return migrationStep{
 			CheckQuery: `
 SELECT 1 FROM system.columns
@@ -425,9 +466,9 @@ WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 			Do: func() error {
 				var modifications string
 				var err error
-				if tableName == "flows" {
+				if resolution.Interval == 0 {
 					// The flows table will get DstASPath, 1st, 2nd, 3rd ASN.
-					modifications, err = addColumnsAndUpdateSortingKey(ctx, conn, tableName,
+					modifications, err = c.addColumnsAndUpdateSortingKey(ctx, conn, tableName,
 						"DstCountry",
 						`DstASPath Array(UInt32)`,
 						`Dst1stAS UInt32`,
@@ -436,7 +477,7 @@ WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 					)
 				} else {
 					// The consolidated table will only get the three first ASNs.
-					modifications, err = addColumnsAndUpdateSortingKey(ctx, conn, tableName,
+					modifications, err = c.addColumnsAndUpdateSortingKey(ctx, conn, tableName,
 						"DstCountry",
 						`Dst1stAS UInt32`,
 						`Dst2ndAS UInt32`,
@@ -446,8 +487,8 @@ WHERE table = $1 AND database = currentDatabase() AND name = $2`,
 				if err != nil {
 					return err
 				}
-				return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s %s`,
-					tableName, modifications))
+				return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s%s %s`,
+					tableName, c.onCluster(), modifications))
 			},
 		}
 	}
@@ -458,15 +499,16 @@ func (c *Component) migrationStepAddDstCommunitiesColumn(ctx context.Context, l
 		CheckQuery: `
 SELECT 1 FROM system.columns
 WHERE table = $1 AND database = currentDatabase() AND name = $2`,
-		Args: []interface{}{"flows", "DstCommunities"},
+		Args: []interface{}{c.localTable("flows"), "DstCommunities"},
 		Do: func() error {
-			modifications, err := addColumnsAndUpdateSortingKey(ctx, conn, "flows",
+			modifications, err := c.addColumnsAndUpdateSortingKey(ctx, conn, c.localTable("flows"),
 				"Dst3rdAS",
 				"DstCommunities Array(UInt32)")
 			if err != nil {
 				return err
 			}
-			return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE flows %s`, modifications))
+			return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s%s %s`,
+				c.localTable("flows"), c.onCluster(), modifications))
 		},
 	}
 }
@@ -476,15 +518,16 @@ func (c *Component) migrationStepAddDstLargeCommunitiesColumn(ctx context.Contex
 		CheckQuery: `
 SELECT 1 FROM system.columns
 WHERE table = $1 AND database = currentDatabase() AND name = $2`,
-		Args: []interface{}{"flows", "DstLargeCommunities"},
+		Args: []interface{}{c.localTable("flows"), "DstLargeCommunities"},
 		Do: func() error {
-			modifications, err := addColumnsAndUpdateSortingKey(ctx, conn, "flows",
+			modifications, err := c.addColumnsAndUpdateSortingKey(ctx, conn, c.localTable("flows"),
 				"DstCommunities",
 				"DstLargeCommunities Array(UInt128)")
 			if err != nil {
 				return err
 			}
-			return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE flows %s`, modifications))
+			return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s%s %s`,
+				c.localTable("flows"), c.onCluster(), modifications))
 		},
 	}
 }
@@ -494,16 +537,17 @@ func (c *Component) migrationStepAddSrcNetMaskDstNetMaskColumns(ctx context.Cont
 		CheckQuery: `
 SELECT 1 FROM system.columns
 WHERE table = $1 AND database = currentDatabase() AND name = $2`,
-		Args: []interface{}{"flows", "SrcNetMask"},
+		Args: []interface{}{c.localTable("flows"), "SrcNetMask"},
 		Do: func() error {
-			modifications, err := addColumnsAndUpdateSortingKey(ctx, conn, "flows",
+			modifications, err := c.addColumnsAndUpdateSortingKey(ctx, conn, c.localTable("flows"),
 				"DstAddr",
 				"SrcNetMask UInt8",
 				"DstNetMask UInt8")
 			if err != nil {
 				return err
 			}
-			return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE flows %s`, modifications))
+			return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s%s %s`,
+				c.localTable("flows"), c.onCluster(), modifications))
 		},
 	}
 }
@@ -513,7 +557,7 @@ func (c *Component) migrationStepAddSrcNetPrefixDstNetPrefixColumn(ctx context.C
 		CheckQuery: `
 SELECT 1 FROM system.columns
 WHERE table = $1 AND database = currentDatabase() AND name = $2`,
-		Args: []interface{}{"flows", "SrcNetPrefix"},
+		Args: []interface{}{c.localTable("flows"), "SrcNetPrefix"},
 		Do: func() error {
 			sql := func(prefix string) string {
 				return fmt.Sprintf(`
@@ -525,23 +569,84 @@ CASE
 END
 `, prefix, prefix, prefix, prefix, prefix, prefix, prefix)
 			}
-			return conn.Exec(ctx, fmt.Sprintf("ALTER TABLE flows %s",
+			return conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s%s %s",
+				c.localTable("flows"), c.onCluster(),
 				addColumnsAfter("DstNetMask", sql("Src"), sql("Dst"))))
 		},
 	}
 }
 
+func (c *Component) migrationStepAddVlanMACMPLSColumns(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
+	return migrationStep{
+		CheckQuery: `
+SELECT 1 FROM system.columns
+WHERE table = $1 AND database = currentDatabase() AND name = $2`,
+		Args: []interface{}{c.localTable("flows"), "MPLS1stLabel"},
+		Do: func() error {
+			modifications, err := c.addColumnsAndUpdateSortingKey(ctx, conn, c.localTable("flows"),
+				"OutIfBoundary",
+				"SrcVlan UInt16",
+				"DstVlan UInt16",
+				"SrcMAC UInt64",
+				"DstMAC UInt64",
+				"MPLS1stLabel UInt32")
+			if err != nil {
+				return err
+			}
+			return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s%s %s`,
+				c.localTable("flows"), c.onCluster(), modifications))
+		},
+	}
+}
+
+func (c *Component) migrationStepAddIPHeaderColumns(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
+	return migrationStep{
+		CheckQuery: `
+SELECT 1 FROM system.columns
+WHERE table = $1 AND database = currentDatabase() AND name = $2`,
+		Args: []interface{}{c.localTable("flows"), "IcmpCode"},
+		Do: func() error {
+			modifications, err := c.addColumnsAndUpdateSortingKey(ctx, conn, c.localTable("flows"),
+				"DstPort",
+				"IPTos UInt8",
+				"IPTTL UInt8",
+				"TCPFlags UInt16",
+				"IcmpType UInt8",
+				"IcmpCode UInt8")
+			if err != nil {
+				return err
+			}
+			return conn.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s%s %s`,
+				c.localTable("flows"), c.onCluster(), modifications))
+		},
+	}
+}
+
+func (c *Component) migrationStepAddDSCPColumn(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
+	return migrationStep{
+		CheckQuery: `
+SELECT 1 FROM system.columns
+WHERE table = $1 AND database = currentDatabase() AND name = $2`,
+		Args: []interface{}{c.localTable("flows"), "DSCP"},
+		Do: func() error {
+			return conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s%s %s",
+				c.localTable("flows"), c.onCluster(),
+				addColumnsAfter("IPTos", "DSCP UInt8 ALIAS bitShiftRight(IPTos, 2)")))
+		},
+	}
+}
+
 func (c *Component) migrationsStepCreateFlowsConsumerTable(resolution ResolutionConfiguration) migrationStepFunc {
 	return func(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
 		if resolution.Interval == 0 {
 			// Consumer for the flows table are done later.
 			return nullMigrationStep
 		}
-		tableName := fmt.Sprintf("flows_%s", resolution.Interval)
-		viewName := fmt.Sprintf("%s_consumer", tableName)
+		tableName := c.localTable(flowsTable(resolution))
+		viewName := fmt.Sprintf("%s_consumer", flowsTable(resolution))
 		selectClause := fmt.Sprintf(`
 SELECT *
-EXCEPT (SrcAddr, DstAddr, SrcNetMask, DstNetMask, SrcPort, DstPort, DstASPath, DstCommunities, DstLargeCommunities)
+EXCEPT (SrcAddr, DstAddr, SrcNetMask, DstNetMask, SrcPort, DstPort, DstASPath, DstCommunities, DstLargeCommunities, SrcVlan, DstVlan, SrcMAC, DstMAC, MPLS1stLabel, IPTos, IPTTL, TCPFlags, IcmpType, IcmpCode)
 REPLACE toStartOfInterval(TimeReceived, toIntervalSecond(%d)) AS TimeReceived`,
 			uint64(resolution.Interval.Seconds()))
 		selectClause = strings.TrimSpace(strings.ReplaceAll(selectClause, "\n", " "))
@@ -552,15 +657,15 @@ REPLACE toStartOfInterval(TimeReceived, toIntervalSecond(%d)) AS TimeReceived`,
 			// No GROUP BY, the SummingMergeTree will take care of that
 			Do: func() error {
 				l.Debug().Msg("drop consumer table")
-				err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s SYNC`, viewName))
+				err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s%s SYNC`, viewName, c.onCluster()))
 				if err != nil {
 					return fmt.Errorf("cannot drop consumer table: %w", err)
 				}
 				l.Debug().Msg("create consumer table")
 				return conn.Exec(ctx, fmt.Sprintf(`
-CREATE MATERIALIZED VIEW %s TO %s
+CREATE MATERIALIZED VIEW %s%s TO %s
 AS %s
-FROM %s`, viewName, tableName, selectClause, "flows"))
+FROM %s`, viewName, c.onCluster(), tableName, selectClause, c.localTable("flows")))
 			},
 		}
 	}
@@ -576,10 +681,7 @@ func (c *Component) migrationsStepSetTTLFlowsTable(resolution ResolutionConfigur
 				Do:         func() error { return nil },
 			}
 		}
-		tableName := "flows"
-		if resolution.Interval != 0 {
-			tableName = fmt.Sprintf("flows_%s", resolution.Interval)
-		}
+		tableName := c.localTable(flowsTable(resolution))
 		seconds := uint64(resolution.TTL.Seconds())
 		ttl := fmt.Sprintf("TTL TimeReceived + toIntervalSecond(%d)", seconds)
 		return migrationStep{
@@ -593,25 +695,27 @@ WHERE name = $1 AND database = currentDatabase() AND engine_full LIKE $2`,
 			Do: func() error {
 				l.Warn().Msgf("updating TTL of flows table with interval %s, this can take a long time",
 					resolution.Interval)
-				return conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s MODIFY %s", tableName, ttl))
+				return conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s%s MODIFY %s",
+					tableName, c.onCluster(), ttl))
 			},
 		}
 	}
 }
 
 func (c *Component) migrationStepCreateExportersView(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
+	viewName := c.localTable("exporters")
 	return migrationStep{
 		CheckQuery: queryTableHash(9989732154180416521, ""),
-		Args:       []interface{}{"exporters"},
+		Args:       []interface{}{viewName},
 		Do: func() error {
 			l.Debug().Msg("drop exporters table")
-			err := conn.Exec(ctx, `DROP TABLE IF EXISTS exporters SYNC`)
+			err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s%s SYNC`, viewName, c.onCluster()))
 			if err != nil {
 				return fmt.Errorf("cannot drop exporters table: %w", err)
 			}
-			return conn.Exec(ctx, `
-CREATE MATERIALIZED VIEW exporters
-ENGINE = ReplacingMergeTree(TimeReceived)
+			return conn.Exec(ctx, fmt.Sprintf(`
+CREATE MATERIALIZED VIEW %s%s
+ENGINE = %s
 ORDER BY (ExporterAddress, IfName)
 AS
 SELECT DISTINCT
@@ -629,9 +733,11 @@ SELECT DISTINCT
  [InIfConnectivity, OutIfConnectivity][num] AS IfConnectivity,
  [InIfProvider, OutIfProvider][num] AS IfProvider,
  [InIfBoundary, OutIfBoundary][num] AS IfBoundary
-FROM flows
+FROM %s
 ARRAY JOIN arrayEnumerate([1,2]) AS num
-`)
+`, viewName, c.onCluster(),
+				c.mergeTreeEngine(viewName, "Replacing", "TimeReceived"),
+				c.localTable("flows")))
 		},
 	}
 }
@@ -648,7 +754,7 @@ SELECT 1 FROM system.tables
 		Args: []interface{}{"protocols", sourceLike},
 		Do: func() error {
 			return conn.Exec(ctx, fmt.Sprintf(`
-CREATE OR REPLACE DICTIONARY protocols (
+CREATE OR REPLACE DICTIONARY protocols%s (
  proto UInt8 INJECTIVE,
  name String,
  description String
@@ -658,7 +764,7 @@ PRIMARY KEY proto
 LIFETIME(MIN 0 MAX 3600)
 LAYOUT(HASHED())
 %s
-`, source, settings))
+`, c.onCluster(), source, settings))
 		},
 	}
 }
@@ -675,7 +781,7 @@ WHERE name = $1 AND database = currentDatabase() AND create_table_query LIKE $2`
 		Args: []interface{}{"asns", sourceLike},
 		Do: func() error {
 			return conn.Exec(ctx, fmt.Sprintf(`
-CREATE OR REPLACE DICTIONARY asns (
+CREATE OR REPLACE DICTIONARY asns%s (
  asn UInt32 INJECTIVE,
  name String
 )
@@ -685,7 +791,7 @@ PRIMARY KEY asn
 LIFETIME(MIN 0 MAX 3600)
 LAYOUT(HASHED())
 %s
-`, source, settings))
+`, c.onCluster(), source, settings))
 		},
 	}
 
@@ -701,7 +807,7 @@ func (c *Component) migrationStepCreateNetworksDictionary(ctx context.Context, l
 		Args:       []interface{}{"networks", sourceLike},
 		Do: func() error {
 			return conn.Exec(ctx, fmt.Sprintf(`
-CREATE OR REPLACE DICTIONARY networks (
+CREATE OR REPLACE DICTIONARY networks%s (
  network String,
  name String,
  role String,
@@ -715,7 +821,7 @@ PRIMARY KEY network
 LIFETIME(MIN 0 MAX 3600)
 LAYOUT(IP_TRIE())
 %s
-`, source, settings))
+`, c.onCluster(), source, settings))
 		},
 	}
 
@@ -737,27 +843,28 @@ func (c *Component) migrationStepCreateRawFlowsTable(ctx context.Context, l repo
 		`kafka_handle_error_mode = 'stream'`,
 	}, ", "))
 	return migrationStep{
-		CheckQuery: queryTableHash(8163754828379578018, "AND engine_full = $2"),
+		CheckQuery: queryTableHash(10182250468349488398, "AND engine_full = $2"),
 		Args:       []interface{}{tableName, kafkaEngine},
 		Do: func() error {
 			l.Debug().Msg("drop raw consumer table")
-			err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s_consumer SYNC`, tableName))
+			err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s_consumer%s SYNC`,
+				tableName, c.onCluster()))
 			if err != nil {
 				return fmt.Errorf("cannot drop raw consumer table: %w", err)
 			}
 			l.Debug().Msg("drop raw table")
-			err = conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s SYNC`, tableName))
+			err = conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s%s SYNC`, tableName, c.onCluster()))
 			if err != nil {
 				return fmt.Errorf("cannot drop raw table: %w", err)
 			}
 			l.Debug().Msg("create raw table")
 			return conn.Exec(ctx, fmt.Sprintf(`
-CREATE TABLE %s
+CREATE TABLE %s%s
 (
 %s,
 DstLargeCommunities Nested(ASN UInt32, LocalData1 UInt32, LocalData2 UInt32)
 )
-ENGINE = %s`, tableName, partialSchema(
+ENGINE = %s`, tableName, c.onCluster(), partialSchema(
 				"SrcNetName", "DstNetName",
 				"SrcNetRole", "DstNetRole",
 				"SrcNetSite", "DstNetSite",
@@ -774,11 +881,11 @@ func (c *Component) migrationStepCreateRawFlowsConsumerView(ctx context.Context,
 	tableName := fmt.Sprintf("flows_%d_raw", flow.CurrentSchemaVersion)
 	viewName := fmt.Sprintf("%s_consumer", tableName)
 	return migrationStep{
-		CheckQuery: queryTableHash(7925127510274634003, "AND as_select LIKE '% WHERE length(_error) = 0'"),
+		CheckQuery: queryTableHash(10870344715592558517, "AND as_select LIKE '% WHERE length(_error) = 0'"),
 		Args:       []interface{}{viewName},
 		Do: func() error {
 			l.Debug().Msg("drop consumer table")
-			err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s SYNC`, viewName))
+			err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s%s SYNC`, viewName, c.onCluster()))
 			if err != nil {
 				return fmt.Errorf("cannot drop consumer table: %w", err)
 			}
@@ -788,7 +895,7 @@ func (c *Component) migrationStepCreateRawFlowsConsumerView(ctx context.Context,
 				"`DstLargeCommunities.LocalData1`",
 				"`DstLargeCommunities.LocalData2`"}, ",")
 			return conn.Exec(ctx, fmt.Sprintf(`
-CREATE MATERIALIZED VIEW %s TO flows
+CREATE MATERIALIZED VIEW %s%s TO %s
 AS WITH arrayCompact(DstASPath) AS c_DstASPath SELECT
  * EXCEPT (%s),
  dictGetOrDefault('networks', 'name', SrcAddr, '') AS SrcNetName,
@@ -807,7 +914,7 @@ AS WITH arrayCompact(DstASPath) AS c_DstASPath SELECT
  arrayMap((asn, l1, l2) -> bitShiftLeft(asn::UInt128, 64) + bitShiftLeft(l1::UInt128, 32) + l2::UInt128, %s) AS DstLargeCommunities
 FROM %s
 WHERE length(_error) = 0`,
-				viewName,
+				viewName, c.onCluster(), c.localTable("flows"),
 				largeCommunitiesColumns, largeCommunitiesColumns,
 				tableName))
 		},
@@ -822,13 +929,13 @@ func (c *Component) migrationStepCreateRawFlowsErrorsView(ctx context.Context, l
 		Args:       []interface{}{viewName},
 		Do: func() error {
 			l.Debug().Msg("drop kafka errors table")
-			err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s SYNC`, viewName))
+			err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s%s SYNC`, viewName, c.onCluster()))
 			if err != nil {
 				return fmt.Errorf("cannot drop kafka errors table: %w", err)
 			}
 			l.Debug().Msg("create kafka errors table")
 			return conn.Exec(ctx, fmt.Sprintf(`
-CREATE MATERIALIZED VIEW %s
+CREATE MATERIALIZED VIEW %s%s
 ENGINE = MergeTree
 ORDER BY (timestamp, topic, partition, offset)
 PARTITION BY toYYYYMMDDhhmmss(toStartOfHour(timestamp))
@@ -842,8 +949,200 @@ AS SELECT
  _error AS error
 FROM %s
 WHERE length(_error) > 0`,
-				viewName,
+				viewName, c.onCluster(),
 				tableName))
 		},
 	}
 }
+
+func (c *Component) migrationStepCreateInterfaceCountersTable(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
+	tableName := c.localTable("interface_counters")
+	return migrationStep{
+		CheckQuery: `
+SELECT 1 FROM system.tables
+WHERE name = $1 AND database = currentDatabase()`,
+		Args: []interface{}{tableName},
+		Do: func() error {
+			return conn.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE %s%s (
+ TimeReceived DateTime CODEC(DoubleDelta, LZ4),
+ ExporterAddress LowCardinality(IPv6),
+ ExporterName LowCardinality(String),
+ IfIndex UInt32,
+ IfName LowCardinality(String),
+ IfDescription String,
+ IfSpeed UInt64,
+ IfInOctets UInt64,
+ IfInErrors UInt32,
+ IfInDiscards UInt32,
+ IfOutOctets UInt64,
+ IfOutErrors UInt32,
+ IfOutDiscards UInt32
+)
+ENGINE = %s
+PARTITION BY toYYYYMMDD(TimeReceived)
+ORDER BY (ExporterAddress, IfIndex, TimeReceived)`,
+				tableName, c.onCluster(), c.mergeTreeEngine(tableName, "")))
+		},
+	}
+}
+
+func (c *Component) migrationStepSetTTLInterfaceCountersTable(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
+	if c.config.InterfaceCountersTTL == 0 {
+		l.Info().Msg("not changing TTL for interface counters table")
+		return migrationStep{
+			CheckQuery: `SELECT 1`,
+			Args:       []interface{}{},
+			Do:         func() error { return nil },
+		}
+	}
+	seconds := uint64(c.config.InterfaceCountersTTL.Seconds())
+	ttl := fmt.Sprintf("TTL TimeReceived + toIntervalSecond(%d)", seconds)
+	return migrationStep{
+		CheckQuery: `
+SELECT 1 FROM system.tables
+WHERE name = $1 AND database = currentDatabase() AND engine_full LIKE $2`,
+		Args: []interface{}{
+			c.localTable("interface_counters"),
+			fmt.Sprintf("%% %s %%", ttl),
+		},
+		Do: func() error {
+			return conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s%s MODIFY %s",
+				c.localTable("interface_counters"), c.onCluster(), ttl))
+		},
+	}
+}
+
+func (c *Component) migrationStepCreateRawInterfaceCountersTable(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
+	tableName := "interface_counters_raw"
+	kafkaEngine := fmt.Sprintf("Kafka SETTINGS %s", strings.Join([]string{
+		fmt.Sprintf(`kafka_broker_list = '%s'`,
+			strings.Join(c.config.Kafka.Brokers, ",")),
+		fmt.Sprintf(`kafka_topic_list = '%s-counters'`, c.config.Kafka.Topic),
+		`kafka_group_name = 'clickhouse'`,
+		`kafka_format = 'JSONEachRow'`,
+		`kafka_num_consumers = 1`,
+		`kafka_handle_error_mode = 'stream'`,
+	}, ", "))
+	return migrationStep{
+		CheckQuery: `
+SELECT 1 FROM system.tables
+WHERE name = $1 AND database = currentDatabase() AND engine_full = $2`,
+		Args: []interface{}{tableName, kafkaEngine},
+		Do: func() error {
+			l.Debug().Msg("drop raw interface counters consumer table")
+			err := conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s_consumer%s SYNC`,
+				tableName, c.onCluster()))
+			if err != nil {
+				return fmt.Errorf("cannot drop raw interface counters consumer table: %w", err)
+			}
+			l.Debug().Msg("drop raw interface counters table")
+			err = conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s%s SYNC`, tableName, c.onCluster()))
+			if err != nil {
+				return fmt.Errorf("cannot drop raw interface counters table: %w", err)
+			}
+			l.Debug().Msg("create raw interface counters table")
+			return conn.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE %s%s
+(
+ TimeReceived UInt64,
+ ExporterAddress IPv6,
+ ExporterName String,
+ IfIndex UInt32,
+ IfName String,
+ IfDescription String,
+ IfSpeed UInt64,
+ IfInOctets UInt64,
+ IfInErrors UInt32,
+ IfInDiscards UInt32,
+ IfOutOctets UInt64,
+ IfOutErrors UInt32,
+ IfOutDiscards UInt32
+)
+ENGINE = %s`, tableName, c.onCluster(), kafkaEngine))
+		},
+	}
+}
+
+func (c *Component) migrationStepCreateRawInterfaceCountersConsumerView(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
+	viewName := "interface_counters_raw_consumer"
+	return migrationStep{
+		CheckQuery: `
+SELECT 1 FROM system.tables
+WHERE name = $1 AND database = currentDatabase()`,
+		Args: []interface{}{viewName},
+		Do: func() error {
+			l.Debug().Msg("create raw interface counters consumer table")
+			return conn.Exec(ctx, fmt.Sprintf(`
+CREATE MATERIALIZED VIEW %s%s TO %s
+AS SELECT
+ toDateTime(TimeReceived) AS TimeReceived,
+ ExporterAddress, ExporterName,
+ IfIndex, IfName, IfDescription, IfSpeed,
+ IfInOctets, IfInErrors, IfInDiscards,
+ IfOutOctets, IfOutErrors, IfOutDiscards
+FROM interface_counters_raw
+WHERE length(_error) = 0`,
+				viewName, c.onCluster(), c.localTable("interface_counters")))
+		},
+	}
+}
+
+// migrationStepCreateDistributedTable creates a distributed table on
+// top of the local table when using a cluster. It is recreated when
+// its columns do not match the ones of the local table. An existing
+// table using another engine is never dropped: switching a
+// non-clustered setup to a cluster has to be done manually.
+func (c *Component) migrationStepCreateDistributedTable(table string) migrationStepFunc {
+	return func(ctx context.Context, l reporter.Logger, conn clickhouse.Conn) migrationStep {
+		if c.config.Cluster == "" {
+			return nullMigrationStep
+		}
+		localTable := c.localTable(table)
+		return migrationStep{
+			CheckQuery: `
+SELECT 1 FROM system.tables
+WHERE name = $1 AND database = currentDatabase() AND engine = 'Distributed'
+AND (
+ SELECT groupBitXor(cityHash64(name,type,position))
+ FROM system.columns
+ WHERE table = $1 AND database = currentDatabase()
+) = (
+ SELECT groupBitXor(cityHash64(name,type,position))
+ FROM system.columns
+ WHERE table = $2 AND database = currentDatabase()
+)`,
+			Args: []interface{}{table, localTable},
+			Do: func() error {
+				rows, err := conn.Query(ctx,
+					`SELECT engine FROM system.tables WHERE name = $1 AND database = currentDatabase()`,
+					table)
+				if err != nil {
+					return fmt.Errorf("cannot query engine for %s: %w", table, err)
+				}
+				var engine string
+				if rows.Next() {
+					if err := rows.Scan(&engine); err != nil {
+						rows.Close()
+						return fmt.Errorf("cannot parse engine for %s: %w", table, err)
+					}
+				}
+				rows.Close()
+				if engine != "" && engine != "Distributed" {
+					return fmt.Errorf("table %s exists with engine %s, it should be migrated manually to a cluster setup",
+						table, engine)
+				}
+				l.Debug().Msgf("drop distributed table %s", table)
+				err = conn.Exec(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s%s SYNC`, table, c.onCluster()))
+				if err != nil {
+					return fmt.Errorf("cannot drop distributed table %s: %w", table, err)
+				}
+				l.Debug().Msgf("create distributed table %s", table)
+				return conn.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE %s%s AS %s
+ENGINE = Distributed(%s, currentDatabase(), %s, rand())`,
+					table, c.onCluster(), localTable, c.quotedCluster(), localTable))
+			},
+		}
+	}
+}
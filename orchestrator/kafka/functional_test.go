@@ -150,3 +150,36 @@ func TestTopicMorePartitions(t *testing.T) {
 			topic.NumPartitions, topic.ReplicationFactor)
 	}
 }
+
+func TestOutputTopicCreation(t *testing.T) {
+	client, brokers := kafka.SetupKafkaBroker(t)
+
+	rand.Seed(time.Now().UnixMicro())
+	topicName := fmt.Sprintf("test-topic-%d", rand.Int())
+	outputTopicName := fmt.Sprintf("test-output-%d", rand.Int())
+	expectedTopicName := fmt.Sprintf("%s-v%d", outputTopicName, flow.CurrentSchemaVersion)
+
+	configuration := DefaultConfiguration()
+	configuration.Topic = topicName
+	configuration.Brokers = brokers
+	configuration.Version = kafka.Version(sarama.V2_8_1_0)
+	c, err := New(reporter.NewMock(t), configuration)
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	c.RegisterOutputTopic(nil, outputTopicName)
+	c.RegisterOutputTopic(brokers, outputTopicName)
+	helpers.StartStop(t, c)
+
+	adminClient, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		t.Fatalf("NewClusterAdmin() error:\n%+v", err)
+	}
+	topics, err := adminClient.ListTopics()
+	if err != nil {
+		t.Fatalf("ListTopics() error:\n%+v", err)
+	}
+	if _, ok := topics[expectedTopicName]; !ok {
+		t.Fatal("ListTopics() did not find the output topic")
+	}
+}
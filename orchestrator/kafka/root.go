@@ -20,8 +20,10 @@ type Component struct {
 	r      *reporter.Reporter
 	config Configuration
 
-	kafkaConfig *sarama.Config
-	kafkaTopic  string
+	kafkaConfig        *sarama.Config
+	kafkaTopic         string
+	kafkaCountersTopic string
+	outputTopics       map[string][]string // brokers → topics
 }
 
 // New creates a new Kafka configurator.
@@ -38,11 +40,31 @@ func New(r *reporter.Reporter, config Configuration) (*Component, error) {
 		r:      r,
 		config: config,
 
-		kafkaConfig: kafkaConfig,
-		kafkaTopic:  fmt.Sprintf("%s-v%d", config.Topic, flow.CurrentSchemaVersion),
+		kafkaConfig:        kafkaConfig,
+		kafkaTopic:         fmt.Sprintf("%s-v%d", config.Topic, flow.CurrentSchemaVersion),
+		kafkaCountersTopic: fmt.Sprintf("%s-counters", config.Topic),
+		outputTopics:       map[string][]string{},
 	}, nil
 }
 
+// RegisterOutputTopic registers an additional topic used by an inlet
+// output. It is created or updated like the main topic. When no
+// brokers are provided, the main brokers are used. This should be
+// called before starting the component.
+func (c *Component) RegisterOutputTopic(brokers []string, topic string) {
+	if len(brokers) == 0 {
+		brokers = c.config.Brokers
+	}
+	key := strings.Join(brokers, ",")
+	name := fmt.Sprintf("%s-v%d", topic, flow.CurrentSchemaVersion)
+	for _, existing := range c.outputTopics[key] {
+		if existing == name {
+			return
+		}
+	}
+	c.outputTopics[key] = append(c.outputTopics[key], name)
+}
+
 // Start starts Kafka configuration.
 func (c *Component) Start() error {
 	c.r.Info().Msg("starting Kafka component")
@@ -52,33 +74,64 @@ func (c *Component) Start() error {
 		c.r.Info().Msg("Kafka component stopped")
 	}()
 
-	// Create topic
-	admin, err := sarama.NewClusterAdmin(c.config.Brokers, c.kafkaConfig)
+	// Create topics
+	mainBrokers := strings.Join(c.config.Brokers, ",")
+	if err := c.configureTopics(c.config.Brokers,
+		append([]string{c.kafkaTopic, c.kafkaCountersTopic}, c.outputTopics[mainBrokers]...)); err != nil {
+		return err
+	}
+	for brokers, names := range c.outputTopics {
+		if brokers == mainBrokers {
+			continue
+		}
+		if err := c.configureTopics(strings.Split(brokers, ","), names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configureTopics creates or updates the provided topics using the
+// provided brokers.
+func (c *Component) configureTopics(brokers []string, names []string) error {
+	admin, err := sarama.NewClusterAdmin(brokers, c.kafkaConfig)
 	if err != nil {
 		c.r.Err(err).
-			Str("brokers", strings.Join(c.config.Brokers, ",")).
+			Str("brokers", strings.Join(brokers, ",")).
 			Msg("unable to get admin client for topic creation")
 		return fmt.Errorf("unable to get admin client for topic creation: %w", err)
 	}
 	defer admin.Close()
-	l := c.r.With().
-		Str("brokers", strings.Join(c.config.Brokers, ",")).
-		Str("topic", c.kafkaTopic).
-		Logger()
 	topics, err := admin.ListTopics()
 	if err != nil {
-		l.Err(err).Msg("unable to get metadata for topics")
+		c.r.Err(err).
+			Str("brokers", strings.Join(brokers, ",")).
+			Msg("unable to get metadata for topics")
 		return fmt.Errorf("unable to get metadata for topics: %w", err)
 	}
-	if topic, ok := topics[c.kafkaTopic]; !ok {
-		if err := admin.CreateTopic(c.kafkaTopic,
+	for _, name := range names {
+		if err := c.configureTopic(admin, topics, brokers, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configureTopic creates or updates the provided topic.
+func (c *Component) configureTopic(admin sarama.ClusterAdmin, topics map[string]sarama.TopicDetail, brokers []string, name string) error {
+	l := c.r.With().
+		Str("brokers", strings.Join(brokers, ",")).
+		Str("topic", name).
+		Logger()
+	if topic, ok := topics[name]; !ok {
+		if err := admin.CreateTopic(name,
 			&sarama.TopicDetail{
 				NumPartitions:     c.config.TopicConfiguration.NumPartitions,
 				ReplicationFactor: c.config.TopicConfiguration.ReplicationFactor,
 				ConfigEntries:     c.config.TopicConfiguration.ConfigEntries,
 			}, false); err != nil {
 			l.Err(err).Msg("unable to create topic")
-			return fmt.Errorf("unable to create topic %q: %w", c.kafkaTopic, err)
+			return fmt.Errorf("unable to create topic %q: %w", name, err)
 		}
 		l.Info().Msg("topic created")
 	} else {
@@ -87,10 +140,10 @@ func (c *Component) Start() error {
 				topic.NumPartitions, c.config.TopicConfiguration.NumPartitions)
 		} else if topic.NumPartitions < c.config.TopicConfiguration.NumPartitions {
 			nb := c.config.TopicConfiguration.NumPartitions
-			if err := admin.CreatePartitions(c.kafkaTopic, nb, nil, false); err != nil {
+			if err := admin.CreatePartitions(name, nb, nil, false); err != nil {
 				l.Err(err).Msg("unable to add more partitions")
 				return fmt.Errorf("unable to add more partitions to topic %q: %w",
-					c.kafkaTopic, err)
+					name, err)
 			}
 		}
 		if c.config.TopicConfiguration.ReplicationFactor != topic.ReplicationFactor {
@@ -98,10 +151,10 @@ func (c *Component) Start() error {
 			l.Warn().Msgf("mismatch for replication factor: got %d, want %d",
 				topic.ReplicationFactor, c.config.TopicConfiguration.ReplicationFactor)
 		}
-		if err := admin.AlterConfig(sarama.TopicResource, c.kafkaTopic, c.config.TopicConfiguration.ConfigEntries, false); err != nil {
+		if err := admin.AlterConfig(sarama.TopicResource, name, c.config.TopicConfiguration.ConfigEntries, false); err != nil {
 			l.Err(err).Msg("unable to set topic configuration")
 			return fmt.Errorf("unable to set topic configuration for %q: %w",
-				c.kafkaTopic, err)
+				name, err)
 		}
 		l.Info().Msg("topic updated")
 	}